@@ -17,6 +17,7 @@ type Config struct {
 	Port                   string
 	AutoMigrate            bool
 	EmailServiceOverride   bool
+	WebSocketHubBackend    string
 }
 
 func Load() *Config {
@@ -49,6 +50,7 @@ func Load() *Config {
 		Port:                   getEnv("PORT", "8080"),
 		AutoMigrate:            autoMigrate,
 		EmailServiceOverride:   emailServiceOverride,
+		WebSocketHubBackend:    getEnv("WS_HUB_BACKEND", "memory"),
 	}
 }
 
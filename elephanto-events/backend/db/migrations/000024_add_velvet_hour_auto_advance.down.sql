@@ -0,0 +1,4 @@
+-- Remove Velvet Hour auto-advance settings
+DROP INDEX IF EXISTS idx_velvet_hour_sessions_round_ends_at;
+ALTER TABLE velvet_hour_sessions DROP COLUMN IF EXISTS auto_advance;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_auto_advance;
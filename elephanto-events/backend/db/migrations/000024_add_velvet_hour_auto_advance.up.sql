@@ -0,0 +1,7 @@
+-- Let the backend scheduler advance Velvet Hour rounds and breaks automatically.
+-- Hosts keep driving rounds by hand until an admin opts the event in.
+ALTER TABLE events ADD COLUMN the_hour_auto_advance BOOLEAN DEFAULT FALSE;
+ALTER TABLE velvet_hour_sessions ADD COLUMN auto_advance BOOLEAN DEFAULT FALSE;
+
+-- The scheduler polls active sessions by their timer
+CREATE INDEX idx_velvet_hour_sessions_round_ends_at ON velvet_hour_sessions(round_ends_at) WHERE is_active = true;
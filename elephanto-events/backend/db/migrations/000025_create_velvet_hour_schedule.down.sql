@@ -0,0 +1,3 @@
+-- Drop the precomputed Velvet Hour schedule
+DROP INDEX IF EXISTS idx_velvet_hour_schedule_session_round;
+DROP TABLE IF EXISTS velvet_hour_schedule;
@@ -0,0 +1,12 @@
+-- Precomputed round-robin schedule for a Velvet Hour session
+CREATE TABLE velvet_hour_schedule (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    round_number INTEGER NOT NULL,
+    user1_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user2_id UUID NULL REFERENCES users(id) ON DELETE CASCADE, -- NULL means user1 has a bye this round
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(session_id, round_number, user1_id)
+);
+
+CREATE INDEX idx_velvet_hour_schedule_session_round ON velvet_hour_schedule(session_id, round_number);
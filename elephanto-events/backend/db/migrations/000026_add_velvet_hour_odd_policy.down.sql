@@ -0,0 +1,16 @@
+-- Remove the Velvet Hour odd-headcount policy
+DELETE FROM velvet_hour_feedback
+WHERE id NOT IN (
+    SELECT DISTINCT ON (match_id, from_user_id) id
+    FROM velvet_hour_feedback
+    ORDER BY match_id, from_user_id, created_at ASC
+);
+ALTER TABLE velvet_hour_feedback DROP CONSTRAINT IF EXISTS unique_feedback_per_recipient;
+ALTER TABLE velvet_hour_feedback
+ADD CONSTRAINT unique_feedback_per_match
+UNIQUE (match_id, from_user_id);
+
+DROP TABLE IF EXISTS velvet_hour_byes;
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS confirmed_user3;
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS user3_id;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_odd_policy;
@@ -0,0 +1,25 @@
+-- How a Velvet Hour event handles an odd number of participants in a round
+ALTER TABLE events ADD COLUMN the_hour_odd_policy VARCHAR(20) DEFAULT 'rotating_bye'
+    CHECK (the_hour_odd_policy IN ('rotating_bye', 'trio'));
+
+-- Optional third member for three-person matches
+ALTER TABLE velvet_hour_matches ADD COLUMN user3_id UUID NULL REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_matches ADD COLUMN confirmed_user3 BOOLEAN DEFAULT FALSE;
+
+-- Participants sitting a round out
+CREATE TABLE velvet_hour_byes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    round_number INTEGER NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(session_id, round_number, user_id)
+);
+
+CREATE INDEX idx_velvet_hour_byes_session ON velvet_hour_byes(session_id);
+
+-- In a trio each member leaves feedback for both of the others
+ALTER TABLE velvet_hour_feedback DROP CONSTRAINT IF EXISTS unique_feedback_per_match;
+ALTER TABLE velvet_hour_feedback
+ADD CONSTRAINT unique_feedback_per_recipient
+UNIQUE (match_id, from_user_id, to_user_id);
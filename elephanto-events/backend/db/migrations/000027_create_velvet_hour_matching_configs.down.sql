@@ -0,0 +1,2 @@
+-- Remove per-event Velvet Hour matching configuration
+DROP TABLE IF EXISTS velvet_hour_matching_configs;
@@ -0,0 +1,11 @@
+-- Per-event tuning of Velvet Hour matching
+CREATE TABLE velvet_hour_matching_configs (
+    event_id UUID PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    weights JSONB NOT NULL, -- connectionType, personality, torontoMeaning, age, gender, cocktail
+    max_age_gap INTEGER NULL CHECK (max_age_gap > 0), -- NULL means no limit
+    require_same_connection_type BOOLEAN NOT NULL DEFAULT FALSE,
+    dating_gender_rule VARCHAR(20) NOT NULL DEFAULT 'different' CHECK (dating_gender_rule IN ('any', 'different')),
+    dating_gender_hard BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Remove Velvet Hour connections
+DROP TABLE IF EXISTS velvet_hour_connections;
+ALTER TABLE velvet_hour_feedback DROP COLUMN IF EXISTS share_email;
+ALTER TABLE velvet_hour_feedback DROP COLUMN IF EXISTS share_instagram;
@@ -0,0 +1,30 @@
+-- Contact details a participant agrees to share if the interest turns out to be mutual
+ALTER TABLE velvet_hour_feedback ADD COLUMN share_instagram BOOLEAN DEFAULT FALSE;
+ALTER TABLE velvet_hour_feedback ADD COLUMN share_email BOOLEAN DEFAULT FALSE;
+
+-- Mutual want-to-connect pairs, stored once per match with the lower user ID first
+CREATE TABLE velvet_hour_connections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    match_id UUID NOT NULL REFERENCES velvet_hour_matches(id) ON DELETE CASCADE,
+    user1_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user2_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user1_id < user2_id),
+    UNIQUE(match_id, user1_id, user2_id)
+);
+
+CREATE INDEX idx_velvet_hour_connections_user1 ON velvet_hour_connections(user1_id);
+CREATE INDEX idx_velvet_hour_connections_user2 ON velvet_hour_connections(user2_id);
+
+-- Pick up mutual interest recorded before connections existed
+INSERT INTO velvet_hour_connections (session_id, match_id, user1_id, user2_id, created_at)
+SELECT m.session_id, m.id, LEAST(f1.from_user_id, f1.to_user_id), GREATEST(f1.from_user_id, f1.to_user_id),
+       GREATEST(f1.submitted_at, f2.submitted_at)
+FROM velvet_hour_feedback f1
+JOIN velvet_hour_feedback f2 ON f2.match_id = f1.match_id
+    AND f2.from_user_id = f1.to_user_id AND f2.to_user_id = f1.from_user_id
+JOIN velvet_hour_matches m ON m.id = f1.match_id
+WHERE f1.want_to_connect = true AND f2.want_to_connect = true
+  AND f1.from_user_id < f1.to_user_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Remove Velvet Hour match status
+DELETE FROM velvet_hour_matches WHERE status = 'abandoned';
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS abandoned_at;
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS status;
@@ -0,0 +1,4 @@
+-- Matches are abandoned when a member disconnects mid-round and their partner is re-paired
+ALTER TABLE velvet_hour_matches ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'abandoned'));
+ALTER TABLE velvet_hour_matches ADD COLUMN abandoned_at TIMESTAMP WITHOUT TIME ZONE NULL;
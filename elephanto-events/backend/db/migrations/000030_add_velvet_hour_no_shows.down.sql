@@ -0,0 +1,8 @@
+-- Remove Velvet Hour no-show tracking
+DROP TABLE IF EXISTS velvet_hour_no_shows;
+UPDATE velvet_hour_matches SET status = 'abandoned' WHERE status = 'no_show';
+ALTER TABLE velvet_hour_matches DROP CONSTRAINT IF EXISTS velvet_hour_matches_status_check;
+ALTER TABLE velvet_hour_matches ADD CONSTRAINT velvet_hour_matches_status_check
+    CHECK (status IN ('active', 'abandoned'));
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_no_show_limit;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_confirmation_window;
@@ -0,0 +1,22 @@
+-- How long matched participants have to confirm, and how many no-shows keep someone out
+-- Off until an admin sets a window for the event
+ALTER TABLE events ADD COLUMN the_hour_confirmation_window INTEGER DEFAULT 0; -- seconds, 0 disables
+ALTER TABLE events ADD COLUMN the_hour_no_show_limit INTEGER NULL; -- NULL means no limit
+
+-- Matches whose confirmation window ran out are closed as no-shows
+ALTER TABLE velvet_hour_matches DROP CONSTRAINT IF EXISTS velvet_hour_matches_status_check;
+ALTER TABLE velvet_hour_matches ADD CONSTRAINT velvet_hour_matches_status_check
+    CHECK (status IN ('active', 'abandoned', 'no_show'));
+
+-- Everyone who didn't confirm a match in time, kept across events
+CREATE TABLE velvet_hour_no_shows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    match_id UUID NOT NULL REFERENCES velvet_hour_matches(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    round_number INTEGER NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(match_id, user_id)
+);
+
+CREATE INDEX idx_velvet_hour_no_shows_user ON velvet_hour_no_shows(user_id);
@@ -0,0 +1,9 @@
+-- Remove structured Velvet Hour feedback answers
+DROP TABLE IF EXISTS velvet_hour_feedback_answers;
+ALTER TABLE velvet_hour_feedback ALTER COLUMN feedback_reason DROP DEFAULT;
+ALTER TABLE velvet_hour_questions DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE velvet_hour_questions DROP CONSTRAINT IF EXISTS velvet_hour_questions_type_check;
+ALTER TABLE velvet_hour_questions DROP COLUMN IF EXISTS scale_max;
+ALTER TABLE velvet_hour_questions DROP COLUMN IF EXISTS scale_min;
+ALTER TABLE velvet_hour_questions DROP COLUMN IF EXISTS is_required;
+ALTER TABLE velvet_hour_questions DROP COLUMN IF EXISTS answer_type;
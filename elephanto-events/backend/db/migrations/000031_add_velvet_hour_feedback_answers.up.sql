@@ -0,0 +1,26 @@
+-- Questions say how they are answered and whether an answer is required
+ALTER TABLE velvet_hour_questions ADD COLUMN answer_type VARCHAR(20) NOT NULL DEFAULT 'single_choice'
+    CHECK (answer_type IN ('single_choice', 'multi_choice', 'scale', 'free_text'));
+ALTER TABLE velvet_hour_questions ADD COLUMN is_required BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE velvet_hour_questions ADD COLUMN scale_min INTEGER NULL;
+ALTER TABLE velvet_hour_questions ADD COLUMN scale_max INTEGER NULL;
+ALTER TABLE velvet_hour_questions ADD CONSTRAINT velvet_hour_questions_type_check
+    CHECK (question_type IN ('connect', 'feedback'));
+
+-- Deleted questions are archived so the answers already given to them are kept
+ALTER TABLE velvet_hour_questions ADD COLUMN archived_at TIMESTAMP WITHOUT TIME ZONE NULL;
+
+-- Events with questions configured no longer send a fixed reason
+ALTER TABLE velvet_hour_feedback ALTER COLUMN feedback_reason SET DEFAULT '';
+
+-- One answer per question per piece of feedback
+CREATE TABLE velvet_hour_feedback_answers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feedback_id UUID NOT NULL REFERENCES velvet_hour_feedback(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL REFERENCES velvet_hour_questions(id),
+    answer JSONB NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(feedback_id, question_id)
+);
+
+CREATE INDEX idx_velvet_hour_feedback_answers_question ON velvet_hour_feedback_answers(question_id);
@@ -0,0 +1,3 @@
+-- Remove Velvet Hour session state guards
+ALTER TABLE velvet_hour_sessions DROP CONSTRAINT IF EXISTS velvet_hour_sessions_status_check;
+DROP INDEX IF EXISTS idx_velvet_hour_sessions_one_active;
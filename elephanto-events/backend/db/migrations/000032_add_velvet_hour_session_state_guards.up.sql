@@ -0,0 +1,14 @@
+-- Keep only the newest active session per event before enforcing one
+UPDATE velvet_hour_sessions s
+SET is_active = false, status = 'completed', ended_at = COALESCE(ended_at, CURRENT_TIMESTAMP)
+WHERE is_active = true AND EXISTS (
+    SELECT 1 FROM velvet_hour_sessions newer
+    WHERE newer.event_id = s.event_id AND newer.is_active = true AND newer.created_at > s.created_at
+);
+
+-- An event can only have one active session
+CREATE UNIQUE INDEX idx_velvet_hour_sessions_one_active ON velvet_hour_sessions(event_id) WHERE is_active = true;
+
+-- Sessions may only be in a known status
+ALTER TABLE velvet_hour_sessions ADD CONSTRAINT velvet_hour_sessions_status_check
+    CHECK (status IN ('waiting', 'in_round', 'break', 'completed'));
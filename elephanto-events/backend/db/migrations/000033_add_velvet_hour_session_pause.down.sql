@@ -0,0 +1,3 @@
+-- Remove Velvet Hour session pausing
+ALTER TABLE velvet_hour_sessions DROP COLUMN IF EXISTS paused_remaining_seconds;
+ALTER TABLE velvet_hour_sessions DROP COLUMN IF EXISTS paused_at;
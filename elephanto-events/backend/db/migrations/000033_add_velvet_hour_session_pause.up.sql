@@ -0,0 +1,3 @@
+-- A paused round or break keeps its remaining time until it is resumed
+ALTER TABLE velvet_hour_sessions ADD COLUMN paused_at TIMESTAMP WITHOUT TIME ZONE NULL;
+ALTER TABLE velvet_hour_sessions ADD COLUMN paused_remaining_seconds INTEGER NULL;
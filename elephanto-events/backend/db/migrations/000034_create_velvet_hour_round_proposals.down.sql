@@ -0,0 +1,2 @@
+-- Remove Velvet Hour round proposals
+DROP TABLE IF EXISTS velvet_hour_round_proposals;
@@ -0,0 +1,14 @@
+-- Previewed rounds an admin can start exactly as reviewed
+CREATE TABLE velvet_hour_round_proposals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    round_number INTEGER NOT NULL,
+    matches JSONB NOT NULL,
+    byes JSONB NOT NULL DEFAULT '[]',
+    compatibility_score REAL NOT NULL DEFAULT 0,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMP WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_velvet_hour_round_proposals_session ON velvet_hour_round_proposals(session_id, round_number);
@@ -0,0 +1,5 @@
+-- Remove cross-event pairing memory
+DROP INDEX IF EXISTS idx_velvet_hour_matches_user3;
+DROP INDEX IF EXISTS idx_velvet_hour_matches_user2;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_memory_lookback_days;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_cross_event_memory;
@@ -0,0 +1,8 @@
+-- Optionally keep regulars from meeting someone they matched with at a recent event
+ALTER TABLE events ADD COLUMN the_hour_cross_event_memory BOOLEAN DEFAULT FALSE;
+ALTER TABLE events ADD COLUMN the_hour_memory_lookback_days INTEGER DEFAULT 90
+    CHECK (the_hour_memory_lookback_days > 0);
+
+-- Finds a user's matches from other events quickly
+CREATE INDEX idx_velvet_hour_matches_user2 ON velvet_hour_matches(user2_id);
+CREATE INDEX idx_velvet_hour_matches_user3 ON velvet_hour_matches(user3_id);
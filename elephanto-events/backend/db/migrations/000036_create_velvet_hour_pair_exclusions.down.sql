@@ -0,0 +1,2 @@
+-- Remove the do-not-pair list
+DROP TABLE IF EXISTS velvet_hour_pair_exclusions;
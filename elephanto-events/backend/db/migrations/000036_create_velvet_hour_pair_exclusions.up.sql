@@ -0,0 +1,22 @@
+-- Pairs who must never be matched, stored with the lower user ID first.
+-- Users block people themselves (directly or from feedback); admins add
+-- exclusions for one event or for every event.
+CREATE TABLE velvet_hour_pair_exclusions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user1_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user2_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_id UUID NULL REFERENCES events(id) ON DELETE CASCADE, -- NULL applies to every event
+    source VARCHAR(20) NOT NULL CHECK (source IN ('user', 'feedback', 'admin')),
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user1_id < user2_id)
+);
+
+CREATE UNIQUE INDEX idx_velvet_hour_pair_exclusions_unique ON velvet_hour_pair_exclusions(
+    user1_id, user2_id,
+    COALESCE(event_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    COALESCE(created_by, '00000000-0000-0000-0000-000000000000'::uuid)
+);
+CREATE INDEX idx_velvet_hour_pair_exclusions_user2 ON velvet_hour_pair_exclusions(user2_id);
+CREATE INDEX idx_velvet_hour_pair_exclusions_event ON velvet_hour_pair_exclusions(event_id);
@@ -0,0 +1,4 @@
+-- Remove pods mode. Pods keep their first three members.
+DROP TABLE IF EXISTS velvet_hour_match_members;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_pod_size;
+ALTER TABLE events DROP COLUMN IF EXISTS the_hour_group_mode;
@@ -0,0 +1,35 @@
+-- Rounds can form groups ("pods") instead of pairs
+ALTER TABLE events ADD COLUMN the_hour_group_mode VARCHAR(10) DEFAULT 'pairs'
+    CHECK (the_hour_group_mode IN ('pairs', 'pods'));
+ALTER TABLE events ADD COLUMN the_hour_pod_size INTEGER DEFAULT 4
+    CHECK (the_hour_pod_size BETWEEN 3 AND 6);
+
+-- Everyone in a match, each confirming for themselves. user1_id, user2_id and
+-- user3_id on the match mirror the first three members.
+CREATE TABLE velvet_hour_match_members (
+    match_id UUID NOT NULL REFERENCES velvet_hour_matches(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL CHECK (position >= 1),
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    confirmed_at TIMESTAMP WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (match_id, user_id),
+    UNIQUE (match_id, position)
+);
+
+CREATE INDEX idx_velvet_hour_match_members_user ON velvet_hour_match_members(user_id);
+
+-- Existing pairs and trios
+INSERT INTO velvet_hour_match_members (match_id, user_id, position, confirmed, confirmed_at, created_at)
+SELECT id, user1_id, 1, COALESCE(confirmed_user1, false),
+       CASE WHEN confirmed_user1 THEN confirmed_at END, created_at
+FROM velvet_hour_matches;
+INSERT INTO velvet_hour_match_members (match_id, user_id, position, confirmed, confirmed_at, created_at)
+SELECT id, user2_id, 2, COALESCE(confirmed_user2, false),
+       CASE WHEN confirmed_user2 THEN confirmed_at END, created_at
+FROM velvet_hour_matches;
+INSERT INTO velvet_hour_match_members (match_id, user_id, position, confirmed, confirmed_at, created_at)
+SELECT id, user3_id, 3, COALESCE(confirmed_user3, false),
+       CASE WHEN confirmed_user3 THEN confirmed_at END, created_at
+FROM velvet_hour_matches
+WHERE user3_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Remove venue tables
+ALTER TABLE velvet_hour_participants DROP COLUMN IF EXISTS needs_accessible_seating;
+DROP INDEX IF EXISTS idx_velvet_hour_matches_table;
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS table_id;
+DROP TABLE IF EXISTS velvet_hour_tables;
@@ -0,0 +1,24 @@
+-- The venue layout for an event: tables or spots matches are sent to, grouped
+-- into zones so people move around the room between rounds
+CREATE TABLE velvet_hour_tables (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    zone VARCHAR(100) NOT NULL DEFAULT '',
+    capacity INTEGER NOT NULL DEFAULT 2 CHECK (capacity BETWEEN 2 AND 20),
+    accessible BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, name)
+);
+
+CREATE INDEX idx_velvet_hour_tables_event ON velvet_hour_tables(event_id);
+
+-- Where each match meets; NULL when the event has no layout
+ALTER TABLE velvet_hour_matches ADD COLUMN table_id UUID NULL
+    REFERENCES velvet_hour_tables(id) ON DELETE SET NULL;
+CREATE INDEX idx_velvet_hour_matches_table ON velvet_hour_matches(table_id);
+
+-- Participants who need a step-free, accessible table
+ALTER TABLE velvet_hour_participants ADD COLUMN needs_accessible_seating BOOLEAN NOT NULL DEFAULT FALSE;
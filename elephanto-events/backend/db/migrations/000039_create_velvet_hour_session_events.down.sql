@@ -0,0 +1,4 @@
+-- Remove the session event log
+DROP TRIGGER IF EXISTS velvet_hour_session_events_append_only ON velvet_hour_session_events;
+DROP FUNCTION IF EXISTS velvet_hour_session_events_append_only();
+DROP TABLE IF EXISTS velvet_hour_session_events;
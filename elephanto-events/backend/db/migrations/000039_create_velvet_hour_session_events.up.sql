@@ -0,0 +1,28 @@
+-- Append-only log of everything that happens in a Velvet Hour session, for
+-- rebuilding a live event afterwards. Rows are never changed; they only go
+-- away with their session.
+CREATE TABLE velvet_hour_session_events (
+    id BIGSERIAL PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    round_number INTEGER NOT NULL DEFAULT 0,
+    user_id UUID NULL,
+    match_id UUID NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX idx_velvet_hour_session_events_session ON velvet_hour_session_events(session_id, id);
+
+CREATE FUNCTION velvet_hour_session_events_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND NOT EXISTS (SELECT 1 FROM velvet_hour_sessions WHERE id = OLD.session_id) THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'velvet_hour_session_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER velvet_hour_session_events_append_only
+    BEFORE UPDATE OR DELETE ON velvet_hour_session_events
+    FOR EACH ROW EXECUTE FUNCTION velvet_hour_session_events_append_only();
@@ -0,0 +1,3 @@
+-- The compatibility the matcher predicted for each match, so analytics can
+-- compare it with how the match turned out. Matches made before this are left empty.
+ALTER TABLE velvet_hour_matches ADD COLUMN compatibility_score REAL NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS velvet_hour_digests;
+ALTER TABLE velvet_hour_sessions DROP COLUMN IF EXISTS digest_sent_at;
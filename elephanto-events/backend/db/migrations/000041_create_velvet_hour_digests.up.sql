@@ -0,0 +1,21 @@
+-- When a completed session's digest emails were sent out
+ALTER TABLE velvet_hour_sessions ADD COLUMN digest_sent_at TIMESTAMP WITHOUT TIME ZONE NULL;
+
+-- Sessions that finished before digests existed are never emailed about
+UPDATE velvet_hour_sessions SET digest_sent_at = COALESCE(ended_at, CURRENT_TIMESTAMP)
+WHERE status = 'completed';
+
+-- One digest per participant per session, so sending again skips anyone
+-- already emailed. updated_at tells how long a digest has been sending.
+CREATE TABLE velvet_hour_digests (
+    session_id UUID NOT NULL REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'sending' CHECK (status IN ('sending', 'sent', 'failed')),
+    error TEXT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    sent_at TIMESTAMP WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, user_id)
+);
+
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS websocket_hub_messages;
+DROP TABLE IF EXISTS websocket_hub_presence;
+DROP TABLE IF EXISTS websocket_hub_instances;
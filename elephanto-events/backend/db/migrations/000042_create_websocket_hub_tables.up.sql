@@ -0,0 +1,29 @@
+-- Backend instances sharing WebSocket rooms through Postgres, with when each
+-- last said it was alive
+CREATE TABLE websocket_hub_instances (
+    id UUID PRIMARY KEY,
+    started_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    heartbeat_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Who is connected to each event through each instance, so presence can be
+-- counted across all of them
+CREATE TABLE websocket_hub_presence (
+    instance_id UUID NOT NULL REFERENCES websocket_hub_instances(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    connections INTEGER NOT NULL CHECK (connections > 0),
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (instance_id, event_id, user_id)
+);
+
+CREATE INDEX idx_websocket_hub_presence_event ON websocket_hub_presence(event_id);
+
+-- Messages too big for a NOTIFY payload; the notification carries the ID
+CREATE TABLE websocket_hub_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    envelope JSONB NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_websocket_hub_messages_created_at ON websocket_hub_messages(created_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS websocket_hub_sequences;
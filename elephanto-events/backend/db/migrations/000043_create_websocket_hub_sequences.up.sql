@@ -0,0 +1,6 @@
+-- The last sequence number given to a message broadcast to each event, shared
+-- by every instance so reconnecting clients can be sent what they missed
+CREATE TABLE websocket_hub_sequences (
+    event_id UUID PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1 @@
+ALTER TABLE velvet_hour_byes DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,5 @@
+-- Why a participant is without a match in a round: a bye the matcher handed
+-- out, or waiting to be paired again after an admin split their match. Only
+-- byes count towards who sits out next.
+ALTER TABLE velvet_hour_byes ADD COLUMN reason VARCHAR(20) NOT NULL DEFAULT 'bye'
+    CHECK (reason IN ('bye', 'waiting'));
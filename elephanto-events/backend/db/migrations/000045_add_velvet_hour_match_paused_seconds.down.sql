@@ -0,0 +1 @@
+ALTER TABLE velvet_hour_matches DROP COLUMN IF EXISTS confirmation_paused_seconds;
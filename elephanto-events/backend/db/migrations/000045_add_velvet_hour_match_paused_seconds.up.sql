@@ -0,0 +1,5 @@
+-- How long the session was paused while a match waited for confirmations, so
+-- time spent paused doesn't count towards its confirmation window. A match that
+-- replaces another mid-round takes over its deadline by subtracting the time
+-- between the two.
+ALTER TABLE velvet_hour_matches ADD COLUMN confirmation_paused_seconds INTEGER NOT NULL DEFAULT 0;
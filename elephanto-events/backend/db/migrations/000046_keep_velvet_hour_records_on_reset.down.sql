@@ -0,0 +1,25 @@
+DELETE FROM velvet_hour_connections WHERE session_id IS NULL OR match_id IS NULL;
+ALTER TABLE velvet_hour_connections DROP CONSTRAINT velvet_hour_connections_match_id_fkey;
+ALTER TABLE velvet_hour_connections ADD CONSTRAINT velvet_hour_connections_match_id_fkey
+    FOREIGN KEY (match_id) REFERENCES velvet_hour_matches(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_connections DROP CONSTRAINT velvet_hour_connections_session_id_fkey;
+ALTER TABLE velvet_hour_connections ADD CONSTRAINT velvet_hour_connections_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_connections ALTER COLUMN match_id SET NOT NULL;
+ALTER TABLE velvet_hour_connections ALTER COLUMN session_id SET NOT NULL;
+ALTER TABLE velvet_hour_connections DROP COLUMN IF EXISTS user2_shares_email;
+ALTER TABLE velvet_hour_connections DROP COLUMN IF EXISTS user2_shares_instagram;
+ALTER TABLE velvet_hour_connections DROP COLUMN IF EXISTS user1_shares_email;
+ALTER TABLE velvet_hour_connections DROP COLUMN IF EXISTS user1_shares_instagram;
+ALTER TABLE velvet_hour_connections DROP COLUMN IF EXISTS event_id;
+
+DELETE FROM velvet_hour_no_shows WHERE session_id IS NULL OR match_id IS NULL;
+ALTER TABLE velvet_hour_no_shows DROP CONSTRAINT velvet_hour_no_shows_match_id_fkey;
+ALTER TABLE velvet_hour_no_shows ADD CONSTRAINT velvet_hour_no_shows_match_id_fkey
+    FOREIGN KEY (match_id) REFERENCES velvet_hour_matches(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_no_shows DROP CONSTRAINT velvet_hour_no_shows_session_id_fkey;
+ALTER TABLE velvet_hour_no_shows ADD CONSTRAINT velvet_hour_no_shows_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES velvet_hour_sessions(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_no_shows ALTER COLUMN match_id SET NOT NULL;
+ALTER TABLE velvet_hour_no_shows ALTER COLUMN session_id SET NOT NULL;
+ALTER TABLE velvet_hour_no_shows DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,43 @@
+-- No-shows and mutual connections outlive a session reset: each row names its
+-- event directly and only loses its session and match when those are deleted.
+-- Connections keep the contact details each person chose to share, since a
+-- reset deletes the feedback they came from.
+ALTER TABLE velvet_hour_no_shows ADD COLUMN event_id UUID REFERENCES events(id) ON DELETE CASCADE;
+UPDATE velvet_hour_no_shows ns SET event_id = s.event_id
+FROM velvet_hour_sessions s WHERE s.id = ns.session_id;
+ALTER TABLE velvet_hour_no_shows ALTER COLUMN event_id SET NOT NULL;
+
+ALTER TABLE velvet_hour_no_shows ALTER COLUMN session_id DROP NOT NULL;
+ALTER TABLE velvet_hour_no_shows ALTER COLUMN match_id DROP NOT NULL;
+ALTER TABLE velvet_hour_no_shows DROP CONSTRAINT velvet_hour_no_shows_session_id_fkey;
+ALTER TABLE velvet_hour_no_shows ADD CONSTRAINT velvet_hour_no_shows_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES velvet_hour_sessions(id) ON DELETE SET NULL;
+ALTER TABLE velvet_hour_no_shows DROP CONSTRAINT velvet_hour_no_shows_match_id_fkey;
+ALTER TABLE velvet_hour_no_shows ADD CONSTRAINT velvet_hour_no_shows_match_id_fkey
+    FOREIGN KEY (match_id) REFERENCES velvet_hour_matches(id) ON DELETE SET NULL;
+
+ALTER TABLE velvet_hour_connections ADD COLUMN event_id UUID REFERENCES events(id) ON DELETE CASCADE;
+ALTER TABLE velvet_hour_connections ADD COLUMN user1_shares_instagram BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE velvet_hour_connections ADD COLUMN user1_shares_email BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE velvet_hour_connections ADD COLUMN user2_shares_instagram BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE velvet_hour_connections ADD COLUMN user2_shares_email BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE velvet_hour_connections c SET event_id = s.event_id
+FROM velvet_hour_sessions s WHERE s.id = c.session_id;
+UPDATE velvet_hour_connections c
+SET user1_shares_instagram = COALESCE(f.share_instagram, false), user1_shares_email = COALESCE(f.share_email, false)
+FROM velvet_hour_feedback f
+WHERE f.match_id = c.match_id AND f.from_user_id = c.user1_id AND f.to_user_id = c.user2_id;
+UPDATE velvet_hour_connections c
+SET user2_shares_instagram = COALESCE(f.share_instagram, false), user2_shares_email = COALESCE(f.share_email, false)
+FROM velvet_hour_feedback f
+WHERE f.match_id = c.match_id AND f.from_user_id = c.user2_id AND f.to_user_id = c.user1_id;
+ALTER TABLE velvet_hour_connections ALTER COLUMN event_id SET NOT NULL;
+
+ALTER TABLE velvet_hour_connections ALTER COLUMN session_id DROP NOT NULL;
+ALTER TABLE velvet_hour_connections ALTER COLUMN match_id DROP NOT NULL;
+ALTER TABLE velvet_hour_connections DROP CONSTRAINT velvet_hour_connections_session_id_fkey;
+ALTER TABLE velvet_hour_connections ADD CONSTRAINT velvet_hour_connections_session_id_fkey
+    FOREIGN KEY (session_id) REFERENCES velvet_hour_sessions(id) ON DELETE SET NULL;
+ALTER TABLE velvet_hour_connections DROP CONSTRAINT velvet_hour_connections_match_id_fkey;
+ALTER TABLE velvet_hour_connections ADD CONSTRAINT velvet_hour_connections_match_id_fkey
+    FOREIGN KEY (match_id) REFERENCES velvet_hour_matches(id) ON DELETE SET NULL;
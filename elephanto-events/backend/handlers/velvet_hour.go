@@ -7,6 +7,7 @@ import (
 	"elephanto-events/services"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net/http"
@@ -15,11 +16,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
 type VelvetHourHandler struct {
-	db  *sql.DB
-	hub *services.Hub
+	db        *sql.DB
+	hub       *services.Hub
+	email     *services.EmailService
+	analytics analyticsCache
 }
 
 func NewVelvetHourHandler(db *sql.DB) *VelvetHourHandler {
@@ -29,6 +33,7 @@ func NewVelvetHourHandler(db *sql.DB) *VelvetHourHandler {
 // SetWebSocketHub sets the WebSocket hub for broadcasting messages
 func (h *VelvetHourHandler) SetWebSocketHub(hub *services.Hub) {
 	h.hub = hub
+	hub.OnPresenceChange(h.handlePresenceChange)
 }
 
 // GetStatus returns the current Velvet Hour status for a user
@@ -70,44 +75,21 @@ func (h *VelvetHourHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	var session models.VelvetHourSession
 	err = h.db.QueryRow(`
 		SELECT id, event_id, started_at, ended_at, is_active, current_round, 
-			   round_started_at, round_ends_at, status, created_at, updated_at
+			   round_started_at, round_ends_at, status, auto_advance,
+			   paused_at, paused_remaining_seconds, created_at, updated_at
 		FROM velvet_hour_sessions 
 		WHERE event_id = $1 AND is_active = true
 	`, eventID).Scan(
 		&session.ID, &session.EventID, &session.StartedAt, &session.EndedAt,
 		&session.IsActive, &session.CurrentRound, &session.RoundStartedAt,
-		&session.RoundEndsAt, &session.Status, &session.CreatedAt, &session.UpdatedAt,
+		&session.RoundEndsAt, &session.Status, &session.AutoAdvance,
+		&session.PausedAt, &session.PausedRemainingSeconds, &session.CreatedAt, &session.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
 		// No active session, but user is attending - allow them to wait/connect
 		// Get event configuration even when no session exists
-		var config models.VelvetHourConfig
-		err = h.db.QueryRow(`
-			SELECT the_hour_round_duration, the_hour_break_duration, 
-				   the_hour_total_rounds
-			FROM events 
-			WHERE id = $1
-		`, eventID).Scan(
-			&config.RoundDuration, &config.BreakDuration,
-			&config.TotalRounds,
-		)
-		if err != nil {
-			log.Printf("Failed to get event config: %v", err)
-			// Use default values if config fetch fails
-			config = models.VelvetHourConfig{
-				RoundDuration:   10,
-				BreakDuration:   5,
-				TotalRounds:     4,
-			}
-		}
-		
-		// Calculate minimum participants based on total rounds (round-robin formula)
-		if config.TotalRounds%2 == 0 {
-			config.MinParticipants = config.TotalRounds + 1
-		} else {
-			config.MinParticipants = config.TotalRounds
-		}
+		config := h.loadEventConfig(eventID)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(models.VelvetHourStatusResponse{
@@ -128,14 +110,14 @@ func (h *VelvetHourHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	// Get participant status
 	var participant models.VelvetHourParticipant
 	err = h.db.QueryRow(`
-		SELECT p.id, p.session_id, p.user_id, p.joined_at, p.status, 
+		SELECT p.id, p.session_id, p.user_id, p.joined_at, p.status, p.needs_accessible_seating,
 			   p.created_at, p.updated_at, u.name, u.email
 		FROM velvet_hour_participants p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.session_id = $1 AND p.user_id = $2
 	`, session.ID, user.ID).Scan(
 		&participant.ID, &participant.SessionID, &participant.UserID,
-		&participant.JoinedAt, &participant.Status, &participant.CreatedAt,
+		&participant.JoinedAt, &participant.Status, &participant.NeedsAccessibleSeating, &participant.CreatedAt,
 		&participant.UpdatedAt, &participant.UserName, &participant.UserEmail,
 	)
 	
@@ -154,29 +136,57 @@ func (h *VelvetHourHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	if participantPtr != nil {
 		var match models.VelvetHourMatch
 		err = h.db.QueryRow(`
-			SELECT m.id, m.session_id, m.round_number, m.user1_id, m.user2_id,
-				   m.match_number, m.match_color, m.started_at, m.confirmed_user1,
-				   m.confirmed_user2, m.confirmed_at, m.created_at, m.updated_at,
-				   u1.name, u2.name
+			SELECT m.id, m.session_id, m.round_number, m.user1_id, m.user2_id, m.user3_id,
+				   m.match_number, m.match_color, m.table_id, m.started_at, m.confirmed_user1,
+				   m.confirmed_user2, m.confirmed_user3, m.confirmed_at, m.created_at, m.updated_at,
+				   u1.name, u2.name, u3.name
 			FROM velvet_hour_matches m
 			JOIN users u1 ON m.user1_id = u1.id
 			JOIN users u2 ON m.user2_id = u2.id
-			WHERE m.session_id = $1 AND m.round_number = $2 
-			  AND (m.user1_id = $3 OR m.user2_id = $3)
+			LEFT JOIN users u3 ON m.user3_id = u3.id
+			WHERE m.session_id = $1 AND m.round_number = $2 AND m.status = 'active'
+			  AND EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.user_id = $3)
 		`, session.ID, session.CurrentRound, user.ID).Scan(
 			&match.ID, &match.SessionID, &match.RoundNumber, &match.User1ID,
-			&match.User2ID, &match.MatchNumber, &match.MatchColor, &match.StartedAt,
-			&match.ConfirmedUser1, &match.ConfirmedUser2, &match.ConfirmedAt,
-			&match.CreatedAt, &match.UpdatedAt, &match.User1Name, &match.User2Name,
+			&match.User2ID, &match.User3ID, &match.MatchNumber, &match.MatchColor, &match.TableID, &match.StartedAt,
+			&match.ConfirmedUser1, &match.ConfirmedUser2, &match.ConfirmedUser3, &match.ConfirmedAt,
+			&match.CreatedAt, &match.UpdatedAt, &match.User1Name, &match.User2Name, &match.User3Name,
 		)
+		match.Status = "active"
 		if err == nil {
+			match.Group, err = loadMatchMembers(h.db, match.ID)
+			if err != nil {
+				log.Printf("Failed to get match members: %v", err)
+			}
+			match.Table, err = loadMatchTable(h.db, match.TableID)
+			if err != nil {
+				log.Printf("Failed to get match table: %v", err)
+			}
 			currentMatch = &match
 		}
 	}
 
+	// Participants without a match this round may be sitting it out
+	hasBye := false
+	if participantPtr != nil && currentMatch == nil && session.CurrentRound > 0 {
+		err = h.db.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM velvet_hour_byes 
+				WHERE session_id = $1 AND round_number = $2 AND user_id = $3
+			)
+		`, session.ID, session.CurrentRound, user.ID).Scan(&hasBye)
+		if err != nil {
+			log.Printf("Failed to check bye: %v", err)
+		}
+	}
+
 	// Calculate time left
 	var timeLeft *int
-	if session.RoundEndsAt != nil {
+	if session.PausedAt != nil && session.PausedRemainingSeconds != nil {
+		// The clock is frozen while the host holds the round
+		remaining := *session.PausedRemainingSeconds
+		timeLeft = &remaining
+	} else if session.RoundEndsAt != nil {
 		// Use UTC for consistent timezone handling
 		currentTimeUTC := time.Now().UTC()
 		remaining := int(session.RoundEndsAt.Sub(currentTimeUTC).Seconds())
@@ -193,31 +203,11 @@ func (h *VelvetHourHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get event configuration
-	var config models.VelvetHourConfig
-	err = h.db.QueryRow(`
-		SELECT the_hour_round_duration, the_hour_break_duration, 
-			   the_hour_total_rounds
-		FROM events 
-		WHERE id = $1
-	`, eventID).Scan(
-		&config.RoundDuration, &config.BreakDuration,
-		&config.TotalRounds,
-	)
+	config := h.loadEventConfig(eventID)
+
+	questions, err := h.loadQuestions(eventID)
 	if err != nil {
-		log.Printf("Failed to get event config: %v", err)
-		// Use default values if config fetch fails
-		config = models.VelvetHourConfig{
-			RoundDuration:   10,
-			BreakDuration:   5,
-			TotalRounds:     4,
-		}
-	}
-	
-	// Calculate minimum participants based on total rounds (round-robin formula)
-	if config.TotalRounds%2 == 0 {
-		config.MinParticipants = config.TotalRounds + 1
-	} else {
-		config.MinParticipants = config.TotalRounds
+		log.Printf("Failed to get questions: %v", err)
 	}
 
 	response := models.VelvetHourStatusResponse{
@@ -226,7 +216,10 @@ func (h *VelvetHourHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 		Participant:  participantPtr,
 		CurrentMatch: currentMatch,
 		TimeLeft:     timeLeft,
+		HasBye:       hasBye,
+		Paused:       session.PausedAt != nil,
 		Config:       &config,
+		Questions:    questions,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -241,7 +234,7 @@ func (h *VelvetHourHandler) JoinSession(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "User not found", http.StatusInternalServerError)
 		return
 	}
-	log.Printf("DEBUG: User found: %s (%s)", user.Name, user.ID)
+	log.Printf("DEBUG: User found: %s", user.ID)
 
 	// Check if user is attending the active event
 	var eventID uuid.UUID
@@ -260,10 +253,11 @@ func (h *VelvetHourHandler) JoinSession(w http.ResponseWriter, r *http.Request)
 
 	// Get active session
 	var sessionID uuid.UUID
+	var currentRound int
 	err = h.db.QueryRow(`
-		SELECT id FROM velvet_hour_sessions 
+		SELECT id, current_round FROM velvet_hour_sessions 
 		WHERE event_id = $1 AND is_active = true
-	`, eventID).Scan(&sessionID)
+	`, eventID).Scan(&sessionID, &currentRound)
 	
 	if err == sql.ErrNoRows {
 		http.Error(w, "No active Velvet Hour session", http.StatusBadRequest)
@@ -275,13 +269,38 @@ func (h *VelvetHourHandler) JoinSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Keep out people who keep leaving their matches hanging, if the event says so.
+	// Only earlier sessions count, and anyone already in this one can rejoin.
+	config := h.loadEventConfig(eventID)
+	if config.NoShowLimit != nil {
+		var joined bool
+		var noShows int
+		err = h.db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM velvet_hour_participants WHERE session_id = $2 AND user_id = $1),
+				   (SELECT COUNT(*) FROM velvet_hour_no_shows WHERE user_id = $1 AND session_id IS DISTINCT FROM $2)
+		`, user.ID, sessionID).Scan(&joined, &noShows)
+		if err != nil {
+			log.Printf("Failed to count no-shows: %v", err)
+		} else if !joined && noShows >= *config.NoShowLimit {
+			http.Error(w, "You have missed too many Velvet Hour matches to join this session", http.StatusForbidden)
+			return
+		}
+	}
+
+	// The body is optional; older clients send none
+	var req models.JoinVelvetHourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
 	// Add participant (or update if already exists)
 	_, err = h.db.Exec(`
-		INSERT INTO velvet_hour_participants (session_id, user_id, status)
-		VALUES ($1, $2, 'waiting')
+		INSERT INTO velvet_hour_participants (session_id, user_id, status, needs_accessible_seating)
+		VALUES ($1, $2, 'waiting', $3)
 		ON CONFLICT (session_id, user_id) 
-		DO UPDATE SET status = 'waiting', updated_at = CURRENT_TIMESTAMP
-	`, sessionID, user.ID)
+		DO UPDATE SET status = 'waiting', needs_accessible_seating = EXCLUDED.needs_accessible_seating, updated_at = CURRENT_TIMESTAMP
+	`, sessionID, user.ID, req.NeedsAccessibleSeating)
 	
 	if err != nil {
 		log.Printf("Failed to add participant: %v", err)
@@ -289,13 +308,29 @@ func (h *VelvetHourHandler) JoinSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	err = recordSessionEvent(h.db, sessionID, sessionEventJoined, currentRound, &user.ID, nil, participantJoinedPayload{
+		NeedsAccessibleSeating: req.NeedsAccessibleSeating,
+	})
+	if err != nil {
+		log.Printf("Failed to record join: %v", err)
+	}
+
+	// Until the first round starts, the schedule follows who has joined
+	if currentRound == 0 {
+		if err := h.replanSchedule(sessionID); err != nil {
+			log.Printf("Failed to plan schedule: %v", err)
+		}
+	}
+
 	// Broadcast participant joined event
 	if h.hub != nil {
-		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourParticipantJoined, map[string]interface{}{
+		h.hub.BroadcastScoped(eventID, []uuid.UUID{user.ID}, services.MessageTypeVelvetHourParticipantJoined, map[string]interface{}{
 			"userId":    user.ID,
 			"userName":  user.Name,
 			"userEmail": user.Email,
 			"sessionId": sessionID,
+		}, map[string]interface{}{
+			"sessionId": sessionID,
 		})
 		
 		// Also broadcast attendance stats update to admin users
@@ -329,13 +364,14 @@ func (h *VelvetHourHandler) ConfirmMatch(w http.ResponseWriter, r *http.Request)
 
 	// Get match details and confirm user is part of this match
 	var match models.VelvetHourMatch
+	var position int
 	err := h.db.QueryRow(`
-		SELECT id, session_id, user1_id, user2_id, confirmed_user1, confirmed_user2
-		FROM velvet_hour_matches
-		WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)
+		SELECT m.id, m.session_id, m.user1_id, m.user2_id, m.user3_id, mm.position
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_match_members mm ON mm.match_id = m.id
+		WHERE m.id = $1 AND m.status = 'active' AND mm.user_id = $2
 	`, req.MatchID, user.ID).Scan(
-		&match.ID, &match.SessionID, &match.User1ID, &match.User2ID,
-		&match.ConfirmedUser1, &match.ConfirmedUser2,
+		&match.ID, &match.SessionID, &match.User1ID, &match.User2ID, &match.User3ID, &position,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -348,39 +384,64 @@ func (h *VelvetHourHandler) ConfirmMatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Update confirmation status
-	var updateQuery string
-	if match.User1ID == user.ID {
-		updateQuery = `
-			UPDATE velvet_hour_matches 
-			SET confirmed_user1 = true, updated_at = CURRENT_TIMESTAMP
-			WHERE id = $1
-		`
-	} else {
-		updateQuery = `
-			UPDATE velvet_hour_matches 
-			SET confirmed_user2 = true, updated_at = CURRENT_TIMESTAMP
-			WHERE id = $1
-		`
-	}
-
-	_, err = h.db.Exec(updateQuery, req.MatchID)
+	tx, err := h.db.Begin()
 	if err != nil {
-		log.Printf("Failed to update match confirmation: %v", err)
+		log.Printf("Failed to begin transaction: %v", err)
 		http.Error(w, "Failed to confirm match", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	// Check if both users have confirmed
-	err = h.db.QueryRow(`
-		SELECT confirmed_user1, confirmed_user2
-		FROM velvet_hour_matches
-		WHERE id = $1
-	`, req.MatchID).Scan(&match.ConfirmedUser1, &match.ConfirmedUser2)
-	
+	// Update confirmation status. The match columns mirror the first three
+	// members for clients that still read them.
+	_, err = tx.Exec(`
+		UPDATE velvet_hour_matches 
+		SET confirmed_user1 = confirmed_user1 OR $2 = 1,
+			confirmed_user2 = confirmed_user2 OR $2 = 2,
+			confirmed_user3 = confirmed_user3 OR $2 = 3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'active'
+	`, req.MatchID, position)
+	var confirmed sql.Result
+	if err == nil {
+		confirmed, err = tx.Exec(`
+			UPDATE velvet_hour_match_members mm
+			SET confirmed = true, confirmed_at = COALESCE(mm.confirmed_at, CURRENT_TIMESTAMP)
+			FROM velvet_hour_matches m
+			WHERE mm.match_id = $1 AND mm.user_id = $2 AND m.id = mm.match_id AND m.status = 'active'
+		`, req.MatchID, user.ID)
+	}
+	if err == nil {
+		if updated, _ := confirmed.RowsAffected(); updated == 0 {
+			// The confirmation window closed while the request was in flight
+			http.Error(w, "Match is no longer active", http.StatusConflict)
+			return
+		}
+		match.Group, err = loadMatchMembers(tx, match.ID)
+	}
+	if err == nil {
+		err = recordMatchEvent(tx, match.ID, sessionEventConfirmed, &user.ID, nil)
+	}
+
+	// Everyone in the match has to confirm; the match starts with the last of them
+	allConfirmed := true
+	for _, member := range match.Group {
+		allConfirmed = allConfirmed && member.Confirmed
+	}
+	if err == nil && allConfirmed {
+		_, err = tx.Exec(`
+			UPDATE velvet_hour_matches
+			SET confirmed_at = COALESCE(confirmed_at, CURRENT_TIMESTAMP),
+				started_at = COALESCE(started_at, CURRENT_TIMESTAMP)
+			WHERE id = $1
+		`, req.MatchID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
-		log.Printf("Failed to check confirmation status: %v", err)
-		http.Error(w, "Failed to check confirmation", http.StatusInternalServerError)
+		log.Printf("Failed to update match confirmation: %v", err)
+		http.Error(w, "Failed to confirm match", http.StatusInternalServerError)
 		return
 	}
 
@@ -394,28 +455,22 @@ func (h *VelvetHourHandler) ConfirmMatch(w http.ResponseWriter, r *http.Request)
 		log.Printf("Failed to get event ID for WebSocket: %v", err)
 	}
 
-	// Broadcast match confirmation
+	// Only the match members and admins learn who confirmed; everyone else
+	// just hears that a match was confirmed
 	if h.hub != nil {
-		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourMatchConfirmed, map[string]interface{}{
+		h.hub.BroadcastScoped(eventID, match.Members(), services.MessageTypeVelvetHourMatchConfirmed, map[string]interface{}{
 			"matchId":  req.MatchID,
 			"userId":   user.ID,
 			"user1Id":  match.User1ID,
 			"user2Id":  match.User2ID,
-			"bothConfirmed": (match.User1ID == user.ID && match.ConfirmedUser2) || (match.User2ID == user.ID && match.ConfirmedUser1),
+			"user3Id":  match.User3ID,
+			"memberIds": match.Members(),
+			"bothConfirmed": allConfirmed,
+		}, map[string]interface{}{
+			"bothConfirmed": allConfirmed,
 		})
 	}
 
-	// Update match confirmation timestamp
-	_, err = h.db.Exec(`
-		UPDATE velvet_hour_matches 
-		SET confirmed_at = CURRENT_TIMESTAMP, started_at = CURRENT_TIMESTAMP
-		WHERE id = $1
-	`, req.MatchID)
-	
-	if err != nil {
-		log.Printf("Failed to update match start time: %v", err)
-	}
-
 	// Note: Session timer is set when round starts, not when individual matches are confirmed
 
 	w.Header().Set("Content-Type", "application/json")
@@ -439,40 +494,103 @@ func (h *VelvetHourHandler) SubmitFeedback(w http.ResponseWriter, r *http.Reques
 	// Get match details to determine the other user
 	var match models.VelvetHourMatch
 	err := h.db.QueryRow(`
-		SELECT id, user1_id, user2_id
-		FROM velvet_hour_matches
-		WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)
-	`, req.MatchID, user.ID).Scan(&match.ID, &match.User1ID, &match.User2ID)
+		SELECT m.id, m.user1_id, m.user2_id, m.user3_id
+		FROM velvet_hour_matches m
+		WHERE m.id = $1
+		  AND EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.user_id = $2)
+	`, req.MatchID, user.ID).Scan(&match.ID, &match.User1ID, &match.User2ID, &match.User3ID)
 	
 	if err == sql.ErrNoRows {
 		http.Error(w, "Match not found or user not part of match", http.StatusNotFound)
 		return
 	}
+	if err == nil {
+		match.Group, err = loadMatchMembers(h.db, match.ID)
+	}
 	if err != nil {
 		log.Printf("Failed to get match: %v", err)
 		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
 		return
 	}
 
-	// Determine the other user
+	// Determine the other user. In a trio or a pod the client names which
+	// member the feedback is about.
 	var toUserID uuid.UUID
-	if match.User1ID == user.ID {
+	if req.ToUserID != nil {
+		if *req.ToUserID == user.ID || !match.HasMember(*req.ToUserID) {
+			http.Error(w, "Feedback recipient is not part of this match", http.StatusBadRequest)
+			return
+		}
+		toUserID = *req.ToUserID
+	} else if len(match.Members()) > 2 {
+		http.Error(w, "toUserId is required for group matches", http.StatusBadRequest)
+		return
+	} else if match.User1ID == user.ID {
 		toUserID = match.User2ID
 	} else {
 		toUserID = match.User1ID
 	}
 
+	// Get event ID for the questions and WebSocket broadcasting
+	var eventID uuid.UUID
+	err = h.db.QueryRow(`
+		SELECT s.event_id 
+		FROM velvet_hour_sessions s
+		JOIN velvet_hour_matches m ON s.id = m.session_id
+		WHERE m.id = $1
+	`, req.MatchID).Scan(&eventID)
+	if err != nil {
+		log.Printf("Failed to get event ID for feedback: %v", err)
+		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+		return
+	}
+
+	// Events with configured questions take structured answers; the rest
+	// still use the single feedback reason
+	questions, err := h.loadQuestions(eventID)
+	if err != nil {
+		log.Printf("Failed to get questions: %v", err)
+		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+		return
+	}
+	var answers map[uuid.UUID]json.RawMessage
+	if len(questions) > 0 {
+		answers, err = validateAnswers(questions, req.WantToConnect, req.Answers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if strings.TrimSpace(req.FeedbackReason) == "" {
+		http.Error(w, "Feedback reason is required", http.StatusBadRequest)
+		return
+	}
+	if req.NeverMatchAgain && req.WantToConnect {
+		http.Error(w, "Cannot ask to connect with someone you never want to match again", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
 	// Insert feedback
-	_, err = h.db.Exec(`
+	var feedbackID uuid.UUID
+	err = tx.QueryRow(`
 		INSERT INTO velvet_hour_feedback 
-		(match_id, from_user_id, to_user_id, want_to_connect, feedback_reason)
-		VALUES ($1, $2, $3, $4, $5)
-	`, req.MatchID, user.ID, toUserID, req.WantToConnect, req.FeedbackReason)
+		(match_id, from_user_id, to_user_id, want_to_connect, feedback_reason, share_instagram, share_email)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, req.MatchID, user.ID, toUserID, req.WantToConnect, req.FeedbackReason,
+		req.WantToConnect && req.ShareInstagram, req.WantToConnect && req.ShareEmail).Scan(&feedbackID)
 	
 	if err != nil {
 		log.Printf("Failed to insert feedback: %v", err)
 		// Check if it's a unique constraint violation (duplicate feedback)
-		if strings.Contains(err.Error(), "unique_feedback_per_match") {
+		if strings.Contains(err.Error(), "unique_feedback_per_recipient") {
 			http.Error(w, "Feedback already submitted for this match", http.StatusConflict)
 		} else {
 			http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
@@ -480,26 +598,72 @@ func (h *VelvetHourHandler) SubmitFeedback(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Get event ID for WebSocket broadcasting
-	var eventID uuid.UUID
-	err = h.db.QueryRow(`
-		SELECT s.event_id 
-		FROM velvet_hour_sessions s
-		JOIN velvet_hour_matches m ON s.id = m.session_id
-		WHERE m.id = $1
-	`, req.MatchID).Scan(&eventID)
-	
+	for questionID, answer := range answers {
+		_, err = tx.Exec(`
+			INSERT INTO velvet_hour_feedback_answers (feedback_id, question_id, answer)
+			VALUES ($1, $2, $3)
+		`, feedbackID, questionID, string(answer))
+		if err != nil {
+			log.Printf("Failed to insert feedback answer: %v", err)
+			http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Never matching again is a private block the recipient doesn't hear about
+	if req.NeverMatchAgain {
+		if err := addBlock(tx, user.ID, toUserID, "feedback", req.FeedbackReason); err != nil {
+			log.Printf("Failed to record block: %v", err)
+			http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = recordMatchEvent(tx, req.MatchID, sessionEventFeedback, &user.ID, feedbackPayload{
+		ToUserID:        toUserID,
+		WantToConnect:   req.WantToConnect,
+		NeverMatchAgain: req.NeverMatchAgain,
+	})
 	if err != nil {
-		log.Printf("Failed to get event ID for WebSocket: %v", err)
-	} else {
-		// Broadcast feedback submission
-		if h.hub != nil {
-			h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourFeedbackSubmitted, map[string]interface{}{
-				"matchId":        req.MatchID,
-				"fromUserId":     user.ID,
-				"toUserId":       toUserID,
-				"wantToConnect":  req.WantToConnect,
-				"feedbackReason": req.FeedbackReason,
+		log.Printf("Failed to record feedback: %v", err)
+		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to commit feedback: %v", err)
+		http.Error(w, "Failed to submit feedback", http.StatusInternalServerError)
+		return
+	}
+
+	// A yes from both sides becomes a connection
+	connected := false
+	if req.WantToConnect {
+		connected, err = h.recordConnectionIfMutual(req.MatchID, user.ID, toUserID)
+		if err != nil {
+			log.Printf("Failed to record connection: %v", err)
+		}
+	}
+
+	// Admins see the full answer. The person it is about only learns that
+	// feedback arrived, so one-sided interest is never revealed, and the
+	// rest of the room hears nothing.
+	if h.hub != nil {
+		h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourFeedbackSubmitted, map[string]interface{}{
+			"matchId":        req.MatchID,
+			"fromUserId":     user.ID,
+			"toUserId":       toUserID,
+			"wantToConnect":   req.WantToConnect,
+			"feedbackReason":  req.FeedbackReason,
+			"answers":         answers,
+			"neverMatchAgain": req.NeverMatchAgain,
+		})
+		h.hub.SendToUsers(eventID, []uuid.UUID{toUserID}, services.MessageTypeVelvetHourFeedbackSubmitted, map[string]interface{}{
+			"matchId": req.MatchID,
+		})
+		if connected {
+			h.hub.SendToUsers(eventID, []uuid.UUID{user.ID, toUserID}, services.MessageTypeVelvetHourConnectionMade, map[string]interface{}{
+				"matchId": req.MatchID,
 			})
 		}
 	}
@@ -525,13 +689,15 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 	var session models.VelvetHourSession
 	err = h.db.QueryRow(`
 		SELECT id, event_id, started_at, ended_at, is_active, current_round,
-			   round_started_at, round_ends_at, status, created_at, updated_at
+			   round_started_at, round_ends_at, status, auto_advance,
+			   paused_at, paused_remaining_seconds, created_at, updated_at
 		FROM velvet_hour_sessions
 		WHERE event_id = $1 AND is_active = true
 	`, eventID).Scan(
 		&session.ID, &session.EventID, &session.StartedAt, &session.EndedAt,
 		&session.IsActive, &session.CurrentRound, &session.RoundStartedAt,
-		&session.RoundEndsAt, &session.Status, &session.CreatedAt, &session.UpdatedAt,
+		&session.RoundEndsAt, &session.Status, &session.AutoAdvance,
+		&session.PausedAt, &session.PausedRemainingSeconds, &session.CreatedAt, &session.UpdatedAt,
 	)
 	
 	var sessionPtr *models.VelvetHourSession
@@ -543,7 +709,7 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 	participants := []models.VelvetHourParticipant{}
 	if sessionPtr != nil {
 		rows, err := h.db.Query(`
-			SELECT p.id, p.session_id, p.user_id, p.joined_at, p.status,
+			SELECT p.id, p.session_id, p.user_id, p.joined_at, p.status, p.needs_accessible_seating,
 				   p.created_at, p.updated_at, u.name, u.email
 			FROM velvet_hour_participants p
 			JOIN users u ON p.user_id = u.id
@@ -557,7 +723,7 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 			for rows.Next() {
 				var p models.VelvetHourParticipant
 				err := rows.Scan(
-					&p.ID, &p.SessionID, &p.UserID, &p.JoinedAt, &p.Status,
+					&p.ID, &p.SessionID, &p.UserID, &p.JoinedAt, &p.Status, &p.NeedsAccessibleSeating,
 					&p.CreatedAt, &p.UpdatedAt, &p.UserName, &p.UserEmail,
 				)
 				if err != nil {
@@ -573,18 +739,19 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 	currentMatches := []models.VelvetHourMatch{}
 	if sessionPtr != nil {
 		rows, err := h.db.Query(`
-			SELECT m.id, m.session_id, m.round_number, m.user1_id, m.user2_id,
-				   m.match_number, m.match_color, m.started_at, m.confirmed_user1,
-				   m.confirmed_user2, m.confirmed_at, m.created_at, m.updated_at,
-				   u1.name, u2.name,
-				   COALESCE(f1.from_user_id IS NOT NULL, false) as user1_feedback_submitted,
-				   COALESCE(f2.from_user_id IS NOT NULL, false) as user2_feedback_submitted
+			SELECT m.id, m.session_id, m.round_number, m.user1_id, m.user2_id, m.user3_id,
+				   m.match_number, m.match_color, m.table_id, m.started_at, m.confirmed_user1,
+				   m.confirmed_user2, m.confirmed_user3, m.confirmed_at, m.status, m.created_at, m.updated_at,
+				   u1.name, u2.name, u3.name,
+				   EXISTS(SELECT 1 FROM velvet_hour_feedback f WHERE f.match_id = m.id AND f.from_user_id = m.user1_id) as user1_feedback_submitted,
+				   EXISTS(SELECT 1 FROM velvet_hour_feedback f WHERE f.match_id = m.id AND f.from_user_id = m.user2_id) as user2_feedback_submitted,
+				   EXISTS(SELECT 1 FROM velvet_hour_feedback f WHERE f.match_id = m.id AND f.from_user_id = m.user3_id) as user3_feedback_submitted
 			FROM velvet_hour_matches m
 			JOIN users u1 ON m.user1_id = u1.id
 			JOIN users u2 ON m.user2_id = u2.id
-			LEFT JOIN velvet_hour_feedback f1 ON m.id = f1.match_id AND f1.from_user_id = m.user1_id
-			LEFT JOIN velvet_hour_feedback f2 ON m.id = f2.match_id AND f2.from_user_id = m.user2_id
+			LEFT JOIN users u3 ON m.user3_id = u3.id
 			WHERE m.session_id = $1 AND m.round_number = $2
+			ORDER BY m.status = 'abandoned', m.match_number
 		`, sessionPtr.ID, sessionPtr.CurrentRound)
 		
 		if err == nil {
@@ -592,11 +759,11 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 			for rows.Next() {
 				var m models.VelvetHourMatch
 				err := rows.Scan(
-					&m.ID, &m.SessionID, &m.RoundNumber, &m.User1ID, &m.User2ID,
-					&m.MatchNumber, &m.MatchColor, &m.StartedAt, &m.ConfirmedUser1,
-					&m.ConfirmedUser2, &m.ConfirmedAt, &m.CreatedAt, &m.UpdatedAt,
-					&m.User1Name, &m.User2Name,
-					&m.User1FeedbackSubmitted, &m.User2FeedbackSubmitted,
+					&m.ID, &m.SessionID, &m.RoundNumber, &m.User1ID, &m.User2ID, &m.User3ID,
+					&m.MatchNumber, &m.MatchColor, &m.TableID, &m.StartedAt, &m.ConfirmedUser1,
+					&m.ConfirmedUser2, &m.ConfirmedUser3, &m.ConfirmedAt, &m.Status, &m.CreatedAt, &m.UpdatedAt,
+					&m.User1Name, &m.User2Name, &m.User3Name,
+					&m.User1FeedbackSubmitted, &m.User2FeedbackSubmitted, &m.User3FeedbackSubmitted,
 				)
 				if err != nil {
 					log.Printf("Failed to scan match: %v", err)
@@ -605,36 +772,23 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 				currentMatches = append(currentMatches, m)
 			}
 		}
-	}
 
-	// Get event configuration
-	var config models.VelvetHourConfig
-	err = h.db.QueryRow(`
-		SELECT the_hour_round_duration, the_hour_break_duration, 
-			   the_hour_total_rounds
-		FROM events 
-		WHERE id = $1
-	`, eventID).Scan(
-		&config.RoundDuration, &config.BreakDuration,
-		&config.TotalRounds,
-	)
-	if err != nil {
-		log.Printf("Failed to get event config: %v", err)
-		// Use default values if config fetch fails
-		config = models.VelvetHourConfig{
-			RoundDuration:   10,
-			BreakDuration:   5,
-			TotalRounds:     4,
+		for i := range currentMatches {
+			members, err := loadMatchMembers(h.db, currentMatches[i].ID)
+			if err != nil {
+				log.Printf("Failed to get match members: %v", err)
+				continue
+			}
+			currentMatches[i].Group = members
+			currentMatches[i].Table, err = loadMatchTable(h.db, currentMatches[i].TableID)
+			if err != nil {
+				log.Printf("Failed to get match table: %v", err)
+			}
 		}
 	}
-	
-	// Calculate minimum participants based on total rounds (round-robin formula)
-	// For R rounds: n_min = R if R is odd, R+1 if R is even
-	if config.TotalRounds%2 == 0 {
-		config.MinParticipants = config.TotalRounds + 1
-	} else {
-		config.MinParticipants = config.TotalRounds
-	}
+
+	// Get event configuration
+	config := h.loadEventConfig(eventID)
 
 	// Determine if admin can start next round
 	canStartRound := false
@@ -643,6 +797,17 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 		canStartRound = len(participants) >= 2
 	}
 
+	// Get the planned schedule, including who sits out each round
+	schedule := []models.VelvetHourScheduleRound{}
+	if sessionPtr != nil {
+		planned, err := h.getSchedule(sessionPtr.ID)
+		if err != nil {
+			log.Printf("Failed to get schedule: %v", err)
+		} else {
+			schedule = planned
+		}
+	}
+
 	response := models.AdminVelvetHourStatusResponse{
 		Session:         sessionPtr,
 		Participants:    participants,
@@ -650,6 +815,7 @@ func (h *VelvetHourHandler) GetAdminStatus(w http.ResponseWriter, r *http.Reques
 		CompletedRounds: 0,
 		CanStartRound:   canStartRound,
 		Config:          config,
+		Schedule:        schedule,
 	}
 
 	// Calculate completed rounds
@@ -731,34 +897,43 @@ func (h *VelvetHourHandler) StartSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check if session already exists
-	var existingID uuid.UUID
-	err = h.db.QueryRow(`
-		SELECT id FROM velvet_hour_sessions 
-		WHERE event_id = $1 AND is_active = true
-	`, eventID).Scan(&existingID)
-	
-	if err == nil {
-		http.Error(w, "Session already active", http.StatusConflict)
+	// Create new session; only one may be active per event
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	// Create new session
-	var sessionID uuid.UUID
-	err = h.db.QueryRow(`
-		INSERT INTO velvet_hour_sessions (event_id, status)
-		VALUES ($1, 'waiting')
-		RETURNING id
-	`, eventID).Scan(&sessionID)
-	
+	sessionID, err := createSession(tx, eventID)
 	if err != nil {
-		log.Printf("Failed to create session: %v", err)
+		writeSessionError(w, err, "Failed to start session")
+		return
+	}
+
+	// Plan every round up front for the attendees in the room, so admins can
+	// see the full schedule before the first round starts
+	var present []uuid.UUID
+	if h.hub != nil {
+		present = h.hub.GetPresentUsers(eventID)
+	}
+	attendees, err := presentAttendees(tx, eventID, present, h.loadEventConfig(eventID).NoShowLimit)
+	var forbidden map[string]bool
+	if err == nil {
+		forbidden, err = h.forbiddenPairs(eventID, attendees)
+	}
+	if err == nil {
+		err = ensureSchedule(tx, sessionID, attendees, forbidden)
+	}
+	if err != nil {
+		log.Printf("Failed to plan schedule: %v", err)
 		http.Error(w, "Failed to start session", http.StatusInternalServerError)
 		return
 	}
 
 	// Update event to mark Velvet Hour as started
-	_, err = h.db.Exec(`
+	_, err = tx.Exec(`
 		UPDATE events SET the_hour_started = true WHERE id = $1
 	`, eventID)
 	
@@ -766,6 +941,12 @@ func (h *VelvetHourHandler) StartSession(w http.ResponseWriter, r *http.Request)
 		log.Printf("Failed to update event status: %v", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to create session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
 	// Broadcast session started event
 	if h.hub != nil {
 		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourSessionStarted, map[string]interface{}{
@@ -803,41 +984,91 @@ func (h *VelvetHourHandler) StartRound(w http.ResponseWriter, r *http.Request) {
 
 	// Get active session
 	var sessionID uuid.UUID
-	var currentRound int
 	err = h.db.QueryRow(`
-		SELECT id, current_round FROM velvet_hour_sessions 
+		SELECT id FROM velvet_hour_sessions 
 		WHERE event_id = $1 AND is_active = true
-	`, eventID).Scan(&sessionID, &currentRound)
+	`, eventID).Scan(&sessionID)
 	
 	if err != nil {
 		http.Error(w, "No active session", http.StatusBadRequest)
 		return
 	}
 
-	nextRound := currentRound + 1
+	nextRound, warnings, err := h.startRound(eventID, sessionID, req.Matches, req.ProposalID, false)
+	if err != nil {
+		writeSessionError(w, err, "Failed to start round")
+		return
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
 
-	// If manual matches provided, use them
-	if len(req.Matches) > 0 {
-		for _, match := range req.Matches {
-			_, err = h.db.Exec(`
-				INSERT INTO velvet_hour_matches 
-				(session_id, round_number, user1_id, user2_id, match_number, match_color)
-				VALUES ($1, $2, $3, $4, $5, $6)
-			`, sessionID, nextRound, match.User1ID, match.User2ID, match.MatchNumber, match.MatchColor)
-			
-			if err != nil {
-				log.Printf("Failed to create manual match: %v", err)
-				http.Error(w, "Failed to create matches", http.StatusInternalServerError)
-				return
-			}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  fmt.Sprintf("Round %d started successfully", nextRound),
+		"round":    nextRound,
+		"warnings": warnings,
+	})
+}
+
+// startRound creates the matches for the session's next round, starts its
+// timer and broadcasts the new round. It is shared by the admin endpoint and the
+// scheduler so both paths produce identical rounds. The session stays locked
+// until the round is stored, so a round can only be started once. A scheduled
+// start only goes ahead if the break is still due once the session is locked.
+func (h *VelvetHourHandler) startRound(eventID, sessionID uuid.UUID, manualMatches []models.ManualMatch, proposalID *uuid.UUID, scheduled bool) (int, []string, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, sessionID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if scheduled {
+		if err := session.requireDue(sessionStatusBreak, time.Now()); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := session.require(sessionStatusInRound); err != nil {
+		return 0, nil, err
+	}
+	nextRound := session.CurrentRound + 1
+
+	var warnings []string
+
+	// A previewed proposal goes live exactly as the admin reviewed it
+	if proposalID != nil {
+		matches, byes, err := h.takeProposal(tx, eventID, *proposalID, sessionID, nextRound)
+		if err != nil {
+			return 0, nil, err
+		}
+		warnings, err = createRound(tx, sessionID, nextRound, matches, byes)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create proposed matches: %w", err)
+		}
+	} else if len(manualMatches) > 0 {
+		// If manual matches provided, use them; anyone left out sits the round out
+		participants, err := h.getActiveParticipants(sessionID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get participants: %w", err)
+		}
+		if err := checkManualMatches(manualMatches, participants, h.loadEventConfig(eventID).GroupMode); err != nil {
+			return 0, nil, err
+		}
+		byes := manualByes(participants, manualMatches)
+
+		warnings, err = createRound(tx, sessionID, nextRound, manualMatches, byes)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create manual matches: %w", err)
 		}
 	} else {
 		// Generate automatic matches
-		err = h.generateMatches(sessionID, nextRound)
+		warnings, err = h.generateMatches(tx, sessionID, nextRound)
 		if err != nil {
-			log.Printf("Failed to generate matches: %v", err)
-			http.Error(w, "Failed to generate matches", http.StatusInternalServerError)
-			return
+			return 0, nil, fmt.Errorf("failed to generate matches: %w", err)
 		}
 	}
 
@@ -863,18 +1094,24 @@ func (h *VelvetHourHandler) StartRound(w http.ResponseWriter, r *http.Request) {
 	log.Printf("⏱️ VelvetHour StartRound: Round ends at (UTC)=%v", roundEnd)
 
 	// Update session to new round with timer
-	_, err = h.db.Exec(`
-		UPDATE velvet_hour_sessions 
-		SET current_round = $1, status = 'in_round', 
-			round_started_at = CURRENT_TIMESTAMP, round_ends_at = $2, 
-			updated_at = CURRENT_TIMESTAMP
-		WHERE id = $3
-	`, nextRound, roundEnd, sessionID)
-	
+	if err := session.beginRound(roundEnd); err != nil {
+		return 0, nil, err
+	}
+
+	var matchCount int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM velvet_hour_matches WHERE session_id = $1 AND round_number = $2 AND status = 'active'
+	`, sessionID, nextRound).Scan(&matchCount)
 	if err != nil {
-		log.Printf("Failed to update session round: %v", err)
-		http.Error(w, "Failed to start round", http.StatusInternalServerError)
-		return
+		log.Printf("Failed to count round matches: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to store round: %w", err)
+	}
+
+	for _, warning := range warnings {
+		log.Printf("⚠️ VelvetHour StartRound: round %d of session %s: %s", nextRound, sessionID, warning)
 	}
 
 	// Broadcast round started event
@@ -883,20 +1120,255 @@ func (h *VelvetHourHandler) StartRound(w http.ResponseWriter, r *http.Request) {
 			"sessionId":   sessionID,
 			"roundNumber": nextRound,
 			"status":      "waiting",
-			"matchCount":  len(req.Matches),
+			"matchCount":  matchCount,
 		})
+		if len(warnings) > 0 {
+			h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
+				"sessionId":    sessionID,
+				"status":       sessionStatusInRound,
+				"currentRound": nextRound,
+				"warnings":     warnings,
+			})
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": fmt.Sprintf("Round %d started successfully", nextRound),
-		"round":   nextRound,
-	})
+	return nextRound, warnings, nil
+}
+
+// generateMatches creates the matches for a round from the session's plan and
+// returns any warnings the planner raised
+func (h *VelvetHourHandler) generateMatches(tx *sql.Tx, sessionID uuid.UUID, roundNumber int) ([]string, error) {
+	matches, byes, warnings, err := h.planRound(tx, sessionID, roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	tableWarnings, err := createRound(tx, sessionID, roundNumber, matches, byes)
+	return append(warnings, tableWarnings...), err
+}
+
+// planRound works out a round's matches from the session's planned
+// round-robin schedule, pairing anyone the plan cannot place greedily and
+// handling an odd headcount according to the event's policy. With cross-event
+// memory on, pairs who met at a recent event are avoided unless that would
+// leave people unpaired, in which case a warning says so. Nothing about the
+// round itself is stored, though a session that has no schedule yet gets one
+// in tx. Events in pods mode get pods instead.
+func (h *VelvetHourHandler) planRound(tx *sql.Tx, sessionID uuid.UUID, roundNumber int) ([]models.ManualMatch, []uuid.UUID, []string, error) {
+	participants, err := h.getActiveParticipants(sessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	config := h.loadSessionConfig(sessionID)
+	if config.GroupMode == groupModePods {
+		return h.planPods(sessionID, roundNumber, participants, config)
+	}
+
+	// Get previous matches to avoid repeating pairs
+	previousPairs, err := h.loadPreviousPairs(sessionID, roundNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Pairs the event's hard constraints rule out are skipped like pairs who already met
+	var eventID uuid.UUID
+	err = h.db.QueryRow(`
+		SELECT event_id FROM velvet_hour_sessions WHERE id = $1
+	`, sessionID).Scan(&eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	forbidden, err := h.forbiddenPairs(eventID, participants)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := ensureSchedule(tx, sessionID, participants, forbidden); err != nil {
+		return nil, nil, nil, err
+	}
+	planned, err := loadPlannedRound(tx, sessionID, roundNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	byeCounts, err := h.getByeCounts(sessionID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sessionExcluded := make(map[string]bool, len(forbidden)+len(previousPairs))
+	for key := range forbidden {
+		sessionExcluded[key] = true
+	}
+	for key := range previousPairs {
+		sessionExcluded[key] = true
+	}
+
+	// Pairs from recent events are avoided on top of this session's
+	excludedPairs := sessionExcluded
+	var pastPairs map[string]bool
+	if config.CrossEventMemory {
+		pastPairs, err = h.loadPastEventPairs(eventID, participants, config.MemoryLookbackDays)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		excludedPairs = make(map[string]bool, len(sessionExcluded)+len(pastPairs))
+		for key := range sessionExcluded {
+			excludedPairs[key] = true
+		}
+		for key := range pastPairs {
+			excludedPairs[key] = true
+		}
+	}
+
+	// Take the planned pairs whose members are both still taking part
+	active := make(map[uuid.UUID]bool)
+	for _, userID := range participants {
+		active[userID] = true
+	}
+	used := make(map[uuid.UUID]bool)
+	var pairs [][2]uuid.UUID
+	for _, pair := range planned {
+		if pair.User2 == uuid.Nil || !active[pair.User1] || !active[pair.User2] {
+			continue
+		}
+		if excludedPairs[pairKey(pair.User1, pair.User2)] {
+			continue
+		}
+		pairs = append(pairs, [2]uuid.UUID{pair.User1, pair.User2})
+		used[pair.User1] = true
+		used[pair.User2] = true
+	}
+
+	// Late joiners, planned byes and people whose planned partner left
+	var unplaced []uuid.UUID
+	for _, userID := range participants {
+		if !used[userID] {
+			unplaced = append(unplaced, userID)
+		}
+	}
+
+	// With rotating byes, whoever has sat out least takes this round's bye
+	var byes []uuid.UUID
+	if config.OddPolicy == oddPolicyRotatingBye && len(unplaced)%2 == 1 {
+		bye := pickByeUser(unplaced, byeCounts)
+		byes = append(byes, bye)
+		unplaced = removeUser(unplaced, bye)
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	fallback, err := h.findUniquePairings(rng, unplaced, excludedPairs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// When avoiding earlier events leaves people unpaired, let those pairs meet
+	// again rather than have anyone sit out for it
+	var warnings []string
+	if len(pastPairs) > 0 && len(unplaced)-2*len(fallback) > len(unplaced)%2 {
+		relaxed, err := h.findUniquePairings(rng, unplaced, sessionExcluded)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(relaxed) > len(fallback) {
+			fallback = relaxed
+			repeats := 0
+			for _, pair := range relaxed {
+				if pastPairs[pairKey(pair[0], pair[1])] {
+					repeats++
+				}
+			}
+			warnings = append(warnings, fmt.Sprintf("%d pair(s) already met at an event in the last %d days; nobody else was free to pair them with", repeats, config.MemoryLookbackDays))
+		}
+	}
+	pairs = append(pairs, fallback...)
+
+	var unmatched []uuid.UUID
+	for _, pair := range fallback {
+		used[pair[0]] = true
+		used[pair[1]] = true
+	}
+	for _, userID := range unplaced {
+		if !used[userID] {
+			unmatched = append(unmatched, userID)
+		}
+	}
+
+	matches, extraByes := resolveOddHeadcount(pairs, unmatched, config.OddPolicy, excludedPairs, forbidden)
+	byes = append(byes, extraByes...)
+
+	return numberMatches(matches), byes, warnings, nil
+}
+
+// matchColors are handed out to matches by match number
+var matchColors = []string{"red", "blue", "green", "purple", "orange", "yellow", "pink", "cyan"}
+
+// numberMatches gives matches without a number or color one, in order
+func numberMatches(matches []models.ManualMatch) []models.ManualMatch {
+	numbered := make([]models.ManualMatch, len(matches))
+	for i, match := range matches {
+		if match.MatchNumber == 0 {
+			match.MatchNumber = i + 1
+		}
+		if match.MatchColor == "" {
+			match.MatchColor = matchColors[match.MatchNumber%len(matchColors)]
+		}
+		numbered[i] = match
+	}
+	return numbered
+}
+
+// manualByes returns the participants a hand-made set of matches leaves out
+func manualByes(participants []uuid.UUID, matches []models.ManualMatch) []uuid.UUID {
+	matched := make(map[uuid.UUID]bool)
+	for _, match := range matches {
+		for _, member := range match.Members() {
+			matched[member] = true
+		}
+	}
+	var byes []uuid.UUID
+	for _, userID := range participants {
+		if !matched[userID] {
+			byes = append(byes, userID)
+		}
+	}
+	return byes
+}
+
+// createRound stores a round's matches and records everyone sitting it out.
+// Matches without a number or color are numbered in order, and matches are
+// sent to tables when the event has a venue layout. It returns a warning for
+// every match left without a table.
+func createRound(tx *sql.Tx, sessionID uuid.UUID, roundNumber int, matches []models.ManualMatch, byes []uuid.UUID) ([]string, error) {
+	seated, warnings, err := assignTables(tx, sessionID, roundNumber, numberMatches(matches))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range seated {
+		_, err := insertRoundMatch(tx, sessionID, roundNumber, match.Members(), match.MatchNumber, match.MatchColor, match.TableID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, userID := range byes {
+		_, err := tx.Exec(`
+			INSERT INTO velvet_hour_byes (session_id, round_number, user_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (session_id, round_number, user_id) DO NOTHING
+		`, sessionID, roundNumber, userID)
+		if err == nil {
+			err = recordSessionEvent(tx, sessionID, sessionEventBye, roundNumber, &userID, nil, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return warnings, nil
 }
 
-// generateMatches creates automatic matches for a round ensuring unique pairings
-func (h *VelvetHourHandler) generateMatches(sessionID uuid.UUID, roundNumber int) error {
-	// Get active participants
+// getActiveParticipants returns the users still taking part in a session
+func (h *VelvetHourHandler) getActiveParticipants(sessionID uuid.UUID) ([]uuid.UUID, error) {
 	participants := []uuid.UUID{}
 	rows, err := h.db.Query(`
 		SELECT user_id FROM velvet_hour_participants 
@@ -905,7 +1377,7 @@ func (h *VelvetHourHandler) generateMatches(sessionID uuid.UUID, roundNumber int
 	`, sessionID)
 	
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -917,58 +1389,76 @@ func (h *VelvetHourHandler) generateMatches(sessionID uuid.UUID, roundNumber int
 		participants = append(participants, userID)
 	}
 
-	// Get previous matches to avoid repeating pairs
-	previousPairs := make(map[string]bool)
-	pairRows, err := h.db.Query(`
-		SELECT user1_id, user2_id FROM velvet_hour_matches 
-		WHERE session_id = $1 AND round_number < $2
-	`, sessionID, roundNumber)
-	
+	return participants, rows.Err()
+}
+
+// loadPreviousPairs returns every pair of users who already met in an earlier
+// round of the session, including pairs formed inside a trio or a pod
+func (h *VelvetHourHandler) loadPreviousPairs(sessionID uuid.UUID, roundNumber int) (map[string]bool, error) {
+	encounters, err := h.loadEncounters(sessionID, roundNumber)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer pairRows.Close()
 
-	for pairRows.Next() {
-		var user1, user2 uuid.UUID
-		if err := pairRows.Scan(&user1, &user2); err != nil {
-			continue
-		}
-		// Store both directions to ensure no repeat pairings
-		key1 := user1.String() + "_" + user2.String()
-		key2 := user2.String() + "_" + user1.String()
-		previousPairs[key1] = true
-		previousPairs[key2] = true
+	previousPairs := make(map[string]bool, len(encounters))
+	for key := range encounters {
+		previousPairs[key] = true
+	}
+	return previousPairs, nil
+}
+
+// loadPastEventPairs returns every pair of the given users who matched at
+// another event within the last lookbackDays days. No-shows and matches cut
+// short don't count as having met.
+func (h *VelvetHourHandler) loadPastEventPairs(eventID uuid.UUID, userIDs []uuid.UUID, lookbackDays int) (map[string]bool, error) {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
 	}
 
-	// Generate unique pairings using a greedy algorithm
-	matches, err := h.findUniquePairings(participants, previousPairs)
+	rows, err := h.db.Query(`
+		SELECT mm.match_id, mm.user_id
+		FROM velvet_hour_match_members mm
+		JOIN velvet_hour_matches m ON mm.match_id = m.id
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		WHERE s.event_id != $1 AND m.status = 'active'
+		  AND m.created_at >= CURRENT_TIMESTAMP - make_interval(days => $3)
+		  AND m.id IN (SELECT match_id FROM velvet_hour_match_members WHERE user_id = ANY($2::uuid[]))
+		ORDER BY mm.match_id, mm.position
+	`, eventID, pq.Array(ids), lookbackDays)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	groups, err := scanMatchGroups(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create matches in database
-	colors := []string{"red", "blue", "green", "purple", "orange", "yellow", "pink", "cyan"}
-	for i, match := range matches {
-		matchNumber := i + 1
-		color := colors[matchNumber%len(colors)]
-		
-		_, err = h.db.Exec(`
-			INSERT INTO velvet_hour_matches 
-			(session_id, round_number, user1_id, user2_id, match_number, match_color)
-			VALUES ($1, $2, $3, $4, $5, $6)
-		`, sessionID, roundNumber, match[0], match[1], matchNumber, color)
-		
-		if err != nil {
-			return err
+	pastPairs := make(map[string]bool)
+	for _, group := range groups {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				addPair(pastPairs, group[i], group[j])
+			}
 		}
 	}
+	return pastPairs, nil
+}
 
-	return nil
+// pairKey identifies an ordered pair of users in a previous-pairs set
+func pairKey(user1, user2 uuid.UUID) string {
+	return user1.String() + "_" + user2.String()
+}
+
+// addPair stores both directions of a pair to ensure no repeat pairings
+func addPair(pairs map[string]bool, user1, user2 uuid.UUID) {
+	pairs[pairKey(user1, user2)] = true
+	pairs[pairKey(user2, user1)] = true
 }
 
-// findUniquePairings implements a greedy algorithm for unique pairing across rounds
-func (h *VelvetHourHandler) findUniquePairings(participants []uuid.UUID, previousPairs map[string]bool) ([][2]uuid.UUID, error) {
+// findUniquePairings implements a greedy algorithm for unique pairing across rounds,
+// shuffling with rng
+func (h *VelvetHourHandler) findUniquePairings(rng *rand.Rand, participants []uuid.UUID, previousPairs map[string]bool) ([][2]uuid.UUID, error) {
 	n := len(participants)
 	if n < 2 {
 		return [][2]uuid.UUID{}, nil
@@ -978,11 +1468,10 @@ func (h *VelvetHourHandler) findUniquePairings(participants []uuid.UUID, previou
 	used := make(map[uuid.UUID]bool)
 	
 	// Shuffle participants for randomization
-	rand.Seed(time.Now().UnixNano())
 	shuffled := make([]uuid.UUID, len(participants))
 	copy(shuffled, participants)
 	for i := range shuffled {
-		j := rand.Intn(i + 1)
+		j := rng.Intn(i + 1)
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	}
 
@@ -999,8 +1488,7 @@ func (h *VelvetHourHandler) findUniquePairings(participants []uuid.UUID, previou
 			}
 			
 			// Check if this pair has met before
-			key := user1.String() + "_" + user2.String()
-			if !previousPairs[key] {
+			if !previousPairs[pairKey(user1, user2)] {
 				// Found a valid pair
 				matches = append(matches, [2]uuid.UUID{user1, user2})
 				used[user1] = true
@@ -1038,21 +1526,29 @@ func (h *VelvetHourHandler) EndSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update session to inactive
-	_, err = h.db.Exec(`
-		UPDATE velvet_hour_sessions 
-		SET is_active = false, ended_at = CURRENT_TIMESTAMP, 
-			status = 'completed', updated_at = CURRENT_TIMESTAMP
-		WHERE event_id = $1 AND is_active = true
-	`, eventID)
-	
+	tx, err := h.db.Begin()
 	if err != nil {
-		log.Printf("Failed to end session: %v", err)
+		log.Printf("Failed to start transaction: %v", err)
 		http.Error(w, "Failed to end session", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, sessionID)
+	if err == errNoActiveSession {
+		http.Error(w, "No active session found", http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		err = session.end()
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to end session")
+		return
+	}
 
 	// Update event status
-	_, err = h.db.Exec(`
+	_, err = tx.Exec(`
 		UPDATE events SET the_hour_started = false WHERE id = $1
 	`, eventID)
 	
@@ -1060,6 +1556,12 @@ func (h *VelvetHourHandler) EndSession(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to update event status: %v", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to end session: %v", err)
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+
 	// Broadcast session ended event
 	if h.hub != nil {
 		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourSessionEnded, map[string]interface{}{
@@ -1105,9 +1607,77 @@ func (h *VelvetHourHandler) UpdateEventConfig(w http.ResponseWriter, r *http.Req
 		args = append(args, *req.BreakDuration)
 		argIndex++
 	}
-	if req.TotalRounds != nil {
-		updates = append(updates, fmt.Sprintf("the_hour_total_rounds = $%d", argIndex))
-		args = append(args, *req.TotalRounds)
+	if req.TotalRounds != nil {
+		updates = append(updates, fmt.Sprintf("the_hour_total_rounds = $%d", argIndex))
+		args = append(args, *req.TotalRounds)
+		argIndex++
+	}
+	if req.AutoAdvance != nil {
+		updates = append(updates, fmt.Sprintf("the_hour_auto_advance = $%d", argIndex))
+		args = append(args, *req.AutoAdvance)
+		argIndex++
+	}
+	if req.OddPolicy != nil {
+		if !validOddPolicies[*req.OddPolicy] {
+			http.Error(w, "Invalid odd policy. Must be 'rotating_bye' or 'trio'", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_odd_policy = $%d", argIndex))
+		args = append(args, *req.OddPolicy)
+		argIndex++
+	}
+	if req.ConfirmationWindow != nil {
+		if *req.ConfirmationWindow < 0 {
+			http.Error(w, "Confirmation window cannot be negative", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_confirmation_window = $%d", argIndex))
+		args = append(args, *req.ConfirmationWindow)
+		argIndex++
+	}
+	if req.NoShowLimit != nil {
+		if *req.NoShowLimit < 0 {
+			http.Error(w, "No-show limit cannot be negative", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_no_show_limit = $%d", argIndex))
+		if *req.NoShowLimit == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.NoShowLimit)
+		}
+		argIndex++
+	}
+	if req.CrossEventMemory != nil {
+		updates = append(updates, fmt.Sprintf("the_hour_cross_event_memory = $%d", argIndex))
+		args = append(args, *req.CrossEventMemory)
+		argIndex++
+	}
+	if req.MemoryLookbackDays != nil {
+		if *req.MemoryLookbackDays < 1 {
+			http.Error(w, "Memory lookback must be at least one day", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_memory_lookback_days = $%d", argIndex))
+		args = append(args, *req.MemoryLookbackDays)
+		argIndex++
+	}
+	if req.GroupMode != nil {
+		if !validGroupModes[*req.GroupMode] {
+			http.Error(w, "Invalid group mode. Must be 'pairs' or 'pods'", http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_group_mode = $%d", argIndex))
+		args = append(args, *req.GroupMode)
+		argIndex++
+	}
+	if req.PodSize != nil {
+		if *req.PodSize < minPodSize || *req.PodSize > maxPodSize {
+			http.Error(w, fmt.Sprintf("Pod size must be between %d and %d", minPodSize, maxPodSize), http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, fmt.Sprintf("the_hour_pod_size = $%d", argIndex))
+		args = append(args, *req.PodSize)
 		argIndex++
 	}
 	// MinParticipants is auto-calculated based on TotalRounds, not user-configurable
@@ -1168,7 +1738,8 @@ func (h *VelvetHourHandler) ResetSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
-	// If session exists, delete all related data
+	// If session exists, delete all related data. No-shows and mutual
+	// connections are kept; they only lose their session and match.
 	if err != sql.ErrNoRows {
 		// Delete feedback (references matches)
 		_, err = tx.Exec(`
@@ -1191,6 +1762,22 @@ func (h *VelvetHourHandler) ResetSession(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// Delete recorded byes
+		_, err = tx.Exec(`DELETE FROM velvet_hour_byes WHERE session_id = $1`, sessionID)
+		if err != nil {
+			log.Printf("Failed to delete byes: %v", err)
+			http.Error(w, "Failed to reset session", http.StatusInternalServerError)
+			return
+		}
+
+		// Delete planned schedule
+		_, err = tx.Exec(`DELETE FROM velvet_hour_schedule WHERE session_id = $1`, sessionID)
+		if err != nil {
+			log.Printf("Failed to delete schedule: %v", err)
+			http.Error(w, "Failed to reset session", http.StatusInternalServerError)
+			return
+		}
+
 		// Delete participants
 		_, err = tx.Exec(`DELETE FROM velvet_hour_participants WHERE session_id = $1`, sessionID)
 		if err != nil {
@@ -1200,7 +1787,7 @@ func (h *VelvetHourHandler) ResetSession(w http.ResponseWriter, r *http.Request)
 		}
 
 		// Delete session
-		_, err = tx.Exec(`DELETE FROM velvet_hour_sessions WHERE id = $1`, sessionID)
+		err = deleteSession(tx, sessionID)
 		if err != nil {
 			log.Printf("Failed to delete session: %v", err)
 			http.Error(w, "Failed to reset session", http.StatusInternalServerError)
@@ -1551,258 +2138,114 @@ func (h *VelvetHourHandler) GenerateAIMatches(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Get active participants who are present
-	var presentUserIDs []uuid.UUID
-	if h.hub != nil {
-		presentUserIDs = h.hub.GetPresentUsers(eventID)
+	// AI matching scores pairs, so it can't plan a round of pods
+	config := h.loadEventConfig(eventID)
+	if config.GroupMode == groupModePods {
+		http.Error(w, "AI matching is only available when the event matches people in pairs", http.StatusBadRequest)
+		return
 	}
 
-	if len(presentUserIDs) < 2 {
-		http.Error(w, "Not enough participants present", http.StatusBadRequest)
+	// Suggest matches for the people who joined the session
+	participants, err := h.getActiveParticipants(sessionID)
+	if err != nil {
+		log.Printf("Failed to get participants: %v", err)
+		http.Error(w, "Failed to get participants", http.StatusInternalServerError)
 		return
 	}
-
-	// Get user preferences for matching
-	userPreferences := make(map[uuid.UUID]map[string]interface{})
-	
-	for _, userID := range presentUserIDs {
-		var preferences map[string]interface{} = make(map[string]interface{})
-		
-		// Get user's survey responses
-		var primaryInterest, whyHere, professionalBackground sql.NullString
-		
-		err := h.db.QueryRow(`
-			SELECT primary_interest, why_here, professional_background
-			FROM survey_responses 
-			WHERE user_id = $1 AND event_id = $2
-		`, userID, eventID).Scan(&primaryInterest, &whyHere, &professionalBackground)
-		
-		if err == nil {
-			preferences["primaryInterest"] = primaryInterest.String
-			preferences["whyHere"] = whyHere.String
-			preferences["professionalBackground"] = professionalBackground.String
-		}
-
-		// Get cocktail preferences 
-		var cocktailPrefs []string
-		prefRows, err := h.db.Query(`
-			SELECT preference_type 
-			FROM cocktail_preferences 
-			WHERE user_id = $1 AND event_id = $2
-		`, userID, eventID)
-		
-		if err == nil {
-			defer prefRows.Close()
-			for prefRows.Next() {
-				var pref string
-				if err := prefRows.Scan(&pref); err == nil {
-					cocktailPrefs = append(cocktailPrefs, pref)
-				}
-			}
-		}
-		
-		preferences["cocktailPreferences"] = cocktailPrefs
-		userPreferences[userID] = preferences
+	if len(participants) < 2 {
+		http.Error(w, "Not enough participants in the session", http.StatusBadRequest)
+		return
 	}
 
-	// Generate matches based on preferences
-	matches := h.generateAIMatchingAlgorithm(presentUserIDs, userPreferences)
-	
-	// Convert to ManualMatch format
-	var manualMatches []models.ManualMatch
-	colors := []string{"red", "blue", "green", "purple", "orange", "yellow", "pink", "cyan", "indigo", "teal", "lime", "rose", "amber", "emerald", "violet", "sky"}
-	
-	for i, match := range matches {
-		if i >= 100 { // Limit to 100 rooms
-			break
-		}
-		
-		color := colors[i%len(colors)]
-		if i >= len(colors) {
-			// For rooms beyond basic colors, add modifiers
-			modifier := ""
-			switch (i / len(colors)) % 6 {
-			case 1:
-				modifier = "-dark"
-			case 2:
-				modifier = "-light"
-			case 3:
-				modifier = "-bright"
-			case 4:
-				modifier = "-deep"
-			case 5:
-				modifier = "-pale"
-			}
-			color = colors[i%len(colors)] + modifier
-		}
-		
-		manualMatches = append(manualMatches, models.ManualMatch{
-			User1ID:     match[0],
-			User2ID:     match[1],
-			MatchNumber: i + 1,
-			MatchColor:  color,
-		})
+	// Get survey answers and drink preferences for matching
+	profiles, err := h.loadMatchProfiles(eventID, participants)
+	if err != nil {
+		log.Printf("Failed to load match profiles: %v", err)
+		http.Error(w, "Failed to load participant preferences", http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(manualMatches)
-}
-
-// generateAIMatchingAlgorithm implements AI matching based on user preferences
-func (h *VelvetHourHandler) generateAIMatchingAlgorithm(userIDs []uuid.UUID, preferences map[uuid.UUID]map[string]interface{}) [][2]uuid.UUID {
-	var matches [][2]uuid.UUID
-	used := make(map[uuid.UUID]bool)
-	
-	// Shuffle for randomization
-	rand.Seed(time.Now().UnixNano())
-	shuffled := make([]uuid.UUID, len(userIDs))
-	copy(shuffled, userIDs)
-	for i := range shuffled {
-		j := rand.Intn(i + 1)
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	var currentRound int
+	err = h.db.QueryRow(`
+		SELECT current_round FROM velvet_hour_sessions WHERE id = $1
+	`, sessionID).Scan(&currentRound)
+	if err != nil {
+		log.Printf("Failed to get current round: %v", err)
 	}
-
-	// Smart matching algorithm
-	for _, user1 := range shuffled {
-		if used[user1] {
-			continue
-		}
-		
-		bestMatch := uuid.Nil
-		bestScore := -1.0
-		
-		// Find best match for user1
-		for _, user2 := range shuffled {
-			if used[user2] || user1 == user2 {
-				continue
-			}
-			
-			score := h.calculateCompatibilityScore(user1, user2, preferences)
-			if score > bestScore {
-				bestScore = score
-				bestMatch = user2
-			}
-		}
-		
-		if bestMatch != uuid.Nil {
-			matches = append(matches, [2]uuid.UUID{user1, bestMatch})
-			used[user1] = true
-			used[bestMatch] = true
-		}
+	previousPairs, err := h.loadPreviousPairs(sessionID, currentRound+1)
+	if err != nil {
+		log.Printf("Failed to load previous pairs: %v", err)
+		previousPairs = make(map[string]bool)
 	}
-	
-	return matches
-}
 
-// calculateCompatibilityScore calculates compatibility between two users
-func (h *VelvetHourHandler) calculateCompatibilityScore(user1, user2 uuid.UUID, preferences map[uuid.UUID]map[string]interface{}) float64 {
-	pref1 := preferences[user1]
-	pref2 := preferences[user2]
-	
-	if pref1 == nil || pref2 == nil {
-		return rand.Float64() // Random if no preferences
-	}
-	
-	score := 0.0
-	totalWeight := 0.0
-	
-	// Compare professional backgrounds (weight: 0.3)
-	if bg1, ok := pref1["professionalBackground"].(string); ok {
-		if bg2, ok := pref2["professionalBackground"].(string); ok && bg1 != "" && bg2 != "" {
-			if bg1 == bg2 {
-				score += 0.3 * 1.0 // Same background = perfect match
-			} else if h.relatedProfessions(bg1, bg2) {
-				score += 0.3 * 0.7 // Related backgrounds = good match
-			} else {
-				score += 0.3 * 0.3 // Different = complement each other
-			}
-			totalWeight += 0.3
+	// With rotating byes, set aside whoever has sat out least before matching
+	candidates := participants
+	if config.OddPolicy == oddPolicyRotatingBye && len(candidates)%2 == 1 {
+		byeCounts, err := h.getByeCounts(sessionID)
+		if err != nil {
+			log.Printf("Failed to get bye counts: %v", err)
 		}
+		candidates = removeUser(candidates, pickByeUser(candidates, byeCounts))
 	}
-	
-	// Compare primary interests (weight: 0.4)
-	if int1, ok := pref1["primaryInterest"].(string); ok {
-		if int2, ok := pref2["primaryInterest"].(string); ok && int1 != "" && int2 != "" {
-			if int1 == int2 {
-				score += 0.4 * 0.8 // Same interest = good match
-			} else {
-				score += 0.4 * 0.5 // Different interests = learn from each other
-			}
-			totalWeight += 0.4
-		}
+
+	matchingConfig, err := h.loadMatchingConfig(eventID)
+	if err != nil {
+		log.Printf("Failed to load matching config: %v", err)
+		matchingConfig = defaultMatchingConfig(eventID)
 	}
-	
-	// Compare cocktail preferences (weight: 0.3)
-	if prefs1, ok := pref1["cocktailPreferences"].([]string); ok {
-		if prefs2, ok := pref2["cocktailPreferences"].([]string); ok {
-			overlap := h.calculateOverlap(prefs1, prefs2)
-			score += 0.3 * overlap
-			totalWeight += 0.3
-		}
+
+	// Blocked pairs are skipped like pairs who already met
+	excludedPairs, err := h.loadExcludedPairs(eventID, participants)
+	if err != nil {
+		log.Printf("Failed to load excluded pairs: %v", err)
+		http.Error(w, "Failed to load do-not-pair list", http.StatusInternalServerError)
+		return
 	}
-	
-	if totalWeight == 0 {
-		return rand.Float64() // Random if no comparable data
+	for key := range excludedPairs {
+		previousPairs[key] = true
 	}
-	
-	// Add small random factor for variety
-	finalScore := (score / totalWeight) + (rand.Float64() * 0.1)
-	return finalScore
-}
 
-// relatedProfessions checks if two professions are related
-func (h *VelvetHourHandler) relatedProfessions(prof1, prof2 string) bool {
-	prof1 = strings.ToLower(prof1)
-	prof2 = strings.ToLower(prof2)
-	
-	// Define related profession groups
-	techFields := []string{"software", "engineer", "developer", "programmer", "tech", "it", "computer"}
-	businessFields := []string{"business", "management", "marketing", "sales", "finance", "consulting"}
-	creativeFields := []string{"design", "creative", "art", "media", "content", "writing"}
-	healthFields := []string{"health", "medical", "doctor", "nurse", "therapy"}
-	
-	groups := [][]string{techFields, businessFields, creativeFields, healthFields}
-	
-	for _, group := range groups {
-		found1, found2 := false, false
-		for _, field := range group {
-			if strings.Contains(prof1, field) {
-				found1 = true
-			}
-			if strings.Contains(prof2, field) {
-				found2 = true
-			}
+	// Pair for the highest overall compatibility without repeating earlier pairs
+	avoided := previousPairs
+	pairs := weightedPairings(candidates, profiles, matchingConfig, previousPairs)
+
+	// With cross-event memory on, pairs who met at a recent event are avoided
+	// too, unless that would leave more people unpaired
+	if config.CrossEventMemory {
+		pastPairs, err := h.loadPastEventPairs(eventID, participants, config.MemoryLookbackDays)
+		if err != nil {
+			log.Printf("Failed to load past event pairs: %v", err)
+			http.Error(w, "Failed to load past pairings", http.StatusInternalServerError)
+			return
+		}
+		withMemory := make(map[string]bool, len(previousPairs)+len(pastPairs))
+		for key := range previousPairs {
+			withMemory[key] = true
 		}
-		if found1 && found2 {
-			return true
+		for key := range pastPairs {
+			withMemory[key] = true
+		}
+		if remembered := weightedPairings(candidates, profiles, matchingConfig, withMemory); len(remembered) >= len(pairs) {
+			avoided, pairs = withMemory, remembered
 		}
 	}
-	
-	return false
-}
 
-// calculateOverlap calculates overlap between two string slices
-func (h *VelvetHourHandler) calculateOverlap(slice1, slice2 []string) float64 {
-	if len(slice1) == 0 || len(slice2) == 0 {
-		return 0.5 // Neutral if one is empty
+	matchedUsers := make(map[uuid.UUID]bool)
+	for _, pair := range pairs {
+		matchedUsers[pair[0]] = true
+		matchedUsers[pair[1]] = true
 	}
-	
-	overlap := 0
-	for _, item1 := range slice1 {
-		for _, item2 := range slice2 {
-			if strings.EqualFold(item1, item2) {
-				overlap++
-				break
-			}
+	var unmatched []uuid.UUID
+	for _, userID := range candidates {
+		if !matchedUsers[userID] {
+			unmatched = append(unmatched, userID)
 		}
 	}
-	
-	maxLen := len(slice1)
-	if len(slice2) > maxLen {
-		maxLen = len(slice2)
-	}
-	
-	return float64(overlap) / float64(maxLen)
+	matches, _ := resolveOddHeadcount(pairs, unmatched, config.OddPolicy, avoided, excludedPairs)
+
+	// Number and color the matches the same way a live round does
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(numberMatches(matches))
 }
 
 // GetUserPreferences returns user preferences for the admin interface
@@ -1823,41 +2266,22 @@ func (h *VelvetHourHandler) GetUserPreferences(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	preferences := make(map[string]interface{})
-	
-	// Get survey responses
-	var primaryInterest, whyHere, professionalBackground sql.NullString
-	err = h.db.QueryRow(`
-		SELECT primary_interest, why_here, professional_background
-		FROM survey_responses 
-		WHERE user_id = $1 AND event_id = $2
-	`, userID, eventID).Scan(&primaryInterest, &whyHere, &professionalBackground)
-	
-	if err == nil {
-		preferences["primaryInterest"] = primaryInterest.String
-		preferences["whyHere"] = whyHere.String
-		preferences["professionalBackground"] = professionalBackground.String
+	profiles, err := h.loadMatchProfiles(eventID, []uuid.UUID{userID})
+	if err != nil {
+		log.Printf("Failed to load user preferences: %v", err)
+		http.Error(w, "Failed to load user preferences", http.StatusInternalServerError)
+		return
 	}
 
-	// Get cocktail preferences
-	var interests []string
-	rows, err := h.db.Query(`
-		SELECT preference_type 
-		FROM cocktail_preferences 
-		WHERE user_id = $1 AND event_id = $2
-	`, userID, eventID)
-	
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var interest string
-			if err := rows.Scan(&interest); err == nil {
-				interests = append(interests, interest)
-			}
-		}
+	profile := profiles[userID]
+	preferences := map[string]interface{}{
+		"age":                profile.Age,
+		"gender":             profile.Gender,
+		"torontoMeaning":     profile.TorontoMeaning,
+		"personality":        profile.Personality,
+		"connectionType":     profile.ConnectionType,
+		"cocktailPreference": profile.CocktailPreference,
 	}
-	
-	preferences["interests"] = interests
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(preferences)
@@ -1876,34 +2300,61 @@ func (h *VelvetHourHandler) CloseRound(w http.ResponseWriter, r *http.Request) {
 
 	// Get active session
 	var sessionID uuid.UUID
-	var currentRound int
 	var totalRounds int
 	err = h.db.QueryRow(`
-		SELECT s.id, s.current_round, e.the_hour_total_rounds
+		SELECT s.id, e.the_hour_total_rounds
 		FROM velvet_hour_sessions s
 		JOIN events e ON s.event_id = e.id
 		WHERE s.event_id = $1 AND s.is_active = true
-	`, eventID).Scan(&sessionID, &currentRound, &totalRounds)
+	`, eventID).Scan(&sessionID, &totalRounds)
 	
 	if err != nil {
 		http.Error(w, "No active session", http.StatusBadRequest)
 		return
 	}
 
+	if err := h.closeRound(eventID, sessionID, totalRounds, false); err != nil {
+		writeSessionError(w, err, "Failed to close round")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// closeRound ends the current round, either completing the session after the
+// final round or starting the break before the next one. The session must be
+// in a round; a scheduled close also needs the round to still be due once the
+// session is locked.
+func (h *VelvetHourHandler) closeRound(eventID, sessionID uuid.UUID, totalRounds int, scheduled bool) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if scheduled {
+		if err := session.requireDue(sessionStatusInRound, time.Now()); err != nil {
+			return err
+		}
+	}
+	if session.Status != sessionStatusInRound {
+		return &sessionTransitionError{From: session.Status, To: sessionStatusBreak}
+	}
+	currentRound := session.CurrentRound
+
 	// Check if this is the last round
 	if currentRound >= totalRounds {
 		// Final round - end session
-		_, err = h.db.Exec(`
-			UPDATE velvet_hour_sessions 
-			SET status = 'completed', ended_at = CURRENT_TIMESTAMP, 
-				updated_at = CURRENT_TIMESTAMP
-			WHERE id = $1
-		`, sessionID)
-		
-		if err != nil {
-			log.Printf("Failed to complete session: %v", err)
-			http.Error(w, "Failed to complete session", http.StatusInternalServerError)
-			return
+		if err := session.complete(); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to complete session: %w", err)
 		}
 
 		// Broadcast session ended
@@ -1913,50 +2364,156 @@ func (h *VelvetHourHandler) CloseRound(w http.ResponseWriter, r *http.Request) {
 				"reason":    "completed",
 			})
 		}
-	} else {
-		// Not final round - start break
-		var breakDuration int
-		err = h.db.QueryRow(`
-			SELECT the_hour_break_duration
-			FROM events 
-			WHERE id = $1
-		`, eventID).Scan(&breakDuration)
-		
-		if err != nil {
-			log.Printf("Failed to get break duration: %v", err)
-			breakDuration = 5 // Default to 5 minutes
-		}
+		return nil
+	}
 
-		breakEnd := time.Now().UTC().Add(time.Duration(breakDuration) * time.Minute)
-		log.Printf("🏁 VelvetHour CloseRound: Starting break for %d minutes, ends at (UTC)=%v", breakDuration, breakEnd)
+	// Not final round - start break
+	var breakDuration int
+	err = h.db.QueryRow(`
+		SELECT the_hour_break_duration
+		FROM events 
+		WHERE id = $1
+	`, eventID).Scan(&breakDuration)
+	
+	if err != nil {
+		log.Printf("Failed to get break duration: %v", err)
+		breakDuration = 5 // Default to 5 minutes
+	}
 
-		// Update session to break status
-		_, err = h.db.Exec(`
-			UPDATE velvet_hour_sessions 
-			SET status = 'break', round_ends_at = $1, 
-				updated_at = CURRENT_TIMESTAMP
-			WHERE id = $2
-		`, breakEnd, sessionID)
-		
-		if err != nil {
-			log.Printf("Failed to start break: %v", err)
-			http.Error(w, "Failed to start break", http.StatusInternalServerError)
-			return
-		}
+	breakEnd := time.Now().UTC().Add(time.Duration(breakDuration) * time.Minute)
+	log.Printf("🏁 VelvetHour CloseRound: Starting break for %d minutes, ends at (UTC)=%v", breakDuration, breakEnd)
 
-		// Broadcast status update
-		if h.hub != nil {
-			h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
-				"sessionId":     sessionID,
-				"status":        "break",
-				"currentRound":  currentRound,
-				"breakDuration": breakDuration,
-			})
-		}
+	// Update session to break status
+	if err := session.startBreak(breakEnd); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to start break: %w", err)
+	}
+
+	// Broadcast status update
+	if h.hub != nil {
+		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
+			"sessionId":     sessionID,
+			"status":        "break",
+			"currentRound":  currentRound,
+			"breakDuration": breakDuration,
+		})
+	}
+
+	return nil
+}
+
+// SetAutoAdvance lets an admin turn the round scheduler on or off for the
+// active session, e.g. to hold the room for an announcement
+func (h *VelvetHourHandler) SetAutoAdvance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventIDStr := vars["eventId"]
+	
+	eventID, err := uuid.Parse(eventIDStr)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetAutoAdvanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to update auto-advance", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	session, err := lockActiveSession(tx, eventID)
+	if err == nil {
+		err = session.setAutoAdvance(req.Enabled)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to update auto-advance")
+		return
+	}
+	sessionID := session.ID
+
+	if h.hub != nil {
+		h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
+			"sessionId":   sessionID,
+			"autoAdvance": req.Enabled,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Auto-advance updated successfully",
+		"autoAdvance": req.Enabled,
+	})
+}
+
+// loadEventConfig returns the Velvet Hour configuration for an event, falling
+// back to the defaults when it cannot be read
+func (h *VelvetHourHandler) loadEventConfig(eventID uuid.UUID) models.VelvetHourConfig {
+	var config models.VelvetHourConfig
+	err := h.db.QueryRow(`
+		SELECT the_hour_round_duration, the_hour_break_duration, 
+			   the_hour_total_rounds, COALESCE(the_hour_auto_advance, false),
+			   COALESCE(the_hour_odd_policy, 'rotating_bye'),
+			   COALESCE(the_hour_confirmation_window, 0), the_hour_no_show_limit,
+			   COALESCE(the_hour_cross_event_memory, false), COALESCE(the_hour_memory_lookback_days, 90),
+			   COALESCE(the_hour_group_mode, 'pairs'), COALESCE(the_hour_pod_size, 4)
+		FROM events 
+		WHERE id = $1
+	`, eventID).Scan(
+		&config.RoundDuration, &config.BreakDuration,
+		&config.TotalRounds, &config.AutoAdvance, &config.OddPolicy,
+		&config.ConfirmationWindow, &config.NoShowLimit,
+		&config.CrossEventMemory, &config.MemoryLookbackDays,
+		&config.GroupMode, &config.PodSize,
+	)
+	if err != nil {
+		log.Printf("Failed to get event config: %v", err)
+		// Use default values if config fetch fails
+		config = models.VelvetHourConfig{
+			RoundDuration: 10,
+			BreakDuration: 5,
+			TotalRounds:   4,
+			AutoAdvance:   false,
+			OddPolicy:     oddPolicyRotatingBye,
+			MemoryLookbackDays: 90,
+			GroupMode:          groupModePairs,
+			PodSize:            4,
+		}
+	}
+	
+	// Calculate minimum participants based on total rounds (round-robin formula)
+	// For R rounds: n_min = R if R is odd, R+1 if R is even
+	if config.TotalRounds%2 == 0 {
+		config.MinParticipants = config.TotalRounds + 1
+	} else {
+		config.MinParticipants = config.TotalRounds
+	}
+
+	return config
+}
+
+// loadSessionConfig returns the Velvet Hour configuration of a session's event
+func (h *VelvetHourHandler) loadSessionConfig(sessionID uuid.UUID) models.VelvetHourConfig {
+	var eventID uuid.UUID
+	err := h.db.QueryRow(`
+		SELECT event_id FROM velvet_hour_sessions WHERE id = $1
+	`, sessionID).Scan(&eventID)
+	if err != nil {
+		log.Printf("Failed to get session event: %v", err)
+	}
+
+	return h.loadEventConfig(eventID)
 }
 
 // Helper function to join strings
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// analyticsCacheTTL is how long a computed analytics report is served before
+// it is worked out again
+const analyticsCacheTTL = time.Minute
+
+// scoreBandWidth splits predicted compatibility into bands of this width
+const scoreBandWidth = 0.2
+
+// analyticsCache keeps recent analytics reports, keyed by event and session
+type analyticsCache struct {
+	mu      sync.Mutex
+	entries map[string]*models.VelvetHourAnalytics
+}
+
+// get returns a cached report that is still fresh
+func (c *analyticsCache) get(key string) *models.VelvetHourAnalytics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report, ok := c.entries[key]
+	if !ok || time.Since(report.GeneratedAt) > analyticsCacheTTL {
+		return nil
+	}
+	return report
+}
+
+// put stores a report, dropping any that have gone stale
+func (c *analyticsCache) put(key string, report *models.VelvetHourAnalytics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*models.VelvetHourAnalytics)
+	}
+	for k, cached := range c.entries {
+		if time.Since(cached.GeneratedAt) > analyticsCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = report
+}
+
+// matchOutcome is what happened in one match
+type matchOutcome struct {
+	RoundNumber   int
+	Status        string
+	Score         sql.NullFloat64
+	Members       int
+	Confirmed     int
+	Feedback      int
+	WantToConnect int
+	Mutual        int
+}
+
+// addOutcome counts a match towards a set of stats
+func addOutcome(stats *models.VelvetHourOutcomeStats, outcome matchOutcome) {
+	if outcome.Status == "abandoned" {
+		stats.AbandonedMatches++
+		return
+	}
+	stats.Matches++
+	stats.Members += outcome.Members
+	stats.Confirmed += outcome.Confirmed
+
+	// Nobody met, so there is no feedback to expect
+	if outcome.Status == "no_show" {
+		stats.NoShowMatches++
+		return
+	}
+	stats.FeedbackExpected += outcome.Members * (outcome.Members - 1)
+	stats.FeedbackSubmitted += outcome.Feedback
+	stats.WantToConnect += outcome.WantToConnect
+	stats.MutualMatches += outcome.Mutual
+}
+
+// outcomeRate returns part/whole rounded to three places, or 0 with nothing to divide
+func outcomeRate(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*1000) / 1000
+}
+
+// finishOutcome fills in the rates once every match has been counted
+func finishOutcome(stats *models.VelvetHourOutcomeStats) {
+	stats.ConfirmationRate = outcomeRate(stats.Confirmed, stats.Members)
+	stats.FeedbackCompletion = outcomeRate(stats.FeedbackSubmitted, stats.FeedbackExpected)
+	stats.WantToConnectRate = outcomeRate(stats.WantToConnect, stats.FeedbackSubmitted)
+}
+
+// scoreBands returns the empty bands predicted compatibility is split into
+func scoreBands() []models.VelvetHourScoreBandAnalytics {
+	count := int(math.Round(1 / scoreBandWidth))
+	bands := make([]models.VelvetHourScoreBandAnalytics, count)
+	for i := range bands {
+		low := math.Round(float64(i)*scoreBandWidth*10) / 10
+		high := math.Round(float64(i+1)*scoreBandWidth*10) / 10
+		bands[i] = models.VelvetHourScoreBandAnalytics{
+			Label:    fmt.Sprintf("%.1f-%.1f", low, high),
+			MinScore: &low,
+			MaxScore: &high,
+		}
+	}
+	return bands
+}
+
+// scoreBandIndex returns the band a predicted score falls in; a perfect score
+// belongs to the top band
+func scoreBandIndex(score float64, bands int) int {
+	i := int(score / scoreBandWidth)
+	if i >= bands {
+		i = bands - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// buildAnalytics works out the analytics report for an event, or for one of
+// its sessions when a session is given
+func (h *VelvetHourHandler) buildAnalytics(eventID uuid.UUID, sessionID *uuid.UUID) (*models.VelvetHourAnalytics, error) {
+	report := &models.VelvetHourAnalytics{
+		EventID:     eventID,
+		SessionID:   sessionID,
+		Rounds:      []models.VelvetHourRoundAnalytics{},
+		ScoreBands:  scoreBands(),
+		Reasons:     []models.VelvetHourReasonCount{},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	err := h.db.QueryRow(`
+		SELECT COUNT(*) FROM velvet_hour_sessions
+		WHERE event_id = $1 AND ($2::uuid IS NULL OR id = $2)
+	`, eventID, sessionID).Scan(&report.Sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT m.round_number, m.status, m.compatibility_score,
+			   (SELECT COUNT(*) FROM velvet_hour_match_members mm WHERE mm.match_id = m.id),
+			   (SELECT COUNT(*) FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.confirmed),
+			   (SELECT COUNT(*) FROM velvet_hour_feedback f WHERE f.match_id = m.id),
+			   (SELECT COUNT(*) FROM velvet_hour_feedback f WHERE f.match_id = m.id AND f.want_to_connect),
+			   (SELECT COUNT(*) FROM velvet_hour_connections c WHERE c.match_id = m.id)
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		WHERE s.event_id = $1 AND ($2::uuid IS NULL OR m.session_id = $2)
+		ORDER BY m.round_number, m.match_number
+	`, eventID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	rounds := make(map[int]int)
+	var unscored *models.VelvetHourScoreBandAnalytics
+	for rows.Next() {
+		var outcome matchOutcome
+		err := rows.Scan(&outcome.RoundNumber, &outcome.Status, &outcome.Score, &outcome.Members,
+			&outcome.Confirmed, &outcome.Feedback, &outcome.WantToConnect, &outcome.Mutual)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan match outcome: %w", err)
+		}
+
+		addOutcome(&report.Overall, outcome)
+
+		i, ok := rounds[outcome.RoundNumber]
+		if !ok {
+			i = len(report.Rounds)
+			rounds[outcome.RoundNumber] = i
+			report.Rounds = append(report.Rounds, models.VelvetHourRoundAnalytics{RoundNumber: outcome.RoundNumber})
+		}
+		addOutcome(&report.Rounds[i].VelvetHourOutcomeStats, outcome)
+
+		if !outcome.Score.Valid {
+			if unscored == nil {
+				unscored = &models.VelvetHourScoreBandAnalytics{Label: "unscored"}
+			}
+			addOutcome(&unscored.VelvetHourOutcomeStats, outcome)
+			continue
+		}
+		band := scoreBandIndex(outcome.Score.Float64, len(report.ScoreBands))
+		addOutcome(&report.ScoreBands[band].VelvetHourOutcomeStats, outcome)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if unscored != nil {
+		report.ScoreBands = append(report.ScoreBands, *unscored)
+	}
+
+	finishOutcome(&report.Overall)
+	for i := range report.Rounds {
+		finishOutcome(&report.Rounds[i].VelvetHourOutcomeStats)
+	}
+	for i := range report.ScoreBands {
+		finishOutcome(&report.ScoreBands[i].VelvetHourOutcomeStats)
+	}
+
+	// Events with configured questions leave the reason blank
+	reasonRows, err := h.db.Query(`
+		SELECT f.feedback_reason, COUNT(*)
+		FROM velvet_hour_feedback f
+		JOIN velvet_hour_matches m ON f.match_id = m.id
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		WHERE s.event_id = $1 AND ($2::uuid IS NULL OR m.session_id = $2)
+		  AND m.status = 'active' AND f.feedback_reason <> ''
+		GROUP BY f.feedback_reason
+		ORDER BY COUNT(*) DESC, f.feedback_reason
+	`, eventID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback reasons: %w", err)
+	}
+	defer reasonRows.Close()
+
+	total := 0
+	for reasonRows.Next() {
+		var reason models.VelvetHourReasonCount
+		if err := reasonRows.Scan(&reason.Reason, &reason.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback reason: %w", err)
+		}
+		total += reason.Count
+		report.Reasons = append(report.Reasons, reason)
+	}
+	if err := reasonRows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range report.Reasons {
+		report.Reasons[i].Share = outcomeRate(report.Reasons[i].Count, total)
+	}
+
+	return report, nil
+}
+
+// GetAnalytics reports how an event's matches turned out: confirmations,
+// feedback, want-to-connect per round, feedback reasons, mutual matches, and
+// outcomes by the compatibility the matcher predicted. Pass ?sessionId= for a
+// single session. Reports are cached briefly; ?refresh=true works one out
+// afresh and ?format=csv downloads it.
+func (h *VelvetHourHandler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	var sessionID *uuid.UUID
+	if raw := query.Get("sessionId"); raw != "" {
+		requested, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+		var exists bool
+		err = h.db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM velvet_hour_sessions WHERE id = $1 AND event_id = $2)
+		`, requested, eventID).Scan(&exists)
+		if err != nil {
+			log.Printf("Failed to get session: %v", err)
+			http.Error(w, "Failed to get analytics", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, errSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		sessionID = &requested
+	}
+
+	key := eventID.String()
+	if sessionID != nil {
+		key += "/" + sessionID.String()
+	}
+	report := h.analytics.get(key)
+	if report == nil || query.Get("refresh") == "true" {
+		report, err = h.buildAnalytics(eventID, sessionID)
+		if err != nil {
+			log.Printf("Failed to build analytics: %v", err)
+			http.Error(w, "Failed to get analytics", http.StatusInternalServerError)
+			return
+		}
+		h.analytics.put(key, report)
+	}
+
+	if format != "csv" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	filename := fmt.Sprintf("velvet_hour_analytics_%s.csv", eventID)
+	if sessionID != nil {
+		filename = fmt.Sprintf("velvet_hour_analytics_%s.csv", *sessionID)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Section", "Label", "Matches", "No-Show Matches", "Abandoned Matches", "Members", "Confirmed", "Confirmation Rate",
+		"Feedback Expected", "Feedback Submitted", "Feedback Completion", "Want To Connect",
+		"Want To Connect Rate", "Mutual Matches",
+	}
+	if err := writer.Write(headers); err != nil {
+		log.Printf("Failed to write CSV headers: %v", err)
+		return
+	}
+	records := [][]string{outcomeRecord("Overall", "All", report.Overall)}
+	for _, round := range report.Rounds {
+		records = append(records, outcomeRecord("Round", strconv.Itoa(round.RoundNumber), round.VelvetHourOutcomeStats))
+	}
+	for _, band := range report.ScoreBands {
+		records = append(records, outcomeRecord("Predicted Score", band.Label, band.VelvetHourOutcomeStats))
+	}
+
+	// Reasons follow as a table of their own
+	records = append(records, []string{}, []string{"Reason", "Count", "Share"})
+	for _, reason := range report.Reasons {
+		records = append(records, []string{reason.Reason, strconv.Itoa(reason.Count), formatRate(reason.Share)})
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			log.Printf("Failed to write CSV row: %v", err)
+			return
+		}
+	}
+}
+
+// outcomeRecord lays out a set of stats as a CSV row
+func outcomeRecord(section, label string, stats models.VelvetHourOutcomeStats) []string {
+	return []string{
+		section,
+		label,
+		strconv.Itoa(stats.Matches),
+		strconv.Itoa(stats.NoShowMatches),
+		strconv.Itoa(stats.AbandonedMatches),
+		strconv.Itoa(stats.Members),
+		strconv.Itoa(stats.Confirmed),
+		formatRate(stats.ConfirmationRate),
+		strconv.Itoa(stats.FeedbackExpected),
+		strconv.Itoa(stats.FeedbackSubmitted),
+		formatRate(stats.FeedbackCompletion),
+		strconv.Itoa(stats.WantToConnect),
+		formatRate(stats.WantToConnectRate),
+		strconv.Itoa(stats.MutualMatches),
+	}
+}
+
+// formatRate writes a rate for CSV
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 3, 64)
+}
@@ -0,0 +1,406 @@
+package handlers
+
+// blossomMatcher finds a maximum-weight matching in a general graph using
+// Edmonds' blossom algorithm with dual variables, in O(n^3). Vertices are
+// numbered 1..n; indices above n are used for contracted blossoms. An edge
+// exists between two vertices when its weight is positive.
+type blossomMatcher struct {
+	n, nx      int
+	weight     [][]int64
+	edgeU      [][]int
+	edgeV      [][]int
+	lab        []int64
+	match      []int
+	slack      []int
+	st         []int
+	pa         []int
+	flowerFrom [][]int
+	s          []int
+	vis        []int
+	visStamp   int
+	flower     [][]int
+	queue      []int
+}
+
+// newBlossomMatcher creates a matcher for a graph with n vertices and no edges
+func newBlossomMatcher(n int) *blossomMatcher {
+	size := 2*n + 1
+	m := &blossomMatcher{
+		n:          n,
+		weight:     make([][]int64, size),
+		edgeU:      make([][]int, size),
+		edgeV:      make([][]int, size),
+		lab:        make([]int64, size),
+		match:      make([]int, size),
+		slack:      make([]int, size),
+		st:         make([]int, size),
+		pa:         make([]int, size),
+		flowerFrom: make([][]int, size),
+		s:          make([]int, size),
+		vis:        make([]int, size),
+		flower:     make([][]int, size),
+	}
+	for u := 0; u < size; u++ {
+		m.weight[u] = make([]int64, size)
+		m.edgeU[u] = make([]int, size)
+		m.edgeV[u] = make([]int, size)
+		m.flowerFrom[u] = make([]int, size)
+		for v := 0; v < size; v++ {
+			m.edgeU[u][v] = u
+			m.edgeV[u][v] = v
+		}
+	}
+	return m
+}
+
+// addEdge sets the weight of the edge between vertices u and v (1-based)
+func (m *blossomMatcher) addEdge(u, v int, w int64) {
+	m.weight[u][v] = w
+	m.weight[v][u] = w
+}
+
+// solve computes the matching and returns each vertex's partner (0 when unmatched)
+func (m *blossomMatcher) solve() []int {
+	m.nx = m.n
+	for u := 0; u <= m.n; u++ {
+		m.st[u] = u
+		m.flower[u] = nil
+		m.match[u] = 0
+	}
+
+	var maxWeight int64
+	for u := 1; u <= m.n; u++ {
+		for v := 1; v <= m.n; v++ {
+			if u == v {
+				m.flowerFrom[u][v] = u
+			} else {
+				m.flowerFrom[u][v] = 0
+			}
+			if m.weight[u][v] > maxWeight {
+				maxWeight = m.weight[u][v]
+			}
+		}
+	}
+	for u := 1; u <= m.n; u++ {
+		m.lab[u] = maxWeight
+	}
+
+	for m.augmentOnce() {
+	}
+
+	result := make([]int, m.n+1)
+	copy(result, m.match[:m.n+1])
+	return result
+}
+
+// dist is the reduced cost of the edge stored at (u, v)
+func (m *blossomMatcher) dist(u, v int) int64 {
+	return m.lab[m.edgeU[u][v]] + m.lab[m.edgeV[u][v]] - m.weight[u][v]*2
+}
+
+func (m *blossomMatcher) updateSlack(u, x int) {
+	if m.slack[x] == 0 || m.dist(u, x) < m.dist(m.slack[x], x) {
+		m.slack[x] = u
+	}
+}
+
+func (m *blossomMatcher) setSlack(x int) {
+	m.slack[x] = 0
+	for u := 1; u <= m.n; u++ {
+		if m.weight[u][x] > 0 && m.st[u] != x && m.s[m.st[u]] == 0 {
+			m.updateSlack(u, x)
+		}
+	}
+}
+
+func (m *blossomMatcher) push(x int) {
+	if x <= m.n {
+		m.queue = append(m.queue, x)
+		return
+	}
+	for _, child := range m.flower[x] {
+		m.push(child)
+	}
+}
+
+func (m *blossomMatcher) setSt(x, b int) {
+	m.st[x] = b
+	if x > m.n {
+		for _, child := range m.flower[x] {
+			m.setSt(child, b)
+		}
+	}
+}
+
+// evenPosition returns the position of xr in blossom b, reversing the blossom
+// when needed so the path from its base to xr has even length
+func (m *blossomMatcher) evenPosition(b, xr int) int {
+	pr := 0
+	for i, x := range m.flower[b] {
+		if x == xr {
+			pr = i
+			break
+		}
+	}
+	if pr%2 == 1 {
+		reverseInts(m.flower[b][1:])
+		return len(m.flower[b]) - pr
+	}
+	return pr
+}
+
+func (m *blossomMatcher) setMatch(u, v int) {
+	m.match[u] = m.edgeV[u][v]
+	if u <= m.n {
+		return
+	}
+	xr := m.flowerFrom[u][m.edgeU[u][v]]
+	pr := m.evenPosition(u, xr)
+	for i := 0; i < pr; i++ {
+		m.setMatch(m.flower[u][i], m.flower[u][i^1])
+	}
+	m.setMatch(xr, v)
+	rotated := append(append([]int{}, m.flower[u][pr:]...), m.flower[u][:pr]...)
+	copy(m.flower[u], rotated)
+}
+
+func (m *blossomMatcher) augment(u, v int) {
+	for {
+		xnv := m.st[m.match[u]]
+		m.setMatch(u, v)
+		if xnv == 0 {
+			return
+		}
+		m.setMatch(xnv, m.st[m.pa[xnv]])
+		u, v = m.st[m.pa[xnv]], xnv
+	}
+}
+
+func (m *blossomMatcher) lowestCommonAncestor(u, v int) int {
+	m.visStamp++
+	for u != 0 || v != 0 {
+		if u != 0 {
+			if m.vis[u] == m.visStamp {
+				return u
+			}
+			m.vis[u] = m.visStamp
+			u = m.st[m.match[u]]
+			if u != 0 {
+				u = m.st[m.pa[u]]
+			}
+		}
+		u, v = v, u
+	}
+	return 0
+}
+
+func (m *blossomMatcher) addBlossom(u, lca, v int) {
+	b := m.n + 1
+	for b <= m.nx && m.st[b] != 0 {
+		b++
+	}
+	if b > m.nx {
+		m.nx++
+	}
+	m.lab[b] = 0
+	m.s[b] = 0
+	m.match[b] = m.match[lca]
+	m.flower[b] = []int{lca}
+	for x := u; x != lca; {
+		y := m.st[m.match[x]]
+		m.flower[b] = append(m.flower[b], x, y)
+		m.push(y)
+		x = m.st[m.pa[y]]
+	}
+	reverseInts(m.flower[b][1:])
+	for x := v; x != lca; {
+		y := m.st[m.match[x]]
+		m.flower[b] = append(m.flower[b], x, y)
+		m.push(y)
+		x = m.st[m.pa[y]]
+	}
+	m.setSt(b, b)
+
+	for x := 1; x <= m.nx; x++ {
+		m.weight[b][x] = 0
+		m.weight[x][b] = 0
+	}
+	for x := 1; x <= m.n; x++ {
+		m.flowerFrom[b][x] = 0
+	}
+	for _, xs := range m.flower[b] {
+		for x := 1; x <= m.nx; x++ {
+			if m.weight[b][x] == 0 || m.dist(xs, x) < m.dist(b, x) {
+				m.copyEdge(b, x, xs, x)
+				m.copyEdge(x, b, x, xs)
+			}
+		}
+		for x := 1; x <= m.n; x++ {
+			if m.flowerFrom[xs][x] != 0 {
+				m.flowerFrom[b][x] = xs
+			}
+		}
+	}
+	m.setSlack(b)
+}
+
+// copyEdge stores the edge found at (fromU, fromV) at position (toU, toV)
+func (m *blossomMatcher) copyEdge(toU, toV, fromU, fromV int) {
+	m.edgeU[toU][toV] = m.edgeU[fromU][fromV]
+	m.edgeV[toU][toV] = m.edgeV[fromU][fromV]
+	m.weight[toU][toV] = m.weight[fromU][fromV]
+}
+
+func (m *blossomMatcher) expandBlossom(b int) {
+	for _, child := range m.flower[b] {
+		m.setSt(child, child)
+	}
+	xr := m.flowerFrom[b][m.edgeU[b][m.pa[b]]]
+	pr := m.evenPosition(b, xr)
+	for i := 0; i < pr; i += 2 {
+		xs, xns := m.flower[b][i], m.flower[b][i+1]
+		m.pa[xs] = m.edgeU[xns][xs]
+		m.s[xs] = 1
+		m.s[xns] = 0
+		m.slack[xs] = 0
+		m.setSlack(xns)
+		m.push(xns)
+	}
+	m.s[xr] = 1
+	m.pa[xr] = m.pa[b]
+	for i := pr + 1; i < len(m.flower[b]); i++ {
+		xs := m.flower[b][i]
+		m.s[xs] = -1
+		m.setSlack(xs)
+	}
+	m.st[b] = 0
+}
+
+// onFoundEdge handles a tight edge and reports whether it completed an augmenting path
+func (m *blossomMatcher) onFoundEdge(eu, ev int) bool {
+	u, v := m.st[eu], m.st[ev]
+	switch m.s[v] {
+	case -1:
+		m.pa[v] = eu
+		m.s[v] = 1
+		nu := m.st[m.match[v]]
+		m.slack[v] = 0
+		m.slack[nu] = 0
+		m.s[nu] = 0
+		m.push(nu)
+	case 0:
+		lca := m.lowestCommonAncestor(u, v)
+		if lca == 0 {
+			m.augment(u, v)
+			m.augment(v, u)
+			return true
+		}
+		m.addBlossom(u, lca, v)
+	}
+	return false
+}
+
+// augmentOnce grows the matching by one edge, returning false once no
+// augmentation can increase the total weight
+func (m *blossomMatcher) augmentOnce() bool {
+	for x := 1; x <= m.nx; x++ {
+		m.s[x] = -1
+		m.slack[x] = 0
+	}
+	m.queue = m.queue[:0]
+	for x := 1; x <= m.nx; x++ {
+		if m.st[x] == x && m.match[x] == 0 {
+			m.pa[x] = 0
+			m.s[x] = 0
+			m.push(x)
+		}
+	}
+	if len(m.queue) == 0 {
+		return false
+	}
+
+	for {
+		for len(m.queue) > 0 {
+			u := m.queue[0]
+			m.queue = m.queue[1:]
+			if m.s[m.st[u]] == 1 {
+				continue
+			}
+			for v := 1; v <= m.n; v++ {
+				if m.weight[u][v] > 0 && m.st[u] != m.st[v] {
+					if m.dist(u, v) == 0 {
+						if m.onFoundEdge(m.edgeU[u][v], m.edgeV[u][v]) {
+							return true
+						}
+					} else {
+						m.updateSlack(u, m.st[v])
+					}
+				}
+			}
+		}
+
+		d := int64(-1)
+		minimize := func(candidate int64) {
+			if d < 0 || candidate < d {
+				d = candidate
+			}
+		}
+		for b := m.n + 1; b <= m.nx; b++ {
+			if m.st[b] == b && m.s[b] == 1 {
+				minimize(m.lab[b] / 2)
+			}
+		}
+		for x := 1; x <= m.nx; x++ {
+			if m.st[x] == x && m.slack[x] != 0 {
+				if m.s[x] == -1 {
+					minimize(m.dist(m.slack[x], x))
+				} else if m.s[x] == 0 {
+					minimize(m.dist(m.slack[x], x) / 2)
+				}
+			}
+		}
+
+		for u := 1; u <= m.n; u++ {
+			switch m.s[m.st[u]] {
+			case 0:
+				if d < 0 || m.lab[u] <= d {
+					return false
+				}
+				m.lab[u] -= d
+			case 1:
+				m.lab[u] += d
+			}
+		}
+		for b := m.n + 1; b <= m.nx; b++ {
+			if m.st[b] == b {
+				switch m.s[b] {
+				case 0:
+					m.lab[b] += d * 2
+				case 1:
+					m.lab[b] -= d * 2
+				}
+			}
+		}
+
+		m.queue = m.queue[:0]
+		for x := 1; x <= m.nx; x++ {
+			if m.st[x] == x && m.slack[x] != 0 && m.st[m.slack[x]] != x && m.dist(m.slack[x], x) == 0 {
+				if m.onFoundEdge(m.edgeU[m.slack[x]][x], m.edgeV[m.slack[x]][x]) {
+					return true
+				}
+			}
+		}
+		for b := m.n + 1; b <= m.nx; b++ {
+			if m.st[b] == b && m.s[b] == 1 && m.lab[b] == 0 {
+				m.expandBlossom(b)
+			}
+		}
+	}
+}
+
+// reverseInts reverses a slice in place
+func reverseInts(values []int) {
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// bruteForceMatchingWeight returns the weight of a maximum-weight matching by
+// trying every matching. weight is 1-based like the matcher; zero means no edge.
+func bruteForceMatchingWeight(n int, weight [][]int64) int64 {
+	used := make([]bool, n+1)
+	var best func(u int) int64
+	best = func(u int) int64 {
+		for u <= n && used[u] {
+			u++
+		}
+		if u > n {
+			return 0
+		}
+		used[u] = true
+		result := best(u + 1) // u stays unmatched
+		for v := u + 1; v <= n; v++ {
+			if used[v] || weight[u][v] <= 0 {
+				continue
+			}
+			used[v] = true
+			if total := weight[u][v] + best(u+1); total > result {
+				result = total
+			}
+			used[v] = false
+		}
+		used[u] = false
+		return result
+	}
+	return best(1)
+}
+
+// checkMatching verifies partners is a matching over the graph's edges and
+// returns its weight
+func checkMatching(t *testing.T, n int, weight [][]int64, partners []int) int64 {
+	t.Helper()
+	if len(partners) < n+1 {
+		t.Fatalf("got %d partners for %d vertices", len(partners), n)
+	}
+	var total int64
+	for u := 1; u <= n; u++ {
+		v := partners[u]
+		if v == 0 {
+			continue
+		}
+		if v < 1 || v > n || v == u {
+			t.Fatalf("vertex %d matched to invalid vertex %d", u, v)
+		}
+		if partners[v] != u {
+			t.Fatalf("vertex %d matched to %d, but %d matched to %d", u, v, v, partners[v])
+		}
+		if weight[u][v] <= 0 {
+			t.Fatalf("vertices %d and %d matched without an edge", u, v)
+		}
+		if u < v {
+			total += weight[u][v]
+		}
+	}
+	return total
+}
+
+func TestBlossomMatcher(t *testing.T) {
+	type edge struct {
+		u, v int
+		w    int64
+	}
+	tests := []struct {
+		name  string
+		n     int
+		edges []edge
+		want  int64
+	}{
+		{name: "no vertices", n: 0, want: 0},
+		{name: "no edges", n: 3, want: 0},
+		{name: "single edge", n: 2, edges: []edge{{1, 2, 5}}, want: 5},
+		{name: "heavy middle edge", n: 4, edges: []edge{{1, 2, 3}, {2, 3, 10}, {3, 4, 3}}, want: 10},
+		{name: "two outer edges", n: 4, edges: []edge{{1, 2, 6}, {2, 3, 10}, {3, 4, 6}}, want: 12},
+		{name: "triangle", n: 3, edges: []edge{{1, 2, 4}, {2, 3, 5}, {1, 3, 6}}, want: 6},
+		{
+			// An odd cycle with a tail forces a blossom to be shrunk and expanded
+			name:  "blossom with stem",
+			n:     6,
+			edges: []edge{{1, 2, 8}, {2, 3, 9}, {3, 4, 8}, {4, 5, 9}, {5, 1, 8}, {5, 6, 7}},
+			want:  23,
+		},
+		{
+			name:  "nested odd cycles",
+			n:     8,
+			edges: []edge{{1, 2, 10}, {2, 3, 10}, {3, 1, 10}, {3, 4, 7}, {4, 5, 10}, {5, 6, 10}, {6, 4, 10}, {6, 7, 6}, {7, 8, 4}},
+			want:  31,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight := make([][]int64, tt.n+1)
+			for i := range weight {
+				weight[i] = make([]int64, tt.n+1)
+			}
+			matcher := newBlossomMatcher(tt.n)
+			for _, e := range tt.edges {
+				matcher.addEdge(e.u, e.v, e.w)
+				weight[e.u][e.v], weight[e.v][e.u] = e.w, e.w
+			}
+
+			got := checkMatching(t, tt.n, weight, matcher.solve())
+			if got != tt.want {
+				t.Errorf("matching weight = %d, want %d", got, tt.want)
+			}
+			if brute := bruteForceMatchingWeight(tt.n, weight); brute != tt.want {
+				t.Errorf("brute force weight = %d, want %d", brute, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlossomMatcherAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 500; trial++ {
+		n := 1 + rng.Intn(10)
+		density := rng.Float64()
+		maxWeight := int64(1 + rng.Intn(20))
+
+		weight := make([][]int64, n+1)
+		for i := range weight {
+			weight[i] = make([]int64, n+1)
+		}
+		matcher := newBlossomMatcher(n)
+		for u := 1; u <= n; u++ {
+			for v := u + 1; v <= n; v++ {
+				if rng.Float64() >= density {
+					continue
+				}
+				w := 1 + rng.Int63n(maxWeight)
+				matcher.addEdge(u, v, w)
+				weight[u][v], weight[v][u] = w, w
+			}
+		}
+
+		got := checkMatching(t, n, weight, matcher.solve())
+		if want := bruteForceMatchingWeight(n, weight); got != want {
+			t.Fatalf("trial %d (n=%d): matching weight = %d, brute force = %d", trial, n, got, want)
+		}
+	}
+}
+
+func TestWeightedPairingsAgainstBruteForce(t *testing.T) {
+	config := defaultMatchingConfig(uuid.Nil)
+	maxGap := 10
+	strict := config
+	strict.MaxAgeGap = &maxGap
+	strict.DatingGenderHard = true
+
+	rng := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 200; trial++ {
+		cfg := config
+		if trial%2 == 1 {
+			cfg = strict
+		}
+		users, profiles, err := syntheticParticipants(rng, 2+rng.Intn(8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := make(map[string]bool)
+		for i := range users {
+			for j := i + 1; j < len(users); j++ {
+				if rng.Intn(4) == 0 {
+					addPair(previous, users[i], users[j])
+				}
+			}
+		}
+
+		// Score pairs the way weightedPairings ranks them: more pairs first,
+		// then the larger total of scaled scores
+		n := len(users)
+		score := make([][]int64, n+1)
+		for i := range score {
+			score[i] = make([]int64, n+1)
+		}
+		index := make(map[uuid.UUID]int, n)
+		for i, a := range users {
+			index[a] = i + 1
+			for j, b := range users {
+				if i == j || previous[pairKey(a, b)] || !pairAllowed(profiles[a], profiles[b], cfg) {
+					continue
+				}
+				score[i+1][j+1] = 1 + int64(math.Round(compatibilityScore(profiles[a], profiles[b], cfg)*1000))
+			}
+		}
+		rank := func(count int, total int64) int64 { return int64(count)*1_000_000 + total }
+		bruteWeight := make([][]int64, n+1)
+		for i := range bruteWeight {
+			bruteWeight[i] = make([]int64, n+1)
+			for j := range bruteWeight[i] {
+				if score[i][j] > 0 {
+					bruteWeight[i][j] = rank(1, score[i][j])
+				}
+			}
+		}
+
+		pairs := weightedPairings(users, profiles, cfg, previous)
+		seen := make(map[uuid.UUID]bool)
+		var total int64
+		for _, pair := range pairs {
+			for _, userID := range pair {
+				if seen[userID] {
+					t.Fatalf("trial %d: %s paired twice", trial, userID)
+				}
+				seen[userID] = true
+			}
+			s := score[index[pair[0]]][index[pair[1]]]
+			if s == 0 {
+				t.Fatalf("trial %d: paired %s and %s, who are excluded", trial, pair[0], pair[1])
+			}
+			total += s
+		}
+
+		if got, want := rank(len(pairs), total), bruteForceMatchingWeight(n, bruteWeight); got != want {
+			t.Fatalf("trial %d (n=%d): got %d pairs scoring %d, brute force ranks %d", trial, n, len(pairs), total, want)
+		}
+	}
+}
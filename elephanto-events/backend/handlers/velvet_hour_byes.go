@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+
+	"github.com/google/uuid"
+)
+
+// Odd-headcount policies an event can choose from
+const (
+	// oddPolicyRotatingBye sits one person out per round, never the same person twice while avoidable
+	oddPolicyRotatingBye = "rotating_bye"
+	// oddPolicyTrio folds the extra person into one of the round's matches
+	oddPolicyTrio = "trio"
+)
+
+// Why a participant is without a match in a round
+const (
+	// byeReasonBye is a bye handed out by the matcher, counted for fairness
+	byeReasonBye = "bye"
+	// byeReasonWaiting is waiting to be paired again after a split or after
+	// losing a partner mid-round, which isn't counted
+	byeReasonWaiting = "waiting"
+)
+
+// validOddPolicies lists the accepted values for an event's odd-headcount policy
+var validOddPolicies = map[string]bool{
+	oddPolicyRotatingBye: true,
+	oddPolicyTrio:        true,
+}
+
+// pickByeUser returns the candidate who has sat out the fewest rounds so far,
+// keeping the earliest candidate on ties so the choice is stable
+func pickByeUser(candidates []uuid.UUID, byeCounts map[uuid.UUID]int) uuid.UUID {
+	best := candidates[0]
+	for _, userID := range candidates[1:] {
+		if byeCounts[userID] < byeCounts[best] {
+			best = userID
+		}
+	}
+	return best
+}
+
+// removeUser returns users without the given user
+func removeUser(users []uuid.UUID, userID uuid.UUID) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		if user != userID {
+			result = append(result, user)
+		}
+	}
+	return result
+}
+
+// resolveOddHeadcount turns a round's pairs into matches and decides what
+// happens to the participants left without a partner. Under the trio policy
+// each of them joins a pair they have met the fewest members of; anyone who
+// cannot be placed (or everyone, under rotating byes) sits the round out.
+func resolveOddHeadcount(pairs [][2]uuid.UUID, unmatched []uuid.UUID, policy string, previousPairs, forbidden map[string]bool) ([]models.ManualMatch, []uuid.UUID) {
+	matches := make([]models.ManualMatch, 0, len(pairs))
+	for _, pair := range pairs {
+		matches = append(matches, models.ManualMatch{User1ID: pair[0], User2ID: pair[1]})
+	}
+
+	if policy != oddPolicyTrio {
+		return matches, unmatched
+	}
+
+	var byes []uuid.UUID
+	for _, userID := range unmatched {
+		best := -1
+		bestRepeats := 3
+		for i, match := range matches {
+			if match.User3ID != nil {
+				continue
+			}
+			if forbidden[pairKey(userID, match.User1ID)] || forbidden[pairKey(userID, match.User2ID)] {
+				continue
+			}
+			repeats := 0
+			if previousPairs[pairKey(userID, match.User1ID)] {
+				repeats++
+			}
+			if previousPairs[pairKey(userID, match.User2ID)] {
+				repeats++
+			}
+			if repeats < bestRepeats {
+				best, bestRepeats = i, repeats
+			}
+		}
+
+		if best < 0 {
+			byes = append(byes, userID)
+			continue
+		}
+		third := userID
+		matches[best].User3ID = &third
+	}
+
+	return matches, byes
+}
+
+// getByeCounts returns how many byes each participant has had in a session.
+// Time spent waiting after a split doesn't count.
+func (h *VelvetHourHandler) getByeCounts(sessionID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := h.db.Query(`
+		SELECT user_id, COUNT(*) FROM velvet_hour_byes
+		WHERE session_id = $1 AND reason = $2
+		GROUP BY user_id
+	`, sessionID, byeReasonBye)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var userID uuid.UUID
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		counts[userID] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPickByeUser(t *testing.T) {
+	users := testUsers(3)
+	tests := []struct {
+		name   string
+		counts map[uuid.UUID]int
+		want   uuid.UUID
+	}{
+		{name: "nobody has sat out", counts: map[uuid.UUID]int{}, want: users[0]},
+		{name: "fewest byes", counts: map[uuid.UUID]int{users[0]: 1, users[1]: 2}, want: users[2]},
+		{name: "earliest on a tie", counts: map[uuid.UUID]int{users[0]: 2, users[1]: 1, users[2]: 1}, want: users[1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickByeUser(users, tt.counts); got != tt.want {
+				t.Errorf("pickByeUser() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOddHeadcount(t *testing.T) {
+	users := testUsers(7)
+	a, b, c, d, e, f, g := users[0], users[1], users[2], users[3], users[4], users[5], users[6]
+	pairs := [][2]uuid.UUID{{a, b}, {c, d}}
+
+	met := func(keys ...[2]uuid.UUID) map[string]bool {
+		pairs := make(map[string]bool)
+		for _, key := range keys {
+			addPair(pairs, key[0], key[1])
+		}
+		return pairs
+	}
+
+	tests := []struct {
+		name      string
+		unmatched []uuid.UUID
+		policy    string
+		previous  map[string]bool
+		forbidden map[string]bool
+		thirds    map[int]uuid.UUID // match index to its third member
+		byes      []uuid.UUID
+	}{
+		{
+			name:      "rotating bye sits everyone out",
+			unmatched: []uuid.UUID{e},
+			policy:    oddPolicyRotatingBye,
+			byes:      []uuid.UUID{e},
+		},
+		{
+			name:      "trio joins the first match",
+			unmatched: []uuid.UUID{e},
+			policy:    oddPolicyTrio,
+			thirds:    map[int]uuid.UUID{0: e},
+		},
+		{
+			name:      "trio avoids people already met",
+			unmatched: []uuid.UUID{e},
+			policy:    oddPolicyTrio,
+			previous:  met([2]uuid.UUID{e, a}),
+			thirds:    map[int]uuid.UUID{1: e},
+		},
+		{
+			name:      "trio never breaks a hard constraint",
+			unmatched: []uuid.UUID{e},
+			policy:    oddPolicyTrio,
+			forbidden: met([2]uuid.UUID{e, a}, [2]uuid.UUID{e, d}),
+			byes:      []uuid.UUID{e},
+		},
+		{
+			name:      "one extra per match, the rest sit out",
+			unmatched: []uuid.UUID{e, f, g},
+			policy:    oddPolicyTrio,
+			thirds:    map[int]uuid.UUID{0: e, 1: f},
+			byes:      []uuid.UUID{g},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, byes := resolveOddHeadcount(pairs, tt.unmatched, tt.policy, tt.previous, tt.forbidden)
+			if len(matches) != len(pairs) {
+				t.Fatalf("got %d matches, want %d", len(matches), len(pairs))
+			}
+			for i, match := range matches {
+				if match.User1ID != pairs[i][0] || match.User2ID != pairs[i][1] {
+					t.Errorf("match %d is not the pair it was given", i)
+				}
+				want, hasThird := tt.thirds[i]
+				switch {
+				case !hasThird && match.User3ID != nil:
+					t.Errorf("match %d got third member %s, want none", i, *match.User3ID)
+				case hasThird && (match.User3ID == nil || *match.User3ID != want):
+					t.Errorf("match %d third member = %v, want %s", i, match.User3ID, want)
+				}
+			}
+			if len(byes) != len(tt.byes) {
+				t.Fatalf("got byes %v, want %v", byes, tt.byes)
+			}
+			for i := range byes {
+				if byes[i] != tt.byes[i] {
+					t.Errorf("bye %d = %s, want %s", i, byes[i], tt.byes[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/middleware"
+	"elephanto-events/models"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// recordConnectionIfMutual creates a connection once both people in a pairing
+// have said they want to connect. It reports whether a new connection was made.
+func (h *VelvetHourHandler) recordConnectionIfMutual(matchID, fromUserID, toUserID uuid.UUID) (bool, error) {
+	var mutual bool
+	err := h.db.QueryRow(`
+		SELECT
+			EXISTS(SELECT 1 FROM velvet_hour_feedback
+				WHERE match_id = $1 AND from_user_id = $2 AND to_user_id = $3 AND want_to_connect = true)
+			AND
+			EXISTS(SELECT 1 FROM velvet_hour_feedback
+				WHERE match_id = $1 AND from_user_id = $3 AND to_user_id = $2 AND want_to_connect = true)
+	`, matchID, fromUserID, toUserID).Scan(&mutual)
+	if err != nil || !mutual {
+		return false, err
+	}
+
+	// Store each pair once, with the lower user ID first
+	user1, user2 := fromUserID, toUserID
+	if user1.String() > user2.String() {
+		user1, user2 = user2, user1
+	}
+
+	// The connection keeps what each person chose to share, so it outlives
+	// the match and its feedback if the session is reset
+	result, err := h.db.Exec(`
+		INSERT INTO velvet_hour_connections
+		(session_id, match_id, event_id, user1_id, user2_id,
+		 user1_shares_instagram, user1_shares_email, user2_shares_instagram, user2_shares_email)
+		SELECT m.session_id, m.id, s.event_id, $2, $3,
+			   COALESCE(f1.share_instagram, false), COALESCE(f1.share_email, false),
+			   COALESCE(f2.share_instagram, false), COALESCE(f2.share_email, false)
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		JOIN velvet_hour_feedback f1 ON f1.match_id = m.id AND f1.from_user_id = $2 AND f1.to_user_id = $3
+		JOIN velvet_hour_feedback f2 ON f2.match_id = m.id AND f2.from_user_id = $3 AND f2.to_user_id = $2
+		WHERE m.id = $1
+		ON CONFLICT (match_id, user1_id, user2_id) DO NOTHING
+	`, matchID, user1, user2)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := result.RowsAffected()
+	return created > 0, err
+}
+
+// GetConnections returns the current user's mutual connections and the contact
+// details each of them chose to share
+func (h *VelvetHourHandler) GetConnections(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "User not found", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT c.id, c.event_id, e.name, c.created_at,
+			   other.id, other.name, other.email, sr.instagramHandle,
+			   CASE WHEN c.user1_id = $1 THEN c.user2_shares_instagram ELSE c.user1_shares_instagram END,
+			   CASE WHEN c.user1_id = $1 THEN c.user2_shares_email ELSE c.user1_shares_email END
+		FROM velvet_hour_connections c
+		JOIN events e ON c.event_id = e.id
+		JOIN users other ON other.id = CASE WHEN c.user1_id = $1 THEN c.user2_id ELSE c.user1_id END
+		LEFT JOIN survey_responses sr ON sr.userId = other.id AND sr.event_id = c.event_id
+		WHERE c.user1_id = $1 OR c.user2_id = $1
+		ORDER BY c.created_at DESC
+	`, user.ID)
+	if err != nil {
+		log.Printf("Failed to get connections: %v", err)
+		http.Error(w, "Failed to get connections", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	connections := []models.VelvetHourConnection{}
+	for rows.Next() {
+		var connection models.VelvetHourConnection
+		var email string
+		var instagram sql.NullString
+		var shareInstagram, shareEmail bool
+		err := rows.Scan(
+			&connection.ID, &connection.EventID, &connection.EventName, &connection.ConnectedAt,
+			&connection.UserID, &connection.Name, &email, &instagram,
+			&shareInstagram, &shareEmail,
+		)
+		if err != nil {
+			log.Printf("Failed to scan connection: %v", err)
+			continue
+		}
+
+		if shareInstagram && instagram.Valid && instagram.String != "" {
+			connection.InstagramHandle = &instagram.String
+		}
+		if shareEmail {
+			connection.Email = &email
+		}
+		connections = append(connections, connection)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connections)
+}
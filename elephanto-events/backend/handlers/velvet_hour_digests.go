@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"elephanto-events/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// digestDelaySeconds gives everyone time to leave feedback on the final round
+// before a completed session's digest goes out
+const digestDelaySeconds = 15 * 60
+
+// digestSendingTimeoutSeconds is how long a digest can stay claimed before it
+// is taken to have been abandoned by a run that crashed, and is claimed again
+const digestSendingTimeoutSeconds = 10 * 60
+
+// The scheduler retries a failed digest every digestRetryDelaySeconds until it
+// has been tried digestMaxAttempts times
+const (
+	digestRetryDelaySeconds = 15 * 60
+	digestMaxAttempts       = 3
+)
+
+// SetEmailService sets the service used to email participants their session digest
+func (h *VelvetHourHandler) SetEmailService(email *services.EmailService) {
+	h.email = email
+}
+
+// sendDueDigests starts emailing digests for sessions that completed long
+// enough ago. Each session is claimed before its emails are sent so only one
+// run ever picks it up. Sessions with digests that failed, or were left
+// sending, are run again to retry them; a failed digest is retried until it
+// has been tried digestMaxAttempts times, after which only an admin sending
+// the session's digests again retries it.
+func (h *VelvetHourHandler) sendDueDigests() {
+	if h.email == nil {
+		return
+	}
+
+	completed, err := h.querySessionIDs(`
+		SELECT id FROM velvet_hour_sessions
+		WHERE status = $1 AND digest_sent_at IS NULL
+		  AND ended_at <= CURRENT_TIMESTAMP - make_interval(secs => $2)
+	`, sessionStatusCompleted, digestDelaySeconds)
+	if err != nil {
+		log.Printf("VelvetHour digests: failed to find completed sessions: %v", err)
+		return
+	}
+
+	var sessionIDs []uuid.UUID
+	for _, sessionID := range completed {
+		claimed, err := h.claimSessionDigests(sessionID)
+		if err != nil {
+			log.Printf("VelvetHour digests: failed to claim session %s: %v", sessionID, err)
+			continue
+		}
+		if claimed {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+
+	retries, err := h.querySessionIDs(`
+		SELECT DISTINCT session_id FROM velvet_hour_digests
+		WHERE (status = 'failed' AND attempts < $1
+			   AND updated_at <= CURRENT_TIMESTAMP - make_interval(secs => $2))
+		   OR (status = 'sending' AND updated_at <= CURRENT_TIMESTAMP - make_interval(secs => $3))
+	`, digestMaxAttempts, digestRetryDelaySeconds, digestSendingTimeoutSeconds)
+	if err != nil {
+		log.Printf("VelvetHour digests: failed to find digests to retry: %v", err)
+	}
+	sessionIDs = append(sessionIDs, retries...)
+
+	for _, sessionID := range sessionIDs {
+		go func(sessionID uuid.UUID) {
+			result, err := h.sendSessionDigests(sessionID, true)
+			if err != nil {
+				log.Printf("VelvetHour digests: failed for session %s: %v", sessionID, err)
+				return
+			}
+			log.Printf("VelvetHour digests: session %s sent %d, failed %d, skipped %d",
+				sessionID, result.Sent, result.Failed, result.Skipped)
+		}(sessionID)
+	}
+}
+
+// querySessionIDs returns the session IDs a query selects
+func (h *VelvetHourHandler) querySessionIDs(query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessionIDs []uuid.UUID
+	for rows.Next() {
+		var sessionID uuid.UUID
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	return sessionIDs, rows.Err()
+}
+
+// claimSessionDigests marks a completed session's digests as going out, under
+// the session lock, and reports whether this call was the first to
+func (h *VelvetHourHandler) claimSessionDigests(sessionID uuid.UUID) (bool, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockAnySession(tx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := session.markDigestsSent()
+	if err != nil {
+		return false, err
+	}
+	return claimed, tx.Commit()
+}
+
+// claimDigest reserves a participant's digest for sending. Digests that
+// already went out, or are being sent, are not claimed again; failed ones are,
+// and so are ones left sending by a run that never finished. The scheduler
+// only claims a failed digest again once its retry is due.
+func (h *VelvetHourHandler) claimDigest(sessionID, userID uuid.UUID, scheduled bool) (bool, error) {
+	var claimed uuid.UUID
+	err := h.db.QueryRow(`
+		INSERT INTO velvet_hour_digests (session_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id, user_id) DO UPDATE
+		SET status = 'sending', error = NULL, attempts = velvet_hour_digests.attempts + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE (velvet_hour_digests.status = 'failed'
+			   AND (NOT $4 OR (velvet_hour_digests.attempts < $5
+							   AND velvet_hour_digests.updated_at <= CURRENT_TIMESTAMP - make_interval(secs => $6))))
+		   OR (velvet_hour_digests.status = 'sending'
+			   AND velvet_hour_digests.updated_at <= CURRENT_TIMESTAMP - make_interval(secs => $3))
+		RETURNING user_id
+	`, sessionID, userID, digestSendingTimeoutSeconds, scheduled, digestMaxAttempts, digestRetryDelaySeconds).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// loadDigest works out what a participant's digest says: how many people
+// they met and their mutual connections, with whatever each chose to share
+func (h *VelvetHourHandler) loadDigest(sessionID, userID uuid.UUID) (services.VelvetHourDigest, error) {
+	var digest services.VelvetHourDigest
+	err := h.db.QueryRow(`
+		SELECT COUNT(DISTINCT other.user_id)
+		FROM velvet_hour_match_members me
+		JOIN velvet_hour_matches m ON me.match_id = m.id
+		JOIN velvet_hour_match_members other ON other.match_id = m.id AND other.user_id <> me.user_id
+		WHERE m.session_id = $1 AND me.user_id = $2 AND m.status <> 'no_show'
+	`, sessionID, userID).Scan(&digest.PeopleMet)
+	if err != nil {
+		return digest, fmt.Errorf("failed to count people met: %w", err)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT COALESCE(NULLIF(other.name, ''), 'Someone you met'), other.email, sr.instagramHandle,
+			   CASE WHEN c.user1_id = $2 THEN c.user2_shares_instagram ELSE c.user1_shares_instagram END,
+			   CASE WHEN c.user1_id = $2 THEN c.user2_shares_email ELSE c.user1_shares_email END
+		FROM velvet_hour_connections c
+		JOIN users other ON other.id = CASE WHEN c.user1_id = $2 THEN c.user2_id ELSE c.user1_id END
+		LEFT JOIN survey_responses sr ON sr.userId = other.id AND sr.event_id = c.event_id
+		WHERE c.session_id = $1 AND (c.user1_id = $2 OR c.user2_id = $2)
+		ORDER BY c.created_at
+	`, sessionID, userID)
+	if err != nil {
+		return digest, fmt.Errorf("failed to get connections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var connection services.VelvetHourDigestConnection
+		var email string
+		var instagram sql.NullString
+		var shareInstagram, shareEmail bool
+		if err := rows.Scan(&connection.Name, &email, &instagram, &shareInstagram, &shareEmail); err != nil {
+			return digest, fmt.Errorf("failed to scan connection: %w", err)
+		}
+		if shareInstagram && instagram.Valid {
+			connection.Instagram = instagram.String
+		}
+		if shareEmail {
+			connection.Email = email
+		}
+		digest.Connections = append(digest.Connections, connection)
+	}
+	return digest, rows.Err()
+}
+
+// sendSessionDigests emails every participant who met someone at the session
+// their personal summary. Anyone already emailed is skipped, so running it
+// again only retries failed sends; scheduled runs only retry the ones that
+// are due.
+func (h *VelvetHourHandler) sendSessionDigests(sessionID uuid.UUID, scheduled bool) (models.VelvetHourDigestResult, error) {
+	result := models.VelvetHourDigestResult{SessionID: sessionID}
+	if h.email == nil {
+		return result, fmt.Errorf("email is not configured")
+	}
+
+	var eventName string
+	err := h.db.QueryRow(`
+		SELECT e.name FROM velvet_hour_sessions s
+		JOIN events e ON s.event_id = e.id
+		WHERE s.id = $1
+	`, sessionID).Scan(&eventName)
+	if err != nil {
+		return result, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	type recipient struct {
+		UserID uuid.UUID
+		Email  string
+		Name   sql.NullString
+	}
+	rows, err := h.db.Query(`
+		SELECT p.user_id, u.email, u.name
+		FROM velvet_hour_participants p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.session_id = $1
+		ORDER BY p.joined_at
+	`, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to get participants: %w", err)
+	}
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.UserID, &r.Email, &r.Name); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("failed to scan participant: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+
+	for _, r := range recipients {
+		digest, err := h.loadDigest(sessionID, r.UserID)
+		if err != nil {
+			return result, err
+		}
+		if digest.PeopleMet == 0 {
+			result.Skipped++
+			continue
+		}
+
+		claimed, err := h.claimDigest(sessionID, r.UserID, scheduled)
+		if err != nil {
+			return result, fmt.Errorf("failed to claim digest: %w", err)
+		}
+		if !claimed {
+			result.Skipped++
+			continue
+		}
+
+		digest.Name = r.Name.String
+		digest.EventName = eventName
+		if err := h.email.SendVelvetHourDigest(r.Email, digest); err != nil {
+			log.Printf("VelvetHour digests: failed to email %s: %v", r.UserID, err)
+			_, updateErr := h.db.Exec(`
+				UPDATE velvet_hour_digests SET status = 'failed', error = $3, updated_at = CURRENT_TIMESTAMP
+				WHERE session_id = $1 AND user_id = $2
+			`, sessionID, r.UserID, err.Error())
+			if updateErr != nil {
+				return result, fmt.Errorf("failed to record digest failure: %w", updateErr)
+			}
+			result.Failed++
+			continue
+		}
+
+		_, err = h.db.Exec(`
+			UPDATE velvet_hour_digests SET status = 'sent', sent_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE session_id = $1 AND user_id = $2
+		`, sessionID, r.UserID)
+		if err != nil {
+			return result, fmt.Errorf("failed to record digest: %w", err)
+		}
+		result.Sent++
+	}
+
+	return result, nil
+}
+
+// SendDigests emails a completed session's participants their digest now,
+// without waiting for the scheduler. Pass ?sessionId= for an earlier session.
+// Anyone already emailed is skipped, so it is safe to run again after failures.
+func (h *VelvetHourHandler) SendDigests(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := h.timelineSession(r, eventID)
+	if err == errSessionNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to send digests", http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the session so the scheduler doesn't send it as well
+	if _, err := h.claimSessionDigests(sessionID); err != nil {
+		writeSessionError(w, err, "Failed to send digests")
+		return
+	}
+
+	result, err := h.sendSessionDigests(sessionID, false)
+	if err != nil {
+		log.Printf("Failed to send digests: %v", err)
+		http.Error(w, "Failed to send digests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/middleware"
+	"elephanto-events/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// errPairExcluded is returned when an admin edit would match two users who are
+// on the do-not-pair list
+var errPairExcluded = errors.New("These participants must not be matched")
+
+// orderedPair returns two user IDs with the lower one first, the order the
+// exclusions table stores them in
+func orderedPair(user1, user2 uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if user1.String() > user2.String() {
+		return user2, user1
+	}
+	return user1, user2
+}
+
+// loadExcludedPairs returns the pairs among users that a block or an admin
+// exclusion keeps apart at the event, in the same form as a previous-pairs set
+func (h *VelvetHourHandler) loadExcludedPairs(eventID uuid.UUID, userIDs []uuid.UUID) (map[string]bool, error) {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	excluded := make(map[string]bool)
+	rows, err := h.db.Query(`
+		SELECT user1_id, user2_id FROM velvet_hour_pair_exclusions
+		WHERE (event_id IS NULL OR event_id = $1)
+		  AND user1_id = ANY($2::uuid[]) AND user2_id = ANY($2::uuid[])
+	`, eventID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user1, user2 uuid.UUID
+		if err := rows.Scan(&user1, &user2); err != nil {
+			return nil, err
+		}
+		addPair(excluded, user1, user2)
+	}
+	return excluded, rows.Err()
+}
+
+// requireNotExcluded makes sure no two of the members are on the do-not-pair list
+func (h *VelvetHourHandler) requireNotExcluded(eventID uuid.UUID, members []uuid.UUID) error {
+	excluded, err := h.loadExcludedPairs(eventID, members)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(members); i++ {
+		for j := i + 1; j < len(members); j++ {
+			if excluded[pairKey(members[i], members[j])] {
+				return errPairExcluded
+			}
+		}
+	}
+	return nil
+}
+
+// addBlock stores a user's own block of someone else for every event
+func addBlock(tx *sql.Tx, userID, blockedID uuid.UUID, source, reason string) error {
+	user1, user2 := orderedPair(userID, blockedID)
+	_, err := tx.Exec(`
+		INSERT INTO velvet_hour_pair_exclusions (user1_id, user2_id, source, created_by, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`, user1, user2, source, userID, reason)
+	return err
+}
+
+// GetBlocks lists the people the current user has asked never to be matched with
+func (h *VelvetHourHandler) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "User not found", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT other.id, COALESCE(NULLIF(other.name, ''), other.email), x.reason, x.created_at
+		FROM velvet_hour_pair_exclusions x
+		JOIN users other ON other.id = CASE WHEN x.user1_id = $1 THEN x.user2_id ELSE x.user1_id END
+		WHERE x.created_by = $1 AND x.source IN ('user', 'feedback')
+		ORDER BY x.created_at DESC
+	`, user.ID)
+	if err != nil {
+		log.Printf("Failed to get blocks: %v", err)
+		http.Error(w, "Failed to get blocks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	blocks := []models.VelvetHourBlock{}
+	for rows.Next() {
+		var block models.VelvetHourBlock
+		if err := rows.Scan(&block.UserID, &block.Name, &block.Reason, &block.CreatedAt); err != nil {
+			log.Printf("Failed to scan block: %v", err)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// BlockUser stops the current user from being matched with someone at any event.
+// The blocked person is never told.
+func (h *VelvetHourHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "User not found", http.StatusInternalServerError)
+		return
+	}
+
+	var req models.BlockUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == uuid.Nil || req.UserID == user.ID {
+		http.Error(w, "Invalid user to block", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, req.UserID).Scan(&exists)
+	if err != nil {
+		log.Printf("Failed to check user: %v", err)
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := addBlock(tx, user.ID, req.UserID, "user", strings.TrimSpace(req.Reason)); err != nil {
+		log.Printf("Failed to block user: %v", err)
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to commit block: %v", err)
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User blocked"})
+}
+
+// UnblockUser removes the current user's own blocks of someone. Admin
+// exclusions of the same pair stay in place.
+func (h *VelvetHourHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		http.Error(w, "User not found", http.StatusInternalServerError)
+		return
+	}
+
+	blockedID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user1, user2 := orderedPair(user.ID, blockedID)
+	result, err := h.db.Exec(`
+		DELETE FROM velvet_hour_pair_exclusions
+		WHERE user1_id = $1 AND user2_id = $2 AND created_by = $3 AND source IN ('user', 'feedback')
+	`, user1, user2, user.ID)
+	if err != nil {
+		log.Printf("Failed to unblock user: %v", err)
+		http.Error(w, "Failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		http.Error(w, "Block not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User unblocked"})
+}
+
+// GetExclusions lists the exclusions that apply at an event: the event's own,
+// plus blocks and permanent exclusions between two of its attendees
+func (h *VelvetHourHandler) GetExclusions(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		WITH people AS (
+			SELECT user_id FROM event_attendance WHERE event_id = $1 AND attending = true
+			UNION
+			SELECT p.user_id FROM velvet_hour_participants p
+			JOIN velvet_hour_sessions s ON p.session_id = s.id
+			WHERE s.event_id = $1
+		)
+		SELECT x.id, x.user1_id, COALESCE(NULLIF(u1.name, ''), u1.email),
+			   x.user2_id, COALESCE(NULLIF(u2.name, ''), u2.email),
+			   x.event_id, x.source, x.created_by, x.reason, x.created_at
+		FROM velvet_hour_pair_exclusions x
+		JOIN users u1 ON x.user1_id = u1.id
+		JOIN users u2 ON x.user2_id = u2.id
+		WHERE x.event_id = $1
+		   OR (x.event_id IS NULL
+			   AND x.user1_id IN (SELECT user_id FROM people)
+			   AND x.user2_id IN (SELECT user_id FROM people))
+		ORDER BY x.created_at DESC
+	`, eventID)
+	if err != nil {
+		log.Printf("Failed to get exclusions: %v", err)
+		http.Error(w, "Failed to get exclusions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	exclusions := []models.VelvetHourPairExclusion{}
+	for rows.Next() {
+		var exclusion models.VelvetHourPairExclusion
+		err := rows.Scan(
+			&exclusion.ID, &exclusion.User1ID, &exclusion.User1Name,
+			&exclusion.User2ID, &exclusion.User2Name,
+			&exclusion.EventID, &exclusion.Source, &exclusion.CreatedBy, &exclusion.Reason, &exclusion.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan exclusion: %v", err)
+			continue
+		}
+		exclusions = append(exclusions, exclusion)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exclusions)
+}
+
+// CreateExclusion keeps two users apart at this event, or at every event when
+// the exclusion is permanent
+func (h *VelvetHourHandler) CreateExclusion(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreatePairExclusionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.User1ID == uuid.Nil || req.User2ID == uuid.Nil || req.User1ID == req.User2ID {
+		http.Error(w, "Pick two different users", http.StatusBadRequest)
+		return
+	}
+
+	var scope *uuid.UUID
+	if !req.Permanent {
+		scope = &eventID
+	}
+	var createdBy *uuid.UUID
+	if admin, ok := middleware.GetUserFromContext(r); ok {
+		createdBy = &admin.ID
+	}
+	user1, user2 := orderedPair(req.User1ID, req.User2ID)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to create exclusion", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	exclusion := models.VelvetHourPairExclusion{
+		User1ID:   user1,
+		User2ID:   user2,
+		EventID:   scope,
+		Source:    "admin",
+		CreatedBy: createdBy,
+		Reason:    strings.TrimSpace(req.Reason),
+	}
+	err = tx.QueryRow(`
+		INSERT INTO velvet_hour_pair_exclusions (user1_id, user2_id, event_id, source, created_by, reason)
+		VALUES ($1, $2, $3, 'admin', $4, $5)
+		RETURNING id, created_at
+	`, user1, user2, scope, createdBy, exclusion.Reason).Scan(&exclusion.ID, &exclusion.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_velvet_hour_pair_exclusions_unique") {
+			http.Error(w, "These users are already excluded", http.StatusConflict)
+			return
+		}
+		if strings.Contains(err.Error(), "foreign key") {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to create exclusion: %v", err)
+		http.Error(w, "Failed to create exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	err = logAdminAction(tx, r, user1, "velvet_hour_exclusion_create", map[string]interface{}{}, exclusion)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to create exclusion: %v", err)
+		http.Error(w, "Failed to create exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(exclusion)
+}
+
+// DeleteExclusion removes an admin exclusion. Users' own blocks can only be
+// lifted by the users themselves.
+func (h *VelvetHourHandler) DeleteExclusion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	exclusionID, err := uuid.Parse(vars["exclusionId"])
+	if err != nil {
+		http.Error(w, "Invalid exclusion ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to delete exclusion", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var exclusion models.VelvetHourPairExclusion
+	err = tx.QueryRow(`
+		DELETE FROM velvet_hour_pair_exclusions
+		WHERE id = $1 AND source = 'admin' AND (event_id IS NULL OR event_id = $2)
+		RETURNING id, user1_id, user2_id, event_id, source, created_by, reason, created_at
+	`, exclusionID, eventID).Scan(
+		&exclusion.ID, &exclusion.User1ID, &exclusion.User2ID, &exclusion.EventID,
+		&exclusion.Source, &exclusion.CreatedBy, &exclusion.Reason, &exclusion.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Exclusion not found", http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		err = logAdminAction(tx, r, exclusion.User1ID, "velvet_hour_exclusion_delete", exclusion, map[string]interface{}{})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to delete exclusion: %v", err)
+		http.Error(w, "Failed to delete exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Exclusion deleted"})
+}
+
+// GetExclusionConflicts lists current-round matches and planned pairings of the
+// active session that put two excluded users together
+func (h *VelvetHourHandler) GetExclusionConflicts(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	conflicts := []models.VelvetHourExclusionConflict{}
+
+	var sessionID uuid.UUID
+	var currentRound int
+	err = h.db.QueryRow(`
+		SELECT id, current_round FROM velvet_hour_sessions
+		WHERE event_id = $1 AND is_active = true
+	`, eventID).Scan(&sessionID, &currentRound)
+	if err == sql.ErrNoRows {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conflicts)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+
+	participants, err := h.getActiveParticipants(sessionID)
+	if err != nil {
+		log.Printf("Failed to get participants: %v", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+	excluded, err := h.loadExcludedPairs(eventID, participants)
+	if err != nil {
+		log.Printf("Failed to load exclusions: %v", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+	if len(excluded) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conflicts)
+		return
+	}
+
+	// Matches of the round in progress
+	rows, err := h.db.Query(`
+		SELECT id, round_number, match_number, user1_id, user2_id, user3_id
+		FROM velvet_hour_matches
+		WHERE session_id = $1 AND round_number = $2 AND status = 'active'
+		ORDER BY match_number
+	`, sessionID, currentRound)
+	if err != nil {
+		log.Printf("Failed to get matches: %v", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+	var matches []models.VelvetHourMatch
+	for rows.Next() {
+		var match models.VelvetHourMatch
+		if err := rows.Scan(&match.ID, &match.RoundNumber, &match.MatchNumber, &match.User1ID, &match.User2ID, &match.User3ID); err != nil {
+			log.Printf("Failed to scan match: %v", err)
+			continue
+		}
+		matches = append(matches, match)
+	}
+	rows.Close()
+
+	for _, match := range matches {
+		match.Group, err = loadMatchMembers(h.db, match.ID)
+		if err != nil {
+			log.Printf("Failed to get match members: %v", err)
+			continue
+		}
+		members := match.Members()
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				if excluded[pairKey(members[i], members[j])] {
+					matchID, matchNumber := match.ID, match.MatchNumber
+					conflicts = append(conflicts, models.VelvetHourExclusionConflict{
+						Kind: "match", RoundNumber: match.RoundNumber, MatchID: &matchID, MatchNumber: &matchNumber,
+						User1ID: members[i], User2ID: members[j],
+					})
+				}
+			}
+		}
+	}
+
+	// Pairings the session's schedule still plans for later rounds
+	rows, err = h.db.Query(`
+		SELECT round_number, user1_id, user2_id FROM velvet_hour_schedule
+		WHERE session_id = $1 AND round_number > $2 AND user2_id IS NOT NULL
+		ORDER BY round_number
+	`, sessionID, currentRound)
+	if err != nil {
+		log.Printf("Failed to get schedule: %v", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var conflict models.VelvetHourExclusionConflict
+		if err := rows.Scan(&conflict.RoundNumber, &conflict.User1ID, &conflict.User2ID); err != nil {
+			log.Printf("Failed to scan schedule: %v", err)
+			continue
+		}
+		if excluded[pairKey(conflict.User1ID, conflict.User2ID)] {
+			conflict.Kind = "schedule"
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	rows.Close()
+
+	names, err := h.loadUserNames(participants)
+	if err != nil {
+		log.Printf("Failed to load names: %v", err)
+	}
+	for i := range conflicts {
+		conflicts[i].User1Name = names[conflicts[i].User1ID]
+		conflicts[i].User2Name = names[conflicts[i].User2ID]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/middleware"
+	"elephanto-events/models"
+	"elephanto-events/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// errNoRoundInProgress is returned when editing matches outside a round
+var errNoRoundInProgress = errors.New("No round is in progress")
+
+// errMatchNotFound is returned when a match isn't an active match of the current round
+var errMatchNotFound = errors.New("Match not found in the current round")
+
+// errUserNotInMatch is returned when a swap names a user who isn't in the given match
+var errUserNotInMatch = errors.New("User is not in that match")
+
+// errParticipantUnavailable is returned when pairing someone who has left or
+// already has a match this round
+var errParticipantUnavailable = errors.New("Participant is not free to be paired")
+
+// errPairNotAllowed is returned when an admin edit would break the event's
+// hard matching constraints
+var errPairNotAllowed = errors.New("These participants can't be matched under the event's matching constraints")
+
+// errPairRepeat is returned when an admin edit would match two users who
+// already met earlier in the session
+var errPairRepeat = errors.New("These participants already met this session")
+
+// SwapMatchPartners moves one user from each of two current-round matches into
+// the other. Both matches keep their number, color and table, and the members
+// who stay keep their confirmations. Neither user may be moved in with someone
+// the event's constraints rule out or whom they already met this session.
+func (h *VelvetHourHandler) SwapMatchPartners(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SwapMatchPartnersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MatchAID == req.MatchBID {
+		http.Error(w, "Pick two different matches", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to swap partners", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	session, matchA, matchB, err := lockMatchPair(tx, eventID, req.MatchAID, req.MatchBID)
+	if err == nil && (!matchA.HasMember(req.UserAID) || !matchB.HasMember(req.UserBID)) {
+		err = errUserNotInMatch
+	}
+	var membersA, membersB []uuid.UUID
+	if err == nil {
+		membersA = replaceMember(matchA.Members(), req.UserAID, req.UserBID)
+		membersB = replaceMember(matchB.Members(), req.UserBID, req.UserAID)
+		err = h.requireNotExcluded(eventID, membersA)
+	}
+	if err == nil {
+		err = h.requireNotExcluded(eventID, membersB)
+	}
+	if err == nil {
+		err = h.requireNewcomerAllowed(eventID, session, req.UserBID, membersA)
+	}
+	if err == nil {
+		err = h.requireNewcomerAllowed(eventID, session, req.UserAID, membersB)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to swap partners")
+		return
+	}
+
+	var changes []reassignment
+	for _, edit := range []struct {
+		match   *models.VelvetHourMatch
+		members []uuid.UUID
+	}{{matchA, membersA}, {matchB, membersB}} {
+		if err := abandonMatch(tx, edit.match.ID); err != nil {
+			writeSessionError(w, err, "Failed to swap partners")
+			return
+		}
+		newMatchID, err := insertRoundMatch(tx, session.ID, session.CurrentRound, edit.members, edit.match.MatchNumber, edit.match.MatchColor, edit.match.TableID)
+		if err == nil {
+			err = carryConfirmations(tx, edit.match.ID, newMatchID)
+		}
+		if err != nil {
+			writeSessionError(w, err, "Failed to swap partners")
+			return
+		}
+		for _, member := range edit.members {
+			changes = append(changes, reassignment{UserID: member, MatchID: &newMatchID, MatchNumber: edit.match.MatchNumber, MatchColor: edit.match.MatchColor, Table: edit.match.Table})
+		}
+	}
+
+	err = logAdminAction(tx, r, req.UserAID, "velvet_hour_match_swap",
+		map[string]interface{}{"matches": []map[string]interface{}{matchSnapshot(matchA), matchSnapshot(matchB)}},
+		map[string]interface{}{"matches": []map[string]interface{}{
+			{"matchNumber": matchA.MatchNumber, "members": membersA},
+			{"matchNumber": matchB.MatchNumber, "members": membersB},
+		}},
+	)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to swap partners")
+		return
+	}
+
+	log.Printf("VelvetHour: swapped %s and %s between matches %d and %d in round %d", req.UserAID, req.UserBID, matchA.MatchNumber, matchB.MatchNumber, session.CurrentRound)
+	h.notifyMatchEdit(eventID, session, changes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Partners swapped"})
+}
+
+// SplitMatch ends a current-round match early and leaves its members waiting
+// so they can be paired again by hand
+func (h *VelvetHourHandler) SplitMatch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	matchID, err := uuid.Parse(vars["matchId"])
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to split match", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	session, err := lockRoundInProgress(tx, eventID)
+	var match *models.VelvetHourMatch
+	if err == nil {
+		match, err = lockRoundMatch(tx, session, matchID)
+	}
+	if err == nil {
+		err = abandonMatch(tx, match.ID)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to split match")
+		return
+	}
+
+	var changes []reassignment
+	for _, member := range match.Members() {
+		_, err = tx.Exec(`
+			INSERT INTO velvet_hour_byes (session_id, round_number, user_id, reason)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (session_id, round_number, user_id) DO NOTHING
+		`, session.ID, session.CurrentRound, member, byeReasonWaiting)
+		if err == nil {
+			err = recordSessionEvent(tx, session.ID, sessionEventWaiting, session.CurrentRound, &member, nil, nil)
+		}
+		if err != nil {
+			log.Printf("Failed to record waiting participant: %v", err)
+			http.Error(w, "Failed to split match", http.StatusInternalServerError)
+			return
+		}
+		changes = append(changes, reassignment{UserID: member})
+	}
+
+	err = logAdminAction(tx, r, match.User1ID, "velvet_hour_match_split", matchSnapshot(match), map[string]interface{}{"waiting": match.Members()})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to split match")
+		return
+	}
+
+	log.Printf("VelvetHour: split match %d in round %d of session %s", match.MatchNumber, session.CurrentRound, session.ID)
+	h.notifyMatchEdit(eventID, session, changes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Match split"})
+}
+
+// PairParticipants matches two participants who are waiting in the current
+// round, under the next free match number and at a free table if there is one
+func (h *VelvetHourHandler) PairParticipants(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PairParticipantsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.User1ID == req.User2ID {
+		http.Error(w, "Pick two different participants", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to pair participants", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	members := []uuid.UUID{req.User1ID, req.User2ID}
+	session, err := lockRoundInProgress(tx, eventID)
+	if err == nil {
+		err = requireWaiting(tx, session, members)
+	}
+	if err == nil {
+		err = h.requireNotExcluded(eventID, members)
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to pair participants")
+		return
+	}
+
+	var matchNumber int
+	err = tx.QueryRow(`
+		SELECT COALESCE(MAX(match_number), 0) + 1 FROM velvet_hour_matches
+		WHERE session_id = $1 AND round_number = $2
+	`, session.ID, session.CurrentRound).Scan(&matchNumber)
+	if err != nil {
+		log.Printf("Failed to get next match number: %v", err)
+		http.Error(w, "Failed to pair participants", http.StatusInternalServerError)
+		return
+	}
+	matchColor := matchColors[matchNumber%len(matchColors)]
+
+	table, err := pickFreeTable(tx, session.ID, session.CurrentRound, members)
+	if err != nil {
+		log.Printf("Failed to find a free table: %v", err)
+		http.Error(w, "Failed to pair participants", http.StatusInternalServerError)
+		return
+	}
+
+	matchID, err := insertRoundMatch(tx, session.ID, session.CurrentRound, members, matchNumber, matchColor, tableIDOf(table))
+	if err == nil {
+		_, err = tx.Exec(`
+			DELETE FROM velvet_hour_byes
+			WHERE session_id = $1 AND round_number = $2 AND user_id IN ($3, $4)
+		`, session.ID, session.CurrentRound, req.User1ID, req.User2ID)
+	}
+	if err == nil {
+		err = logAdminAction(tx, r, req.User1ID, "velvet_hour_match_pair",
+			map[string]interface{}{"waiting": members},
+			map[string]interface{}{"matchId": matchID, "matchNumber": matchNumber, "matchColor": matchColor, "table": table, "members": members},
+		)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeSessionError(w, err, "Failed to pair participants")
+		return
+	}
+
+	var changes []reassignment
+	for _, member := range members {
+		changes = append(changes, reassignment{UserID: member, MatchID: &matchID, MatchNumber: matchNumber, MatchColor: matchColor, Table: table})
+	}
+
+	log.Printf("VelvetHour: paired %s and %s as match %d in round %d", req.User1ID, req.User2ID, matchNumber, session.CurrentRound)
+	h.notifyMatchEdit(eventID, session, changes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Participants paired"})
+}
+
+// lockRoundInProgress locks the event's active session and makes sure a round
+// is running
+func lockRoundInProgress(tx *sql.Tx, eventID uuid.UUID) (*sessionState, error) {
+	session, err := lockActiveSession(tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != sessionStatusInRound {
+		return nil, errNoRoundInProgress
+	}
+	return session, nil
+}
+
+// lockMatchPair locks the session and two of its current-round matches, always
+// in the same order so concurrent swaps can't deadlock
+func lockMatchPair(tx *sql.Tx, eventID, matchAID, matchBID uuid.UUID) (*sessionState, *models.VelvetHourMatch, *models.VelvetHourMatch, error) {
+	session, err := lockRoundInProgress(tx, eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	first, second := matchAID, matchBID
+	if first.String() > second.String() {
+		first, second = second, first
+	}
+	locked := make(map[uuid.UUID]*models.VelvetHourMatch)
+	for _, matchID := range []uuid.UUID{first, second} {
+		match, err := lockRoundMatch(tx, session, matchID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		locked[matchID] = match
+	}
+	return session, locked[matchAID], locked[matchBID], nil
+}
+
+// lockRoundMatch locks an active match of the session's current round
+func lockRoundMatch(tx *sql.Tx, session *sessionState, matchID uuid.UUID) (*models.VelvetHourMatch, error) {
+	var match models.VelvetHourMatch
+	err := tx.QueryRow(`
+		SELECT id, user1_id, user2_id, user3_id, match_number, match_color, table_id
+		FROM velvet_hour_matches
+		WHERE id = $1 AND session_id = $2 AND round_number = $3 AND status = 'active'
+		FOR UPDATE
+	`, matchID, session.ID, session.CurrentRound).Scan(
+		&match.ID, &match.User1ID, &match.User2ID, &match.User3ID, &match.MatchNumber, &match.MatchColor, &match.TableID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errMatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+	match.Group, err = loadMatchMembers(tx, match.ID)
+	if err != nil {
+		return nil, err
+	}
+	match.Table, err = loadMatchTable(tx, match.TableID)
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// requireNewcomerAllowed makes sure a user moved into a match may be paired
+// with each of its other members: the event's hard constraints allow it and
+// they haven't met earlier in the session
+func (h *VelvetHourHandler) requireNewcomerAllowed(eventID uuid.UUID, session *sessionState, newcomer uuid.UUID, members []uuid.UUID) error {
+	forbidden, err := h.forbiddenPairs(eventID, members)
+	if err != nil {
+		return err
+	}
+	previousPairs, err := h.loadPreviousPairs(session.ID, session.CurrentRound)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member == newcomer {
+			continue
+		}
+		if forbidden[pairKey(newcomer, member)] {
+			return errPairNotAllowed
+		}
+		if previousPairs[pairKey(newcomer, member)] {
+			return errPairRepeat
+		}
+	}
+	return nil
+}
+
+// requireWaiting makes sure every user is still taking part and has no active
+// match in the current round
+func requireWaiting(tx *sql.Tx, session *sessionState, userIDs []uuid.UUID) error {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	var free int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM velvet_hour_participants p
+		WHERE p.session_id = $1 AND p.user_id = ANY($3::uuid[]) AND p.status != 'completed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM velvet_hour_matches m
+			JOIN velvet_hour_match_members mm ON mm.match_id = m.id
+			WHERE m.session_id = $1 AND m.round_number = $2 AND m.status = 'active'
+			  AND mm.user_id = p.user_id
+		  )
+	`, session.ID, session.CurrentRound, pq.Array(ids)).Scan(&free)
+	if err != nil {
+		return fmt.Errorf("failed to check participants: %w", err)
+	}
+	if free != len(userIDs) {
+		return errParticipantUnavailable
+	}
+	return nil
+}
+
+// abandonMatch closes a match that is being replaced
+func abandonMatch(tx *sql.Tx, matchID uuid.UUID) error {
+	_, err := tx.Exec(`
+		UPDATE velvet_hour_matches
+		SET status = 'abandoned', abandoned_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, matchID)
+	if err != nil {
+		return fmt.Errorf("failed to abandon match: %w", err)
+	}
+	return recordMatchEvent(tx, matchID, sessionEventMatchClosed, nil, matchClosedPayload{Status: "abandoned"})
+}
+
+// insertRoundMatch stores a pair, trio or pod in the given round, at a table
+// when one is given. The match's user columns hold the first three members;
+// everyone is listed as a member. The compatibility the event's weights
+// predict for the match is stored with it.
+func insertRoundMatch(tx *sql.Tx, sessionID uuid.UUID, roundNumber int, members []uuid.UUID, matchNumber int, matchColor string, tableID *uuid.UUID) (uuid.UUID, error) {
+	var user3 *uuid.UUID
+	if len(members) > 2 {
+		user3 = &members[2]
+	}
+
+	score, err := predictMatchScore(tx, sessionID, members)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var matchID uuid.UUID
+	err = tx.QueryRow(`
+		INSERT INTO velvet_hour_matches
+		(session_id, round_number, user1_id, user2_id, user3_id, match_number, match_color, table_id, compatibility_score)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, sessionID, roundNumber, members[0], members[1], user3, matchNumber, matchColor, tableID, score).Scan(&matchID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create match: %w", err)
+	}
+
+	for i, member := range members {
+		_, err = tx.Exec(`
+			INSERT INTO velvet_hour_match_members (match_id, user_id, position)
+			VALUES ($1, $2, $3)
+		`, matchID, member, i+1)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to add match member: %w", err)
+		}
+	}
+
+	err = recordSessionEvent(tx, sessionID, sessionEventMatchCreated, roundNumber, nil, &matchID, matchCreatedPayload{
+		Members:     members,
+		MatchNumber: matchNumber,
+		MatchColor:  matchColor,
+		TableID:     tableID,
+	})
+	return matchID, err
+}
+
+// replaceMember returns the members with one user swapped for another
+func replaceMember(members []uuid.UUID, from, to uuid.UUID) []uuid.UUID {
+	replaced := make([]uuid.UUID, len(members))
+	for i, member := range members {
+		if member == from {
+			member = to
+		}
+		replaced[i] = member
+	}
+	return replaced
+}
+
+// matchSnapshot describes a match for the audit log
+func matchSnapshot(match *models.VelvetHourMatch) map[string]interface{} {
+	return map[string]interface{}{
+		"matchId":     match.ID,
+		"matchNumber": match.MatchNumber,
+		"matchColor":  match.MatchColor,
+		"table":       match.Table,
+		"members":     match.Members(),
+	}
+}
+
+// logAdminAction records an admin's Velvet Hour change in the audit log
+func logAdminAction(tx *sql.Tx, r *http.Request, targetUserID uuid.UUID, action string, oldValue, newValue interface{}) error {
+	var adminID *uuid.UUID
+	if admin, ok := middleware.GetUserFromContext(r); ok {
+		adminID = &admin.ID
+	}
+	oldJSON, _ := json.Marshal(oldValue)
+	newJSON, _ := json.Marshal(newValue)
+
+	_, err := tx.Exec(`
+		INSERT INTO adminauditlogs (adminid, targetuserid, action, oldvalue, newvalue, ipaddress)
+		VALUES ($1, $2, $3, $4::jsonb, $5::jsonb, $6)
+	`, adminID, targetUserID, action, string(oldJSON), string(newJSON), getClientIP(r))
+	if err != nil {
+		return fmt.Errorf("failed to log admin action: %w", err)
+	}
+	return nil
+}
+
+// notifyMatchEdit sends the affected participants their new matches and lets
+// admins know the round changed
+func (h *VelvetHourHandler) notifyMatchEdit(eventID uuid.UUID, session *sessionState, changes []reassignment) {
+	if h.hub == nil {
+		return
+	}
+
+	h.sendReassignments(eventID, session.ID, session.CurrentRound, changes)
+	h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
+		"sessionId":    session.ID,
+		"status":       session.Status,
+		"currentRound": session.CurrentRound,
+	})
+}
@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// matchProfile holds the survey answers and drink preference used to score a pairing
+type matchProfile struct {
+	Age                int
+	Gender             string
+	TorontoMeaning     string
+	Personality        string
+	ConnectionType     string
+	CocktailPreference string
+}
+
+// Gender rules an event can apply when both people came for dating
+const (
+	// datingGenderAny ignores gender
+	datingGenderAny = "any"
+	// datingGenderDifferent favours, or with the hard flag requires, pairs of different genders
+	datingGenderDifferent = "different"
+)
+
+// validDatingGenderRules lists the accepted values for an event's dating gender rule
+var validDatingGenderRules = map[string]bool{
+	datingGenderAny:       true,
+	datingGenderDifferent: true,
+}
+
+// defaultMatchingWeights favours people looking for the same kind of connection
+var defaultMatchingWeights = models.VelvetHourMatchingWeights{
+	ConnectionType: 0.30,
+	Personality:    0.20,
+	TorontoMeaning: 0.15,
+	Age:            0.15,
+	Gender:         0.10,
+	Cocktail:       0.10,
+}
+
+// defaultMatchingConfig is used for events whose admins have not tuned matching
+func defaultMatchingConfig(eventID uuid.UUID) models.VelvetHourMatchingConfig {
+	return models.VelvetHourMatchingConfig{
+		EventID:          eventID,
+		Weights:          defaultMatchingWeights,
+		DatingGenderRule: datingGenderDifferent,
+	}
+}
+
+// complementaryPersonalities lists personality types that tend to get on well
+var complementaryPersonalities = map[string]string{
+	"Social":      "Adventurous",
+	"Adventurous": "Social",
+	"Ambitious":   "Balanced",
+	"Balanced":    "Intentional",
+	"Intentional": "Balanced",
+}
+
+// maxAgeGapScored is the age gap at which the age component drops to zero
+const maxAgeGapScored = 15.0
+
+// bothDating reports whether both people came to the event for dating
+func bothDating(a, b matchProfile) bool {
+	return a.ConnectionType == "Dating" && b.ConnectionType == "Dating"
+}
+
+// pairAllowed applies the event's hard constraints to a pairing. Missing
+// survey answers never rule a pair out.
+func pairAllowed(a, b matchProfile, config models.VelvetHourMatchingConfig) bool {
+	if config.MaxAgeGap != nil && a.Age > 0 && b.Age > 0 {
+		gap := a.Age - b.Age
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > *config.MaxAgeGap {
+			return false
+		}
+	}
+
+	if config.RequireSameConnectionType && a.ConnectionType != "" && b.ConnectionType != "" && a.ConnectionType != b.ConnectionType {
+		return false
+	}
+
+	if config.DatingGenderHard && config.DatingGenderRule == datingGenderDifferent && bothDating(a, b) &&
+		a.Gender != "" && a.Gender == b.Gender {
+		return false
+	}
+
+	return true
+}
+
+// compatibilityScore rates a pairing between 0 and 1. Fields either person
+// left blank are ignored; with nothing to compare the pairing scores 0.5.
+func compatibilityScore(a, b matchProfile, config models.VelvetHourMatchingConfig) float64 {
+	weights := config.Weights
+	score := 0.0
+	totalWeight := 0.0
+	add := func(weight, value float64) {
+		score += weight * value
+		totalWeight += weight
+	}
+
+	if a.ConnectionType != "" && b.ConnectionType != "" {
+		if a.ConnectionType == b.ConnectionType {
+			add(weights.ConnectionType, 1.0)
+		} else {
+			add(weights.ConnectionType, 0.2)
+		}
+	}
+
+	if a.Personality != "" && b.Personality != "" {
+		switch {
+		case a.Personality == b.Personality:
+			add(weights.Personality, 1.0)
+		case complementaryPersonalities[a.Personality] == b.Personality || complementaryPersonalities[b.Personality] == a.Personality:
+			add(weights.Personality, 0.7)
+		default:
+			add(weights.Personality, 0.4)
+		}
+	}
+
+	if a.TorontoMeaning != "" && b.TorontoMeaning != "" {
+		if a.TorontoMeaning == b.TorontoMeaning {
+			add(weights.TorontoMeaning, 1.0)
+		} else {
+			add(weights.TorontoMeaning, 0.4)
+		}
+	}
+
+	if a.Age > 0 && b.Age > 0 {
+		gap := math.Abs(float64(a.Age - b.Age))
+		add(weights.Age, math.Max(0, 1-gap/maxAgeGapScored))
+	}
+
+	// Gender only matters when both people came for dating
+	if config.DatingGenderRule == datingGenderDifferent && bothDating(a, b) && a.Gender != "" && b.Gender != "" {
+		if a.Gender != b.Gender {
+			add(weights.Gender, 1.0)
+		} else {
+			add(weights.Gender, 0.3)
+		}
+	}
+
+	if a.CocktailPreference != "" && b.CocktailPreference != "" {
+		switch {
+		case a.CocktailPreference == b.CocktailPreference:
+			add(weights.Cocktail, 1.0)
+		case a.CocktailPreference == "non-alcoholic" || b.CocktailPreference == "non-alcoholic":
+			add(weights.Cocktail, 0.2)
+		default:
+			add(weights.Cocktail, 0.5)
+		}
+	}
+
+	if totalWeight == 0 {
+		return 0.5
+	}
+	return score / totalWeight
+}
+
+// groupScore rates a match as the average compatibility of every pairing in it
+func groupScore(members []uuid.UUID, profiles map[uuid.UUID]matchProfile, config models.VelvetHourMatchingConfig) float64 {
+	total := 0.0
+	pairings := 0
+	for i := 0; i < len(members); i++ {
+		for j := i + 1; j < len(members); j++ {
+			total += compatibilityScore(profiles[members[i]], profiles[members[j]], config)
+			pairings++
+		}
+	}
+	if pairings == 0 {
+		return 0
+	}
+	return total / float64(pairings)
+}
+
+// predictMatchScore works out the compatibility the event's matching weights
+// predict for a new match, so outcomes can later be compared against it
+func predictMatchScore(q queryer, sessionID uuid.UUID, members []uuid.UUID) (float64, error) {
+	var eventID uuid.UUID
+	err := q.QueryRow(`
+		SELECT event_id FROM velvet_hour_sessions WHERE id = $1
+	`, sessionID).Scan(&eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session event: %w", err)
+	}
+	config, err := queryMatchingConfig(q, eventID)
+	if err != nil {
+		return 0, err
+	}
+	profiles, err := queryMatchProfiles(q, eventID, members)
+	if err != nil {
+		return 0, err
+	}
+	return groupScore(members, profiles, config), nil
+}
+
+// weightedPairings pairs users so the total compatibility across the round is
+// as high as possible, never pairing two people who already met or whom the
+// event's hard constraints keep apart. It first
+// maximises the number of pairs, then their combined score. Anyone who cannot
+// be paired is left out of the result.
+func weightedPairings(userIDs []uuid.UUID, profiles map[uuid.UUID]matchProfile, config models.VelvetHourMatchingConfig, previousPairs map[string]bool) [][2]uuid.UUID {
+	n := len(userIDs)
+	if n < 2 {
+		return [][2]uuid.UUID{}
+	}
+
+	// Scores are scaled to integers; the per-edge bonus outweighs any score
+	// difference so an extra pair always wins over a better-scoring smaller set
+	const scoreScale = 1000
+	bonus := int64((scoreScale + 1) * n)
+
+	matcher := newBlossomMatcher(n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if previousPairs[pairKey(userIDs[i], userIDs[j])] {
+				continue
+			}
+			a, b := profiles[userIDs[i]], profiles[userIDs[j]]
+			if !pairAllowed(a, b, config) {
+				continue
+			}
+			score := compatibilityScore(a, b, config)
+			matcher.addEdge(i+1, j+1, bonus+1+int64(math.Round(score*scoreScale)))
+		}
+	}
+
+	partners := matcher.solve()
+	pairs := [][2]uuid.UUID{}
+	for i := 1; i <= n; i++ {
+		if partners[i] > i {
+			pairs = append(pairs, [2]uuid.UUID{userIDs[i-1], userIDs[partners[i]-1]})
+		}
+	}
+	return pairs
+}
+
+// loadMatchProfiles reads the survey answers and drink preferences for an event's users
+func (h *VelvetHourHandler) loadMatchProfiles(eventID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]matchProfile, error) {
+	return queryMatchProfiles(h.db, eventID, userIDs)
+}
+
+// queryMatchProfiles reads match profiles with any queryer, so they can be
+// loaded inside a transaction
+func queryMatchProfiles(q queryer, eventID uuid.UUID, userIDs []uuid.UUID) (map[uuid.UUID]matchProfile, error) {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	profiles := make(map[uuid.UUID]matchProfile)
+	rows, err := q.Query(`
+		SELECT userId, age, gender, torontoMeaning, personality, connectionType
+		FROM survey_responses
+		WHERE event_id = $1 AND userId = ANY($2::uuid[])
+	`, eventID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load survey responses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID uuid.UUID
+		var age sql.NullInt64
+		var gender, torontoMeaning, personality, connectionType sql.NullString
+		if err := rows.Scan(&userID, &age, &gender, &torontoMeaning, &personality, &connectionType); err != nil {
+			return nil, fmt.Errorf("failed to scan survey response: %w", err)
+		}
+		profiles[userID] = matchProfile{
+			Age:            int(age.Int64),
+			Gender:         gender.String,
+			TorontoMeaning: torontoMeaning.String,
+			Personality:    personality.String,
+			ConnectionType: connectionType.String,
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefRows, err := q.Query(`
+		SELECT userId, preference
+		FROM cocktail_preferences
+		WHERE event_id = $1 AND userId = ANY($2::uuid[])
+	`, eventID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cocktail preferences: %w", err)
+	}
+	defer prefRows.Close()
+
+	for prefRows.Next() {
+		var userID uuid.UUID
+		var preference string
+		if err := prefRows.Scan(&userID, &preference); err != nil {
+			return nil, fmt.Errorf("failed to scan cocktail preference: %w", err)
+		}
+		profile := profiles[userID]
+		profile.CocktailPreference = preference
+		profiles[userID] = profile
+	}
+
+	return profiles, prefRows.Err()
+}
+
+// forbiddenPairs returns the pairs among users that the event's hard
+// constraints or the do-not-pair list rule out, in the same form as a
+// previous-pairs set
+func (h *VelvetHourHandler) forbiddenPairs(eventID uuid.UUID, userIDs []uuid.UUID) (map[string]bool, error) {
+	forbidden, err := h.loadExcludedPairs(eventID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	config, err := h.loadMatchingConfig(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxAgeGap == nil && !config.RequireSameConnectionType && !config.DatingGenderHard {
+		return forbidden, nil
+	}
+
+	profiles, err := h.loadMatchProfiles(eventID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(userIDs); i++ {
+		for j := i + 1; j < len(userIDs); j++ {
+			if !pairAllowed(profiles[userIDs[i]], profiles[userIDs[j]], config) {
+				addPair(forbidden, userIDs[i], userIDs[j])
+			}
+		}
+	}
+
+	return forbidden, nil
+}
+
+// loadMatchingConfig returns an event's matching configuration, or the defaults
+// when none has been saved
+func (h *VelvetHourHandler) loadMatchingConfig(eventID uuid.UUID) (models.VelvetHourMatchingConfig, error) {
+	return queryMatchingConfig(h.db, eventID)
+}
+
+// queryMatchingConfig reads an event's matching configuration with any queryer
+func queryMatchingConfig(q queryer, eventID uuid.UUID) (models.VelvetHourMatchingConfig, error) {
+	config := defaultMatchingConfig(eventID)
+	var weights []byte
+	var maxAgeGap sql.NullInt64
+	var updatedAt sql.NullTime
+	err := q.QueryRow(`
+		SELECT weights, max_age_gap, require_same_connection_type,
+			   dating_gender_rule, dating_gender_hard, updated_at
+		FROM velvet_hour_matching_configs
+		WHERE event_id = $1
+	`, eventID).Scan(
+		&weights, &maxAgeGap, &config.RequireSameConnectionType,
+		&config.DatingGenderRule, &config.DatingGenderHard, &updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to load matching config: %w", err)
+	}
+
+	if err := json.Unmarshal(weights, &config.Weights); err != nil {
+		return config, fmt.Errorf("failed to parse matching weights: %w", err)
+	}
+	if maxAgeGap.Valid {
+		gap := int(maxAgeGap.Int64)
+		config.MaxAgeGap = &gap
+	}
+	if updatedAt.Valid {
+		config.UpdatedAt = &updatedAt.Time
+	}
+
+	return config, nil
+}
+
+// GetMatchingConfig returns the matching weights and constraints of an event
+func (h *VelvetHourHandler) GetMatchingConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	config, err := h.loadMatchingConfig(eventID)
+	if err != nil {
+		log.Printf("Failed to get matching config: %v", err)
+		http.Error(w, "Failed to get matching configuration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+// UpdateMatchingConfig changes the matching weights and constraints of an event
+func (h *VelvetHourHandler) UpdateMatchingConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateVelvetHourMatchingConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	config, err := h.loadMatchingConfig(eventID)
+	if err != nil {
+		log.Printf("Failed to get matching config: %v", err)
+		http.Error(w, "Failed to get matching configuration", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Weights != nil {
+		weights := *req.Weights
+		values := []float64{weights.ConnectionType, weights.Personality, weights.TorontoMeaning, weights.Age, weights.Gender, weights.Cocktail}
+		total := 0.0
+		for _, value := range values {
+			if value < 0 {
+				http.Error(w, "Weights cannot be negative", http.StatusBadRequest)
+				return
+			}
+			total += value
+		}
+		if total == 0 {
+			http.Error(w, "At least one weight must be positive", http.StatusBadRequest)
+			return
+		}
+		config.Weights = weights
+	}
+	if req.MaxAgeGap != nil {
+		if *req.MaxAgeGap < 0 {
+			http.Error(w, "Max age gap cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if *req.MaxAgeGap == 0 {
+			config.MaxAgeGap = nil
+		} else {
+			config.MaxAgeGap = req.MaxAgeGap
+		}
+	}
+	if req.RequireSameConnectionType != nil {
+		config.RequireSameConnectionType = *req.RequireSameConnectionType
+	}
+	if req.DatingGenderRule != nil {
+		if !validDatingGenderRules[*req.DatingGenderRule] {
+			http.Error(w, "Invalid dating gender rule. Must be 'any' or 'different'", http.StatusBadRequest)
+			return
+		}
+		config.DatingGenderRule = *req.DatingGenderRule
+	}
+	if req.DatingGenderHard != nil {
+		config.DatingGenderHard = *req.DatingGenderHard
+	}
+
+	weights, err := json.Marshal(config.Weights)
+	if err != nil {
+		http.Error(w, "Invalid weights", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO velvet_hour_matching_configs
+		(event_id, weights, max_age_gap, require_same_connection_type, dating_gender_rule, dating_gender_hard)
+		VALUES ($1, $2::jsonb, $3, $4, $5, $6)
+		ON CONFLICT (event_id) DO UPDATE SET
+			weights = EXCLUDED.weights,
+			max_age_gap = EXCLUDED.max_age_gap,
+			require_same_connection_type = EXCLUDED.require_same_connection_type,
+			dating_gender_rule = EXCLUDED.dating_gender_rule,
+			dating_gender_hard = EXCLUDED.dating_gender_hard,
+			updated_at = CURRENT_TIMESTAMP
+	`, eventID, string(weights), config.MaxAgeGap, config.RequireSameConnectionType,
+		config.DatingGenderRule, config.DatingGenderHard)
+	if err != nil {
+		log.Printf("Failed to update matching config: %v", err)
+		http.Error(w, "Failed to update matching configuration", http.StatusInternalServerError)
+		return
+	}
+
+	config, err = h.loadMatchingConfig(eventID)
+	if err != nil {
+		log.Printf("Failed to reload matching config: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
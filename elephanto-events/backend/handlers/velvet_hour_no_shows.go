@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"elephanto-events/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// expireUnconfirmedMatches closes every current-round match whose confirmation
+// window has run out without everyone confirming. Time the session spent
+// paused doesn't count towards the window.
+func (h *VelvetHourHandler) expireUnconfirmedMatches() {
+	rows, err := h.db.Query(`
+		SELECT m.id, s.event_id
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		JOIN events e ON s.event_id = e.id
+		WHERE s.is_active = true AND s.status = 'in_round' AND s.paused_at IS NULL
+		  AND m.round_number = s.current_round AND m.status = 'active'
+		  AND EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND NOT mm.confirmed)
+		  AND COALESCE(e.the_hour_confirmation_window, 0) > 0
+		  AND m.created_at + make_interval(secs => e.the_hour_confirmation_window + m.confirmation_paused_seconds) <= CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		log.Printf("Failed to find unconfirmed matches: %v", err)
+		return
+	}
+
+	type expiredMatch struct {
+		ID      uuid.UUID
+		EventID uuid.UUID
+	}
+	var expired []expiredMatch
+	for rows.Next() {
+		var match expiredMatch
+		if err := rows.Scan(&match.ID, &match.EventID); err != nil {
+			log.Printf("Failed to scan unconfirmed match: %v", err)
+			continue
+		}
+		expired = append(expired, match)
+	}
+	rows.Close()
+
+	for _, match := range expired {
+		if err := h.recordNoShow(match.EventID, match.ID); err != nil {
+			log.Printf("Failed to record no-show for match %s: %v", match.ID, err)
+		}
+	}
+}
+
+// recordNoShow marks a match as a no-show, records everyone who didn't confirm,
+// and re-pairs whoever did. The window is checked again once the session is
+// locked, since it may have been paused or resumed after the match was picked.
+func (h *VelvetHourHandler) recordNoShow(eventID, matchID uuid.UUID) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	session, err := lockRoundInProgress(tx, eventID)
+	if errors.Is(err, errNoActiveSession) || errors.Is(err, errNoRoundInProgress) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.PausedAt != nil {
+		// Paused since the match was picked; its clock is stopped
+		return nil
+	}
+	sessionID, currentRound := session.ID, session.CurrentRound
+
+	var match models.VelvetHourMatch
+	err = tx.QueryRow(`
+		SELECT m.id, m.match_number, m.match_color, m.table_id
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_sessions s ON m.session_id = s.id
+		JOIN events e ON s.event_id = e.id
+		WHERE m.id = $1 AND m.session_id = $2 AND m.round_number = $3 AND m.status = 'active'
+		  AND COALESCE(e.the_hour_confirmation_window, 0) > 0
+		  AND m.created_at + make_interval(secs => e.the_hour_confirmation_window + m.confirmation_paused_seconds) <= CURRENT_TIMESTAMP
+		FOR UPDATE OF m
+	`, matchID, sessionID, currentRound).Scan(&match.ID, &match.MatchNumber, &match.MatchColor, &match.TableID)
+	if err == sql.ErrNoRows {
+		// Someone else already closed or replaced it, or a pause since it was
+		// picked has pushed its window back
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get match: %w", err)
+	}
+	match.Group, err = loadMatchMembers(tx, match.ID)
+	if err != nil {
+		return err
+	}
+	match.Table, err = loadMatchTable(tx, match.TableID)
+	if err != nil {
+		return err
+	}
+
+	var noShows, remaining []uuid.UUID
+	for _, member := range match.Group {
+		if member.Confirmed {
+			remaining = append(remaining, member.UserID)
+		} else {
+			noShows = append(noShows, member.UserID)
+		}
+	}
+	if len(noShows) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE velvet_hour_matches
+		SET status = 'no_show', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, matchID)
+	if err == nil {
+		err = recordMatchEvent(tx, matchID, sessionEventMatchClosed, nil, matchClosedPayload{Status: "no_show"})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark no-show: %w", err)
+	}
+
+	for _, userID := range noShows {
+		_, err = tx.Exec(`
+			INSERT INTO velvet_hour_no_shows (event_id, session_id, match_id, user_id, round_number)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (match_id, user_id) DO NOTHING
+		`, eventID, sessionID, matchID, userID, currentRound)
+		if err != nil {
+			return fmt.Errorf("failed to record no-show: %w", err)
+		}
+	}
+
+	changes, err := h.reassignRemaining(tx, eventID, sessionID, matchID, currentRound, match.MatchNumber, match.MatchColor, match.Table, remaining, noShows)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("VelvetHour: match %s in round %d expired with %d no-show(s)", matchID, currentRound, len(noShows))
+	if h.hub != nil {
+		h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourNoShow, map[string]interface{}{
+			"sessionId":   sessionID,
+			"roundNumber": currentRound,
+			"matchId":     matchID,
+			"matchNumber": match.MatchNumber,
+			"userIds":     noShows,
+		})
+	}
+	h.notifyReassignments(eventID, sessionID, currentRound, matchID, changes)
+	return nil
+}
+
+// GetReliability lists how reliably users have turned up for their Velvet Hour
+// matches across all events, including sessions that were since reset.
+// minNoShows narrows it to repeat offenders.
+func (h *VelvetHourHandler) GetReliability(w http.ResponseWriter, r *http.Request) {
+	minNoShows := 0
+	if value := r.URL.Query().Get("minNoShows"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid minNoShows", http.StatusBadRequest)
+			return
+		}
+		minNoShows = parsed
+	}
+
+	rows, err := h.db.Query(`
+		WITH memberships AS (
+			SELECT m.id AS match_id, s.event_id, mm.user_id
+			FROM velvet_hour_matches m
+			JOIN velvet_hour_sessions s ON m.session_id = s.id
+			JOIN velvet_hour_match_members mm ON mm.match_id = m.id
+			WHERE m.status != 'abandoned'
+			UNION ALL
+			-- No-shows from a reset session still count as a match they missed
+			SELECT ns.id, ns.event_id, ns.user_id
+			FROM velvet_hour_no_shows ns
+			WHERE ns.match_id IS NULL
+		)
+		SELECT u.id, u.name, u.email,
+			   COUNT(DISTINCT ms.event_id), COUNT(DISTINCT ms.match_id),
+			   (SELECT COUNT(*) FROM velvet_hour_no_shows ns WHERE ns.user_id = u.id) AS no_shows
+		FROM memberships ms
+		JOIN users u ON ms.user_id = u.id
+		GROUP BY u.id, u.name, u.email
+		HAVING (SELECT COUNT(*) FROM velvet_hour_no_shows ns WHERE ns.user_id = u.id) >= $1
+		ORDER BY no_shows DESC, u.name
+	`, minNoShows)
+	if err != nil {
+		log.Printf("Failed to get reliability: %v", err)
+		http.Error(w, "Failed to get reliability", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	records := []models.VelvetHourReliability{}
+	for rows.Next() {
+		var record models.VelvetHourReliability
+		err := rows.Scan(
+			&record.UserID, &record.Name, &record.Email,
+			&record.EventCount, &record.MatchCount, &record.NoShowCount,
+		)
+		if err != nil {
+			log.Printf("Failed to scan reliability: %v", err)
+			continue
+		}
+		if record.MatchCount > 0 {
+			record.NoShowRate = float64(record.NoShowCount) / float64(record.MatchCount)
+		}
+		records = append(records, record)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ways an event can group people in a round
+const (
+	// groupModePairs matches people one on one, folding in a third when the headcount is odd
+	groupModePairs = "pairs"
+	// groupModePods puts people in groups of minPodSize to maxPodSize
+	groupModePods = "pods"
+)
+
+// validGroupModes lists the accepted values for an event's group mode
+var validGroupModes = map[string]bool{
+	groupModePairs: true,
+	groupModePods:  true,
+}
+
+// Pod sizes an event may ask for
+const (
+	minPodSize = 3
+	maxPodSize = 6
+)
+
+// podAttempts is how many shuffled orders the pod planner tries per round
+const podAttempts = 40
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// loadMatchMembers returns everyone in a match in order, with their
+// confirmation and whether they've left feedback
+func loadMatchMembers(q queryer, matchID uuid.UUID) ([]models.VelvetHourMatchMember, error) {
+	rows, err := q.Query(`
+		SELECT mm.user_id, u.name, mm.position, mm.confirmed, mm.confirmed_at,
+			   EXISTS(SELECT 1 FROM velvet_hour_feedback f WHERE f.match_id = mm.match_id AND f.from_user_id = mm.user_id)
+		FROM velvet_hour_match_members mm
+		JOIN users u ON mm.user_id = u.id
+		WHERE mm.match_id = $1
+		ORDER BY mm.position
+	`, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.VelvetHourMatchMember{}
+	for rows.Next() {
+		var member models.VelvetHourMatchMember
+		err := rows.Scan(&member.UserID, &member.Name, &member.Position, &member.Confirmed, &member.ConfirmedAt, &member.FeedbackSubmitted)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// scanMatchGroups reads (match_id, user_id) rows into the members of each match
+func scanMatchGroups(rows *sql.Rows) ([][]uuid.UUID, error) {
+	defer rows.Close()
+
+	index := make(map[uuid.UUID]int)
+	var groups [][]uuid.UUID
+	for rows.Next() {
+		var matchID, userID uuid.UUID
+		if err := rows.Scan(&matchID, &userID); err != nil {
+			return nil, err
+		}
+		i, ok := index[matchID]
+		if !ok {
+			i = len(groups)
+			index[matchID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], userID)
+	}
+	return groups, rows.Err()
+}
+
+// countEncounters adds one encounter for every pair of people in each group
+func countEncounters(counts map[string]int, groups [][]uuid.UUID) {
+	for _, group := range groups {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				counts[pairKey(group[i], group[j])]++
+				counts[pairKey(group[j], group[i])]++
+			}
+		}
+	}
+}
+
+// loadEncounters counts how often each pair of users shared a match in the
+// session's earlier rounds
+func (h *VelvetHourHandler) loadEncounters(sessionID uuid.UUID, roundNumber int) (map[string]int, error) {
+	rows, err := h.db.Query(`
+		SELECT mm.match_id, mm.user_id
+		FROM velvet_hour_match_members mm
+		JOIN velvet_hour_matches m ON mm.match_id = m.id
+		WHERE m.session_id = $1 AND m.round_number < $2
+		ORDER BY mm.match_id, mm.position
+	`, sessionID, roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := scanMatchGroups(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	countEncounters(counts, groups)
+	return counts, nil
+}
+
+// podSizes splits n people into groups as close to the target size as the
+// pod limits allow, with sizes differing by at most one
+func podSizes(n, target int) []int {
+	if n < 2 {
+		return nil
+	}
+
+	groups := (n + target/2) / target
+	if groups < 1 {
+		groups = 1
+	}
+	for (n+groups-1)/groups > maxPodSize {
+		groups++
+	}
+	for groups > 1 && n/groups < minPodSize {
+		groups--
+	}
+
+	sizes := make([]int, groups)
+	for i := range sizes {
+		sizes[i] = n / groups
+		if i < n%groups {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// groupIntoPods plans one round of pods with a greedy social-golfer heuristic:
+// each pod is grown by adding whoever has met its current members least often.
+// Several shuffled orders are tried and the plan with the fewest repeat
+// encounters wins; the orders are shuffled with rng. Forbidden pairs never
+// share a pod; anyone who can't be placed without breaking that is returned as
+// left out.
+func groupIntoPods(rng *rand.Rand, participants []uuid.UUID, target int, encounters map[string]int, forbidden map[string]bool) ([][]uuid.UUID, []uuid.UUID) {
+	sizes := podSizes(len(participants), target)
+	if len(sizes) == 0 {
+		return nil, participants
+	}
+
+	conflicts := func(group []uuid.UUID, userID uuid.UUID) bool {
+		for _, member := range group {
+			if forbidden[pairKey(member, userID)] {
+				return true
+			}
+		}
+		return false
+	}
+	cost := func(group []uuid.UUID, userID uuid.UUID) int {
+		total := 0
+		for _, member := range group {
+			total += encounters[pairKey(member, userID)]
+		}
+		return total
+	}
+
+	var bestPods [][]uuid.UUID
+	var bestLeft []uuid.UUID
+	bestRepeats, bestLeftCount := -1, -1
+	for attempt := 0; attempt < podAttempts; attempt++ {
+		order := make([]uuid.UUID, len(participants))
+		copy(order, participants)
+		rng.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+
+		placed := make(map[uuid.UUID]bool)
+		pods := make([][]uuid.UUID, 0, len(sizes))
+		repeats := 0
+		for _, size := range sizes {
+			var pod []uuid.UUID
+			for len(pod) < size {
+				best, bestCost := uuid.Nil, -1
+				for _, userID := range order {
+					if placed[userID] || conflicts(pod, userID) {
+						continue
+					}
+					if c := cost(pod, userID); bestCost < 0 || c < bestCost {
+						best, bestCost = userID, c
+					}
+				}
+				if bestCost < 0 {
+					break
+				}
+				pod = append(pod, best)
+				placed[best] = true
+				repeats += bestCost
+			}
+			pods = append(pods, pod)
+		}
+
+		// Anyone still out joins the pod with room they've met least, if one
+		// will have them; pods too small to talk in are broken up the same way
+		var left []uuid.UUID
+		var kept [][]uuid.UUID
+		for _, pod := range pods {
+			if len(pod) >= 2 {
+				kept = append(kept, pod)
+				continue
+			}
+			for _, userID := range pod {
+				delete(placed, userID)
+			}
+		}
+		for _, userID := range order {
+			if placed[userID] {
+				continue
+			}
+			bestPod, bestCost := -1, -1
+			for i, pod := range kept {
+				if len(pod) >= maxPodSize || conflicts(pod, userID) {
+					continue
+				}
+				if c := cost(pod, userID); bestCost < 0 || c < bestCost {
+					bestPod, bestCost = i, c
+				}
+			}
+			if bestPod < 0 {
+				left = append(left, userID)
+				continue
+			}
+			kept[bestPod] = append(kept[bestPod], userID)
+			repeats += bestCost
+		}
+
+		if bestLeftCount < 0 || len(left) < bestLeftCount || (len(left) == bestLeftCount && repeats < bestRepeats) {
+			bestPods, bestLeft = kept, left
+			bestRepeats, bestLeftCount = repeats, len(left)
+		}
+		if bestLeftCount == 0 && bestRepeats == 0 {
+			break
+		}
+	}
+
+	return bestPods, bestLeft
+}
+
+// planPods works out a round of pods for the session. Repeat encounters within
+// the session are kept to a minimum, and with cross-event memory on so are
+// encounters from recent events. Nothing about the round itself is stored.
+func (h *VelvetHourHandler) planPods(sessionID uuid.UUID, roundNumber int, participants []uuid.UUID, config models.VelvetHourConfig) ([]models.ManualMatch, []uuid.UUID, []string, error) {
+	var eventID uuid.UUID
+	err := h.db.QueryRow(`
+		SELECT event_id FROM velvet_hour_sessions WHERE id = $1
+	`, sessionID).Scan(&eventID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	forbidden, err := h.forbiddenPairs(eventID, participants)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encounters, err := h.loadEncounters(sessionID, roundNumber)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if config.CrossEventMemory {
+		pastPairs, err := h.loadPastEventPairs(eventID, participants, config.MemoryLookbackDays)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for key := range pastPairs {
+			encounters[key]++
+		}
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	pods, left := groupIntoPods(rng, participants, config.PodSize, encounters, forbidden)
+
+	matches := make([]models.ManualMatch, 0, len(pods))
+	repeats := 0
+	for _, pod := range pods {
+		matches = append(matches, models.NewManualMatch(pod))
+		for i := 0; i < len(pod); i++ {
+			for j := i + 1; j < len(pod); j++ {
+				if encounters[pairKey(pod[i], pod[j])] > 0 {
+					repeats++
+				}
+			}
+		}
+	}
+
+	var warnings []string
+	if repeats > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d pair(s) in this round's pods have met before", repeats))
+	}
+	if len(left) > 0 && len(participants) >= 2 {
+		warnings = append(warnings, fmt.Sprintf("%d participant(s) could not join a pod without breaking the event's constraints", len(left)))
+	}
+
+	return numberMatches(matches), left, warnings, nil
+}
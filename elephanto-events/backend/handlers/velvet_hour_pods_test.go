@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPodSizes(t *testing.T) {
+	tests := []struct {
+		n, target int
+		want      []int
+	}{
+		{n: 1, target: 4, want: nil},
+		{n: 2, target: 4, want: []int{2}},
+		{n: 5, target: 3, want: []int{5}},
+		{n: 7, target: 4, want: []int{4, 3}},
+		{n: 8, target: 6, want: []int{4, 4}},
+		{n: 13, target: 4, want: []int{5, 4, 4}},
+	}
+
+	for _, tt := range tests {
+		got := podSizes(tt.n, tt.target)
+		if len(got) != len(tt.want) {
+			t.Errorf("podSizes(%d, %d) = %v, want %v", tt.n, tt.target, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("podSizes(%d, %d) = %v, want %v", tt.n, tt.target, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPodSizesStayWithinLimits(t *testing.T) {
+	for target := minPodSize; target <= maxPodSize; target++ {
+		for n := 2; n <= 60; n++ {
+			sizes := podSizes(n, target)
+			total, smallest, largest := 0, n, 0
+			for _, size := range sizes {
+				total += size
+				if size < smallest {
+					smallest = size
+				}
+				if size > largest {
+					largest = size
+				}
+			}
+			if total != n {
+				t.Errorf("podSizes(%d, %d) = %v places %d people", n, target, sizes, total)
+			}
+			if largest-smallest > 1 {
+				t.Errorf("podSizes(%d, %d) = %v differ by more than one", n, target, sizes)
+			}
+			if largest > maxPodSize || (len(sizes) > 1 && smallest < minPodSize) {
+				t.Errorf("podSizes(%d, %d) = %v break the pod limits", n, target, sizes)
+			}
+		}
+	}
+}
+
+func TestGroupIntoPods(t *testing.T) {
+	users := testUsers(14)
+	forbidden := make(map[string]bool)
+	addPair(forbidden, users[0], users[1])
+	addPair(forbidden, users[0], users[2])
+	addPair(forbidden, users[3], users[4])
+
+	// Everyone met their neighbours in an earlier round
+	encounters := make(map[string]int)
+	for i := 0; i+1 < len(users); i += 2 {
+		encounters[pairKey(users[i], users[i+1])]++
+		encounters[pairKey(users[i+1], users[i])]++
+	}
+
+	tests := []struct {
+		name       string
+		users      []uuid.UUID
+		target     int
+		encounters map[string]int
+		forbidden  map[string]bool
+	}{
+		{name: "too few", users: users[:1], target: 4},
+		{name: "even split", users: users[:12], target: 4},
+		{name: "uneven split", users: users, target: 5},
+		{name: "with history and constraints", users: users, target: 4, encounters: encounters, forbidden: forbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods, left := groupIntoPods(rand.New(rand.NewSource(1)), tt.users, tt.target, tt.encounters, tt.forbidden)
+
+			seen := make(map[uuid.UUID]bool)
+			for _, pod := range pods {
+				if len(pod) < 2 || len(pod) > maxPodSize {
+					t.Errorf("pod of %d", len(pod))
+				}
+				for i, member := range pod {
+					if seen[member] {
+						t.Fatalf("%s placed twice", member)
+					}
+					seen[member] = true
+					for _, other := range pod[i+1:] {
+						if tt.forbidden[pairKey(member, other)] {
+							t.Errorf("%s and %s share a pod despite a hard constraint", member, other)
+						}
+					}
+				}
+			}
+			for _, userID := range left {
+				if seen[userID] {
+					t.Fatalf("%s both placed and left out", userID)
+				}
+				seen[userID] = true
+			}
+			if len(seen) != len(tt.users) {
+				t.Errorf("%d of %d participants accounted for", len(seen), len(tt.users))
+			}
+			if len(tt.users) >= 2 && len(left) > 0 {
+				t.Errorf("%d left out, want everyone placed", len(left))
+			}
+		})
+	}
+}
+
+func TestGroupIntoPodsSameSeed(t *testing.T) {
+	users := testUsers(14)
+	first, _ := groupIntoPods(rand.New(rand.NewSource(7)), users, 4, nil, nil)
+	second, _ := groupIntoPods(rand.New(rand.NewSource(7)), users, 4, nil, nil)
+	if len(first) != len(second) {
+		t.Fatalf("%d pods, then %d with the same seed", len(first), len(second))
+	}
+	for i := range first {
+		for j := range first[i] {
+			if first[i][j] != second[i][j] {
+				t.Fatalf("pod %d differs between runs with the same seed", i+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"elephanto-events/services"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// presenceGracePeriod is how long a participant may stay disconnected during a
+// round before their match is given up, so a page refresh doesn't break it
+const presenceGracePeriod = 30 * time.Second
+
+// reassignment is a change to one participant's match after someone left
+type reassignment struct {
+	UserID      uuid.UUID
+	MatchID     *uuid.UUID
+	MatchNumber int
+	MatchColor  string
+	Table       *models.VelvetHourMatchTable
+}
+
+// handlePresenceChange reacts to a participant dropping out of the event room
+// while a round is running
+func (h *VelvetHourHandler) handlePresenceChange(eventID, userID uuid.UUID, present bool) {
+	h.recordPresence(eventID, userID, present)
+	if present {
+		return
+	}
+
+	time.AfterFunc(presenceGracePeriod, func() {
+		if h.hub != nil && h.hub.IsUserPresent(eventID, userID) {
+			return
+		}
+		if err := h.repairMatchAfterDeparture(eventID, userID); err != nil {
+			log.Printf("Failed to re-pair after user %s left event %s: %v", userID, eventID, err)
+		}
+	})
+}
+
+// repairMatchAfterDeparture abandons the departed user's match and pairs the
+// partner they left behind with another orphan or someone sitting the round out.
+// In a trio the two remaining members simply carry on as a pair.
+func (h *VelvetHourHandler) repairMatchAfterDeparture(eventID, userID uuid.UUID) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the session so concurrent departures don't claim the same partner
+	session, err := lockRoundInProgress(tx, eventID)
+	if errors.Is(err, errNoActiveSession) || errors.Is(err, errNoRoundInProgress) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sessionID, currentRound := session.ID, session.CurrentRound
+
+	var match struct {
+		ID          uuid.UUID
+		MatchNumber int
+		MatchColor  string
+		TableID     *uuid.UUID
+	}
+	err = tx.QueryRow(`
+		SELECT m.id, m.match_number, m.match_color, m.table_id
+		FROM velvet_hour_matches m
+		WHERE m.session_id = $1 AND m.round_number = $2 AND m.status = 'active'
+		  AND EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.user_id = $3)
+	`, sessionID, currentRound, userID).Scan(&match.ID, &match.MatchNumber, &match.MatchColor, &match.TableID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get match: %w", err)
+	}
+	group, err := loadMatchMembers(tx, match.ID)
+	if err != nil {
+		return err
+	}
+	table, err := loadMatchTable(tx, match.TableID)
+	if err != nil {
+		return err
+	}
+
+	if err := abandonMatch(tx, match.ID); err != nil {
+		return err
+	}
+
+	var remaining []uuid.UUID
+	for _, member := range group {
+		if member.UserID != userID && h.hub.IsUserPresent(eventID, member.UserID) {
+			remaining = append(remaining, member.UserID)
+		}
+	}
+
+	changes, err := h.reassignRemaining(tx, eventID, sessionID, match.ID, currentRound, match.MatchNumber, match.MatchColor, table, remaining, []uuid.UUID{userID})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("VelvetHour: user %s left round %d of session %s, %d participant(s) reassigned", userID, currentRound, sessionID, len(changes))
+	h.notifyReassignments(eventID, sessionID, currentRound, match.ID, changes)
+	return nil
+}
+
+// reassignRemaining finds new company for the members left behind when a
+// match ends early. Two or more carry on together under the old match number,
+// keeping the confirmations they already gave; a lone member is paired with
+// someone free and both have to confirm, or sits out if nobody is free.
+// Either way they stay at the old match's table. Excluded users are never
+// chosen as a partner.
+func (h *VelvetHourHandler) reassignRemaining(tx *sql.Tx, eventID, sessionID, previousMatchID uuid.UUID, roundNumber, matchNumber int, matchColor string, table *models.VelvetHourMatchTable, remaining, excluded []uuid.UUID) ([]reassignment, error) {
+	var changes []reassignment
+	switch {
+	case len(remaining) >= 2:
+		newMatchID, err := insertRoundMatch(tx, sessionID, roundNumber, remaining, matchNumber, matchColor, tableIDOf(table))
+		if err == nil {
+			err = carryConfirmations(tx, previousMatchID, newMatchID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range remaining {
+			changes = append(changes, reassignment{UserID: member, MatchID: &newMatchID, MatchNumber: matchNumber, MatchColor: matchColor, Table: table})
+		}
+
+	case len(remaining) == 1:
+		orphan := remaining[0]
+		partner, err := h.findReplacementPartner(tx, eventID, sessionID, roundNumber, orphan, excluded)
+		if err != nil {
+			return nil, err
+		}
+
+		if partner == uuid.Nil {
+			// Nobody free right now; the orphan waits until someone else is,
+			// which doesn't count as sitting a round out
+			_, err = tx.Exec(`
+				INSERT INTO velvet_hour_byes (session_id, round_number, user_id, reason)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (session_id, round_number, user_id) DO NOTHING
+			`, sessionID, roundNumber, orphan, byeReasonWaiting)
+			if err == nil {
+				err = recordSessionEvent(tx, sessionID, sessionEventWaiting, roundNumber, &orphan, nil, nil)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to record waiting participant: %w", err)
+			}
+			changes = append(changes, reassignment{UserID: orphan})
+			break
+		}
+
+		newMatchID, err := insertRoundMatch(tx, sessionID, roundNumber, []uuid.UUID{orphan, partner}, matchNumber, matchColor, tableIDOf(table))
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`
+			DELETE FROM velvet_hour_byes
+			WHERE session_id = $1 AND round_number = $2 AND user_id IN ($3, $4)
+		`, sessionID, roundNumber, orphan, partner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear byes: %w", err)
+		}
+		for _, member := range []uuid.UUID{orphan, partner} {
+			changes = append(changes, reassignment{UserID: member, MatchID: &newMatchID, MatchNumber: matchNumber, MatchColor: matchColor, Table: table})
+		}
+	}
+
+	return changes, nil
+}
+
+// carryConfirmations copies each member's confirmation from the match they
+// were in to the match that replaces it, so people who already found each
+// other aren't asked again or later counted as no-shows. Newcomers have to
+// confirm, by the old match's deadline rather than a fresh one.
+func carryConfirmations(tx *sql.Tx, fromMatchID, toMatchID uuid.UUID) error {
+	_, err := tx.Exec(`
+		UPDATE velvet_hour_match_members mm
+		SET confirmed = prev.confirmed, confirmed_at = prev.confirmed_at
+		FROM velvet_hour_match_members prev
+		WHERE mm.match_id = $2 AND prev.match_id = $1 AND prev.user_id = mm.user_id
+	`, fromMatchID, toMatchID)
+	if err != nil {
+		return fmt.Errorf("failed to carry confirmations: %w", err)
+	}
+
+	// The match columns mirror the first three members. The match only counts
+	// as confirmed and started if nobody in it still has to confirm.
+	_, err = tx.Exec(`
+		UPDATE velvet_hour_matches m
+		SET confirmed_user1 = EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.position = 1 AND mm.confirmed),
+			confirmed_user2 = EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.position = 2 AND mm.confirmed),
+			confirmed_user3 = EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND mm.position = 3 AND mm.confirmed),
+			confirmed_at = CASE WHEN NOT EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND NOT mm.confirmed)
+				THEN prev.confirmed_at END,
+			started_at = CASE WHEN NOT EXISTS(SELECT 1 FROM velvet_hour_match_members mm WHERE mm.match_id = m.id AND NOT mm.confirmed)
+				THEN prev.started_at END,
+			confirmation_paused_seconds = prev.confirmation_paused_seconds
+				- EXTRACT(EPOCH FROM (m.created_at - prev.created_at))::int
+		FROM velvet_hour_matches prev
+		WHERE m.id = $2 AND prev.id = $1
+	`, fromMatchID, toMatchID)
+	if err != nil {
+		return fmt.Errorf("failed to carry confirmations: %w", err)
+	}
+	return nil
+}
+
+// findReplacementPartner picks someone for an orphaned participant: a present
+// participant with no active match this round (another orphan or whoever is
+// sitting the round out), preferring people the orphan hasn't met yet.
+// It returns uuid.Nil when nobody is available.
+func (h *VelvetHourHandler) findReplacementPartner(tx *sql.Tx, eventID, sessionID uuid.UUID, roundNumber int, orphan uuid.UUID, excluded []uuid.UUID) (uuid.UUID, error) {
+	excludedIDs := []string{orphan.String()}
+	for _, userID := range excluded {
+		excludedIDs = append(excludedIDs, userID.String())
+	}
+
+	rows, err := tx.Query(`
+		SELECT p.user_id FROM velvet_hour_participants p
+		WHERE p.session_id = $1 AND p.status != 'completed'
+		  AND p.user_id != ALL($3::uuid[])
+		  AND NOT EXISTS (
+			SELECT 1 FROM velvet_hour_matches m
+			JOIN velvet_hour_match_members mm ON mm.match_id = m.id
+			WHERE m.session_id = $1 AND m.round_number = $2 AND m.status = 'active'
+			  AND mm.user_id = p.user_id
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM velvet_hour_no_shows ns
+			WHERE ns.session_id = $1 AND ns.round_number = $2 AND ns.user_id = p.user_id
+		  )
+		ORDER BY p.joined_at
+	`, sessionID, roundNumber, pq.Array(excludedIDs))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find free participants: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []uuid.UUID
+	for rows.Next() {
+		var candidate uuid.UUID
+		if err := rows.Scan(&candidate); err != nil {
+			return uuid.Nil, err
+		}
+		if h.hub.IsUserPresent(eventID, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, err
+	}
+	if len(candidates) == 0 {
+		return uuid.Nil, nil
+	}
+
+	excludedPairs, err := h.loadExcludedPairs(eventID, append(candidates, orphan))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	allowed := candidates[:0]
+	for _, candidate := range candidates {
+		if !excludedPairs[pairKey(orphan, candidate)] {
+			allowed = append(allowed, candidate)
+		}
+	}
+	candidates = allowed
+	if len(candidates) == 0 {
+		return uuid.Nil, nil
+	}
+
+	previousPairs, err := h.loadPreviousPairs(sessionID, roundNumber)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, candidate := range candidates {
+		if !previousPairs[pairKey(orphan, candidate)] {
+			return candidate, nil
+		}
+	}
+	return candidates[0], nil
+}
+
+// notifyReassignments tells each affected participant about their new match
+// and lets admins know the round changed
+func (h *VelvetHourHandler) notifyReassignments(eventID, sessionID uuid.UUID, roundNumber int, abandonedMatchID uuid.UUID, changes []reassignment) {
+	if h.hub == nil {
+		return
+	}
+
+	h.sendReassignments(eventID, sessionID, roundNumber, changes)
+	h.hub.BroadcastToAdmins(eventID, services.MessageTypeVelvetHourStatusUpdate, map[string]interface{}{
+		"sessionId":        sessionID,
+		"status":           "in_round",
+		"currentRound":     roundNumber,
+		"abandonedMatchId": abandonedMatchID,
+	})
+}
+
+// sendReassignments sends each affected participant their new match number,
+// color and table, or tells them to wait when they have no match for now
+func (h *VelvetHourHandler) sendReassignments(eventID, sessionID uuid.UUID, roundNumber int, changes []reassignment) {
+	for _, change := range changes {
+		payload := map[string]interface{}{
+			"sessionId":   sessionID,
+			"roundNumber": roundNumber,
+			"matchId":     change.MatchID,
+			"waiting":     change.MatchID == nil,
+		}
+		if change.MatchID != nil {
+			payload["matchNumber"] = change.MatchNumber
+			payload["matchColor"] = change.MatchColor
+			payload["table"] = change.Table
+		}
+		h.hub.SendToUsers(eventID, []uuid.UUID{change.UserID}, services.MessageTypeVelvetHourMatchReassigned, payload)
+	}
+}
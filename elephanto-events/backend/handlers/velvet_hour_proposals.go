@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/middleware"
+	"elephanto-events/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// errProposalNotFound is returned when starting a round from an unknown proposal
+var errProposalNotFound = errors.New("Proposal not found")
+
+// errProposalStale is returned when a proposal no longer fits the session:
+// it was made for another round, was already used, or names someone who left
+var errProposalStale = errors.New("Proposal is out of date, preview the round again")
+
+// manualMatchError reports a hand-made match that can't be used as given
+type manualMatchError struct {
+	Match   int
+	Problem string
+}
+
+func (e *manualMatchError) Error() string {
+	return fmt.Sprintf("Match %d %s", e.Match, e.Problem)
+}
+
+// PreviewRound builds a proposal for the next round without starting it. The
+// proposal is scored and checked for repeat pairs, and can then be started
+// unchanged by passing its ID to StartRound.
+func (h *VelvetHourHandler) PreviewRound(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PreviewRoundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var sessionID uuid.UUID
+	var status string
+	var currentRound int
+	err = h.db.QueryRow(`
+		SELECT id, status, current_round FROM velvet_hour_sessions
+		WHERE event_id = $1 AND is_active = true
+	`, eventID).Scan(&sessionID, &status, &currentRound)
+	if err == sql.ErrNoRows {
+		http.Error(w, "No active session", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+	if !canTransition(status, sessionStatusInRound) {
+		writeSessionError(w, &sessionTransitionError{From: status, To: sessionStatusInRound}, "Failed to preview round")
+		return
+	}
+	roundNumber := currentRound + 1
+
+	participants, err := h.getActiveParticipants(sessionID)
+	if err != nil {
+		log.Printf("Failed to get participants: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+
+	// Planning the first round may store the session's schedule, which is
+	// kept along with the proposal
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var matches []models.ManualMatch
+	var byes []uuid.UUID
+	var warnings []string
+	if len(req.Matches) > 0 {
+		if err := checkManualMatches(req.Matches, participants, h.loadEventConfig(eventID).GroupMode); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		matches = numberMatches(req.Matches)
+		byes = manualByes(participants, matches)
+	} else {
+		matches, byes, warnings, err = h.planRound(tx, sessionID, roundNumber)
+		if err != nil {
+			log.Printf("Failed to plan round: %v", err)
+			http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	proposal, err := h.annotateProposal(eventID, sessionID, roundNumber, participants, matches, byes)
+	if err != nil {
+		log.Printf("Failed to annotate proposal: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+	proposal.Warnings = append(proposal.Warnings, warnings...)
+
+	var createdBy *uuid.UUID
+	if user, ok := middleware.GetUserFromContext(r); ok {
+		createdBy = &user.ID
+	}
+	matchesJSON, err := json.Marshal(proposal.Matches)
+	if err != nil {
+		log.Printf("Failed to encode proposal: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+	byesJSON, _ := json.Marshal(byes)
+	err = tx.QueryRow(`
+		INSERT INTO velvet_hour_round_proposals
+		(session_id, round_number, matches, byes, compatibility_score, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, sessionID, roundNumber, string(matchesJSON), string(byesJSON), proposal.CompatibilityScore, createdBy).Scan(&proposal.ID, &proposal.CreatedAt)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to store proposal: %v", err)
+		http.Error(w, "Failed to preview round", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proposal)
+}
+
+// checkManualMatches makes sure hand-made matches fit the event's group mode,
+// pairs or trios in pairs mode and up to maxPodSize people in pods mode, and
+// only use active participants, each at most once
+func checkManualMatches(matches []models.ManualMatch, participants []uuid.UUID, groupMode string) error {
+	maxSize := 3
+	if groupMode == groupModePods {
+		maxSize = maxPodSize
+	}
+
+	active := make(map[uuid.UUID]bool)
+	for _, userID := range participants {
+		active[userID] = true
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for i, match := range matches {
+		members := match.Members()
+		if len(members) < 2 || len(members) > maxSize {
+			return &manualMatchError{Match: i + 1, Problem: fmt.Sprintf("must have between 2 and %d members", maxSize)}
+		}
+		for _, member := range members {
+			if !active[member] {
+				return &manualMatchError{Match: i + 1, Problem: "includes a user who is not taking part"}
+			}
+			if seen[member] {
+				return &manualMatchError{Match: i + 1, Problem: "includes a user who is already in another match"}
+			}
+			seen[member] = true
+		}
+	}
+	return nil
+}
+
+// annotateProposal scores each match and flags repeat pairs, pairs the event's
+// hard constraints rule out and members who aren't connected right now. Pairs
+// who met at a recent event are flagged when cross-event memory is on.
+func (h *VelvetHourHandler) annotateProposal(eventID, sessionID uuid.UUID, roundNumber int, participants []uuid.UUID, matches []models.ManualMatch, byes []uuid.UUID) (*models.VelvetHourRoundProposal, error) {
+	profiles, err := h.loadMatchProfiles(eventID, participants)
+	if err != nil {
+		return nil, err
+	}
+	config, err := h.loadMatchingConfig(eventID)
+	if err != nil {
+		return nil, err
+	}
+	previousPairs, err := h.loadPreviousPairs(sessionID, roundNumber)
+	if err != nil {
+		return nil, err
+	}
+	names, err := h.loadUserNames(participants)
+	if err != nil {
+		return nil, err
+	}
+	excludedPairs, err := h.loadExcludedPairs(eventID, participants)
+	if err != nil {
+		return nil, err
+	}
+	pastPairs := map[string]bool{}
+	if eventConfig := h.loadEventConfig(eventID); eventConfig.CrossEventMemory {
+		pastPairs, err = h.loadPastEventPairs(eventID, participants, eventConfig.MemoryLookbackDays)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	proposal := &models.VelvetHourRoundProposal{
+		SessionID:   sessionID,
+		RoundNumber: roundNumber,
+		Matches:     []models.VelvetHourProposedMatch{},
+		Unmatched:   []models.VelvetHourProposalUser{},
+		Warnings:    []string{},
+	}
+
+	totalScore := 0.0
+	for _, match := range matches {
+		proposed := models.VelvetHourProposedMatch{
+			ManualMatch: match,
+			User1Name:   names[match.User1ID],
+			User2Name:   names[match.User2ID],
+		}
+		if match.User3ID != nil {
+			name := names[*match.User3ID]
+			proposed.User3Name = &name
+		}
+		members := match.Members()
+		for _, member := range members {
+			proposed.MemberNames = append(proposed.MemberNames, names[member])
+		}
+
+		// A trio scores the average of its three pairings
+		pairScore := 0.0
+		pairings := 0
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				a, b := members[i], members[j]
+				pairScore += compatibilityScore(profiles[a], profiles[b], config)
+				pairings++
+
+				if previousPairs[pairKey(a, b)] {
+					proposed.RepeatPair = true
+					proposed.Warnings = append(proposed.Warnings, fmt.Sprintf("%s and %s already met this session", names[a], names[b]))
+				}
+				if excludedPairs[pairKey(a, b)] {
+					proposed.Warnings = append(proposed.Warnings, fmt.Sprintf("%s and %s are on the do-not-pair list", names[a], names[b]))
+				}
+				if pastPairs[pairKey(a, b)] {
+					proposed.Warnings = append(proposed.Warnings, fmt.Sprintf("%s and %s met at a recent event", names[a], names[b]))
+				}
+				if !pairAllowed(profiles[a], profiles[b], config) {
+					proposed.Warnings = append(proposed.Warnings, fmt.Sprintf("%s and %s break the event's matching constraints", names[a], names[b]))
+				}
+			}
+		}
+		proposed.CompatibilityScore = math.Round(pairScore/float64(pairings)*100) / 100
+		totalScore += proposed.CompatibilityScore
+
+		if h.hub != nil {
+			for _, member := range members {
+				if !h.hub.IsUserPresent(eventID, member) {
+					proposed.Warnings = append(proposed.Warnings, fmt.Sprintf("%s is not connected right now", names[member]))
+				}
+			}
+		}
+
+		if proposed.RepeatPair {
+			proposal.RepeatPairs++
+		}
+		proposal.Matches = append(proposal.Matches, proposed)
+	}
+
+	for _, userID := range byes {
+		proposal.Unmatched = append(proposal.Unmatched, models.VelvetHourProposalUser{UserID: userID, Name: names[userID]})
+	}
+
+	if len(matches) > 0 {
+		proposal.CompatibilityScore = math.Round(totalScore/float64(len(matches))*100) / 100
+	}
+	if proposal.RepeatPairs > 0 {
+		proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("%d match(es) repeat an earlier pairing", proposal.RepeatPairs))
+	}
+	if len(byes) > 0 {
+		proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("%d participant(s) sit this round out", len(byes)))
+	}
+	return proposal, nil
+}
+
+// loadUserNames returns display names for the given users, falling back to
+// their email when no name is set
+func (h *VelvetHourHandler) loadUserNames(userIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, COALESCE(NULLIF(name, ''), email) FROM users WHERE id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var userID uuid.UUID
+		var name string
+		if err := rows.Scan(&userID, &name); err != nil {
+			return nil, err
+		}
+		names[userID] = name
+	}
+	return names, rows.Err()
+}
+
+// takeProposal loads a proposal for the round being started and marks it used.
+// It refuses proposals made for another round, or for a different set of
+// participants than are taking part now, and matches that the do-not-pair list
+// has since ruled out.
+func (h *VelvetHourHandler) takeProposal(tx *sql.Tx, eventID, proposalID, sessionID uuid.UUID, roundNumber int) ([]models.ManualMatch, []uuid.UUID, error) {
+	var proposalRound int
+	var matchesJSON, byesJSON []byte
+	var used bool
+	err := tx.QueryRow(`
+		SELECT round_number, matches, byes, used_at IS NOT NULL
+		FROM velvet_hour_round_proposals
+		WHERE id = $1 AND session_id = $2
+		FOR UPDATE
+	`, proposalID, sessionID).Scan(&proposalRound, &matchesJSON, &byesJSON, &used)
+	if err == sql.ErrNoRows {
+		return nil, nil, errProposalNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load proposal: %w", err)
+	}
+	if used || proposalRound != roundNumber {
+		return nil, nil, errProposalStale
+	}
+
+	var proposed []models.VelvetHourProposedMatch
+	if err := json.Unmarshal(matchesJSON, &proposed); err != nil {
+		return nil, nil, fmt.Errorf("invalid proposal matches: %w", err)
+	}
+	var byes []uuid.UUID
+	if err := json.Unmarshal(byesJSON, &byes); err != nil {
+		return nil, nil, fmt.Errorf("invalid proposal byes: %w", err)
+	}
+
+	// Everyone taking part now has to be in a proposed match or sitting out,
+	// and nobody else
+	planned := make(map[uuid.UUID]bool)
+	matches := make([]models.ManualMatch, len(proposed))
+	for i, match := range proposed {
+		matches[i] = match.ManualMatch
+		for _, member := range match.Members() {
+			planned[member] = true
+		}
+	}
+	for _, userID := range byes {
+		planned[userID] = true
+	}
+
+	rows, err := tx.Query(`
+		SELECT user_id FROM velvet_hour_participants
+		WHERE session_id = $1 AND status != 'completed'
+	`, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check proposal participants: %w", err)
+	}
+	active := 0
+	stale := false
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		active++
+		stale = stale || !planned[userID]
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if stale || active != len(planned) {
+		return nil, nil, errProposalStale
+	}
+
+	for _, match := range matches {
+		if err := h.requireNotExcluded(eventID, match.Members()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE velvet_hour_round_proposals SET used_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, proposalID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to mark proposal used: %w", err)
+	}
+	return matches, byes, nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckManualMatches(t *testing.T) {
+	users := testUsers(8)
+	outsider := uuid.New()
+
+	tests := []struct {
+		name      string
+		groupMode string
+		matches   []models.ManualMatch
+		wantErr   bool
+	}{
+		{name: "pairs and a trio", matches: []models.ManualMatch{
+			models.NewManualMatch(users[:2]),
+			models.NewManualMatch(users[2:5]),
+		}},
+		{name: "pod", groupMode: groupModePods, matches: []models.ManualMatch{models.NewManualMatch(users[:6])}},
+		{name: "pod in pairs mode", groupMode: groupModePairs, matches: []models.ManualMatch{models.NewManualMatch(users[:4])}, wantErr: true},
+		{name: "single member", matches: []models.ManualMatch{{Group: users[:1]}}, wantErr: true},
+		{name: "pod too large", groupMode: groupModePods, matches: []models.ManualMatch{{Group: users[:maxPodSize+1]}}, wantErr: true},
+		{name: "not taking part", matches: []models.ManualMatch{models.NewManualMatch([]uuid.UUID{users[0], outsider})}, wantErr: true},
+		{name: "member twice", matches: []models.ManualMatch{
+			models.NewManualMatch(users[:2]),
+			models.NewManualMatch(users[1:3]),
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkManualMatches(tt.matches, users[:7], tt.groupMode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkManualMatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var matchErr *manualMatchError
+			if err != nil && !errors.As(err, &matchErr) {
+				t.Errorf("checkManualMatches() error = %T, want *manualMatchError", err)
+			}
+		})
+	}
+}
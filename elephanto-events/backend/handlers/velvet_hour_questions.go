@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	answerTypeSingleChoice = "single_choice"
+	answerTypeMultiChoice  = "multi_choice"
+	answerTypeScale        = "scale"
+	answerTypeFreeText     = "free_text"
+)
+
+var validAnswerTypes = map[string]bool{
+	answerTypeSingleChoice: true,
+	answerTypeMultiChoice:  true,
+	answerTypeScale:        true,
+	answerTypeFreeText:     true,
+}
+
+// "connect" questions are only asked when someone wants to connect;
+// "feedback" questions are asked after every match
+var validQuestionTypes = map[string]bool{
+	"connect":  true,
+	"feedback": true,
+}
+
+const (
+	defaultScaleMin   = 1
+	defaultScaleMax   = 5
+	maxFreeTextAnswer = 500
+)
+
+// loadQuestions returns an event's live feedback questions in display order
+func (h *VelvetHourHandler) loadQuestions(eventID uuid.UUID) ([]models.VelvetHourQuestion, error) {
+	rows, err := h.db.Query(`
+		SELECT id, event_id, question_type, question_text, answer_type, options,
+			   scale_min, scale_max, is_required, display_order, created_at, updated_at
+		FROM velvet_hour_questions
+		WHERE event_id = $1 AND archived_at IS NULL
+		ORDER BY display_order, created_at
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := []models.VelvetHourQuestion{}
+	for rows.Next() {
+		question, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+
+// scanQuestion reads one velvet_hour_questions row, decoding its JSON options
+func scanQuestion(row interface{ Scan(...interface{}) error }) (models.VelvetHourQuestion, error) {
+	var question models.VelvetHourQuestion
+	var options []byte
+	err := row.Scan(
+		&question.ID, &question.EventID, &question.QuestionType, &question.QuestionText,
+		&question.AnswerType, &options, &question.ScaleMin, &question.ScaleMax,
+		&question.IsRequired, &question.DisplayOrder, &question.CreatedAt, &question.UpdatedAt,
+	)
+	if err != nil {
+		return question, err
+	}
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &question.Options); err != nil {
+			return question, fmt.Errorf("invalid options for question %s: %w", question.ID, err)
+		}
+	}
+	return question, nil
+}
+
+// sameAnswerFormat reports whether an update leaves a question answered the
+// same way, so answers already given to it still fit
+func sameAnswerFormat(question models.VelvetHourQuestion, req models.CreateVelvetHourQuestionRequest) bool {
+	sameBound := func(a, b *int) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	if question.AnswerType != req.AnswerType || len(question.Options) != len(req.Options) ||
+		!sameBound(question.ScaleMin, req.ScaleMin) || !sameBound(question.ScaleMax, req.ScaleMax) {
+		return false
+	}
+	for i, option := range question.Options {
+		if req.Options[i] != option {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeQuestion checks a create/update request and fills in defaults
+func normalizeQuestion(req *models.CreateVelvetHourQuestionRequest) error {
+	req.QuestionText = strings.TrimSpace(req.QuestionText)
+	if req.QuestionText == "" {
+		return errors.New("Question text is required")
+	}
+	if req.QuestionType == "" {
+		req.QuestionType = "feedback"
+	}
+	if !validQuestionTypes[req.QuestionType] {
+		return errors.New("Invalid question type. Must be 'connect' or 'feedback'")
+	}
+	if !validAnswerTypes[req.AnswerType] {
+		return errors.New("Invalid answer type. Must be 'single_choice', 'multi_choice', 'scale' or 'free_text'")
+	}
+
+	switch req.AnswerType {
+	case answerTypeSingleChoice, answerTypeMultiChoice:
+		seen := map[string]bool{}
+		var options []string
+		for _, option := range req.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[option] {
+				continue
+			}
+			seen[option] = true
+			options = append(options, option)
+		}
+		if len(options) < 2 {
+			return errors.New("Choice questions need at least two distinct options")
+		}
+		req.Options = options
+		req.ScaleMin, req.ScaleMax = nil, nil
+	case answerTypeScale:
+		min, max := defaultScaleMin, defaultScaleMax
+		if req.ScaleMin != nil {
+			min = *req.ScaleMin
+		}
+		if req.ScaleMax != nil {
+			max = *req.ScaleMax
+		}
+		if min >= max {
+			return errors.New("Scale minimum must be below the maximum")
+		}
+		req.ScaleMin, req.ScaleMax = &min, &max
+		req.Options = nil
+	default:
+		req.Options = nil
+		req.ScaleMin, req.ScaleMax = nil, nil
+	}
+	return nil
+}
+
+// validateAnswers checks a participant's answers against the questions that
+// apply to their feedback and returns them ready to store, keyed by question
+func validateAnswers(questions []models.VelvetHourQuestion, wantToConnect bool, answers []models.VelvetHourAnswer) (map[uuid.UUID]json.RawMessage, error) {
+	applicable := map[uuid.UUID]models.VelvetHourQuestion{}
+	for _, question := range questions {
+		if question.QuestionType == "connect" && !wantToConnect {
+			continue
+		}
+		applicable[question.ID] = question
+	}
+
+	validated := map[uuid.UUID]json.RawMessage{}
+	for _, answer := range answers {
+		question, ok := applicable[answer.QuestionID]
+		if !ok {
+			return nil, fmt.Errorf("Question %s does not apply to this feedback", answer.QuestionID)
+		}
+		if _, duplicate := validated[answer.QuestionID]; duplicate {
+			return nil, fmt.Errorf("Question %s was answered more than once", answer.QuestionID)
+		}
+
+		value, empty, err := validateAnswer(question, answer.Value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid answer to %q: %v", question.QuestionText, err)
+		}
+		if !empty {
+			validated[answer.QuestionID] = value
+		}
+	}
+
+	for _, question := range applicable {
+		if _, answered := validated[question.ID]; question.IsRequired && !answered {
+			return nil, fmt.Errorf("An answer to %q is required", question.QuestionText)
+		}
+	}
+	return validated, nil
+}
+
+// validateAnswer checks one answer value against its question's answer type.
+// It reports empty for a missing or blank answer, which is left unstored.
+func validateAnswer(question models.VelvetHourQuestion, raw json.RawMessage) (json.RawMessage, bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, true, nil
+	}
+
+	isOption := func(value string) bool {
+		for _, option := range question.Options {
+			if option == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	var value interface{}
+	switch question.AnswerType {
+	case answerTypeSingleChoice:
+		var choice string
+		if err := json.Unmarshal(raw, &choice); err != nil {
+			return nil, false, errors.New("expected a single option")
+		}
+		if choice == "" {
+			return nil, true, nil
+		}
+		if !isOption(choice) {
+			return nil, false, fmt.Errorf("%q is not one of the options", choice)
+		}
+		value = choice
+
+	case answerTypeMultiChoice:
+		var choices []string
+		if err := json.Unmarshal(raw, &choices); err != nil {
+			return nil, false, errors.New("expected a list of options")
+		}
+		if len(choices) == 0 {
+			return nil, true, nil
+		}
+		seen := map[string]bool{}
+		for _, choice := range choices {
+			if !isOption(choice) {
+				return nil, false, fmt.Errorf("%q is not one of the options", choice)
+			}
+			if seen[choice] {
+				return nil, false, fmt.Errorf("%q was chosen more than once", choice)
+			}
+			seen[choice] = true
+		}
+		value = choices
+
+	case answerTypeScale:
+		var rating float64
+		if err := json.Unmarshal(raw, &rating); err != nil || rating != float64(int(rating)) {
+			return nil, false, errors.New("expected a whole number")
+		}
+		min, max := defaultScaleMin, defaultScaleMax
+		if question.ScaleMin != nil {
+			min = *question.ScaleMin
+		}
+		if question.ScaleMax != nil {
+			max = *question.ScaleMax
+		}
+		if int(rating) < min || int(rating) > max {
+			return nil, false, fmt.Errorf("must be between %d and %d", min, max)
+		}
+		value = int(rating)
+
+	case answerTypeFreeText:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, false, errors.New("expected text")
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, true, nil
+		}
+		if len([]rune(text)) > maxFreeTextAnswer {
+			return nil, false, fmt.Errorf("must be at most %d characters", maxFreeTextAnswer)
+		}
+		value = text
+
+	default:
+		return nil, false, fmt.Errorf("unsupported answer type %q", question.AnswerType)
+	}
+
+	normalized, err := json.Marshal(value)
+	return normalized, false, err
+}
+
+// GetQuestions lists an event's feedback questions
+func (h *VelvetHourHandler) GetQuestions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	questions, err := h.loadQuestions(eventID)
+	if err != nil {
+		log.Printf("Failed to get questions: %v", err)
+		http.Error(w, "Failed to get questions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(questions)
+}
+
+// CreateQuestion adds a feedback question to an event
+func (h *VelvetHourHandler) CreateQuestion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateVelvetHourQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := normalizeQuestion(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options, err := marshalOptions(req.Options)
+	if err != nil {
+		http.Error(w, "Invalid options", http.StatusBadRequest)
+		return
+	}
+	isRequired := true
+	if req.IsRequired != nil {
+		isRequired = *req.IsRequired
+	}
+
+	// New questions go last unless the admin places them
+	row := h.db.QueryRow(`
+		INSERT INTO velvet_hour_questions
+		(event_id, question_type, question_text, answer_type, options, scale_min, scale_max, is_required, display_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+			COALESCE($9, (SELECT COALESCE(MAX(display_order), 0) + 1 FROM velvet_hour_questions WHERE event_id = $1)))
+		RETURNING id, event_id, question_type, question_text, answer_type, options,
+				  scale_min, scale_max, is_required, display_order, created_at, updated_at
+	`, eventID, req.QuestionType, req.QuestionText, req.AnswerType, options,
+		req.ScaleMin, req.ScaleMax, isRequired, req.DisplayOrder)
+	question, err := scanQuestion(row)
+	if err != nil {
+		log.Printf("Failed to create question: %v", err)
+		if strings.Contains(err.Error(), "foreign key") {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to create question", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(question)
+}
+
+// UpdateQuestion replaces a feedback question. Once it has been answered its
+// answer type, options and scale can no longer change.
+func (h *VelvetHourHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	questionID, err := uuid.Parse(vars["questionId"])
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateVelvetHourQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := normalizeQuestion(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options, err := marshalOptions(req.Options)
+	if err != nil {
+		http.Error(w, "Invalid options", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, "Failed to update question", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	existing, err := scanQuestion(tx.QueryRow(`
+		SELECT id, event_id, question_type, question_text, answer_type, options,
+			   scale_min, scale_max, is_required, display_order, created_at, updated_at
+		FROM velvet_hour_questions
+		WHERE id = $1 AND event_id = $2 AND archived_at IS NULL
+		FOR UPDATE
+	`, questionID, eventID))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get question: %v", err)
+		http.Error(w, "Failed to update question", http.StatusInternalServerError)
+		return
+	}
+
+	if !sameAnswerFormat(existing, req) {
+		var answered bool
+		err := tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM velvet_hour_feedback_answers WHERE question_id = $1)
+		`, questionID).Scan(&answered)
+		if err != nil {
+			log.Printf("Failed to check question answers: %v", err)
+			http.Error(w, "Failed to update question", http.StatusInternalServerError)
+			return
+		}
+		if answered {
+			http.Error(w, "Question has already been answered; its answer type, options and scale can't change", http.StatusConflict)
+			return
+		}
+	}
+
+	question, err := scanQuestion(tx.QueryRow(`
+		UPDATE velvet_hour_questions
+		SET question_type = $3, question_text = $4, answer_type = $5, options = $6,
+			scale_min = $7, scale_max = $8,
+			is_required = COALESCE($9, is_required),
+			display_order = COALESCE($10, display_order)
+		WHERE id = $1 AND event_id = $2
+		RETURNING id, event_id, question_type, question_text, answer_type, options,
+				  scale_min, scale_max, is_required, display_order, created_at, updated_at
+	`, questionID, eventID, req.QuestionType, req.QuestionText, req.AnswerType, options,
+		req.ScaleMin, req.ScaleMax, req.IsRequired, req.DisplayOrder))
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to update question: %v", err)
+		http.Error(w, "Failed to update question", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(question)
+}
+
+// DeleteQuestion archives a feedback question so it is no longer asked.
+// Answers already given to it are kept in the session results.
+func (h *VelvetHourHandler) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	questionID, err := uuid.Parse(vars["questionId"])
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE velvet_hour_questions SET archived_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND event_id = $2 AND archived_at IS NULL
+	`, questionID, eventID)
+	if err != nil {
+		log.Printf("Failed to delete question: %v", err)
+		http.Error(w, "Failed to delete question", http.StatusInternalServerError)
+		return
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		http.Error(w, "Question not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Question deleted successfully"})
+}
+
+// marshalOptions encodes choice options for the JSONB column, storing NULL
+// for questions without options
+func marshalOptions(options []string) (interface{}, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
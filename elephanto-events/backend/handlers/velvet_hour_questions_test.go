@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateAnswer(t *testing.T) {
+	scaleMin, scaleMax := 0, 10
+	single := models.VelvetHourQuestion{AnswerType: answerTypeSingleChoice, Options: []string{"Yes", "No"}}
+	multi := models.VelvetHourQuestion{AnswerType: answerTypeMultiChoice, Options: []string{"Art", "Food", "Music"}}
+	scale := models.VelvetHourQuestion{AnswerType: answerTypeScale}
+	customScale := models.VelvetHourQuestion{AnswerType: answerTypeScale, ScaleMin: &scaleMin, ScaleMax: &scaleMax}
+	freeText := models.VelvetHourQuestion{AnswerType: answerTypeFreeText}
+
+	tests := []struct {
+		name     string
+		question models.VelvetHourQuestion
+		raw      string
+		want     string
+		empty    bool
+		wantErr  bool
+	}{
+		{name: "missing", question: single, raw: "", empty: true},
+		{name: "null", question: single, raw: "null", empty: true},
+		{name: "single choice", question: single, raw: `"Yes"`, want: `"Yes"`},
+		{name: "single choice blank", question: single, raw: `""`, empty: true},
+		{name: "single choice not an option", question: single, raw: `"Maybe"`, wantErr: true},
+		{name: "single choice wrong type", question: single, raw: `["Yes"]`, wantErr: true},
+		{name: "multi choice", question: multi, raw: `["Music","Art"]`, want: `["Music","Art"]`},
+		{name: "multi choice none", question: multi, raw: `[]`, empty: true},
+		{name: "multi choice repeated", question: multi, raw: `["Art","Art"]`, wantErr: true},
+		{name: "multi choice not an option", question: multi, raw: `["Sport"]`, wantErr: true},
+		{name: "scale", question: scale, raw: `4`, want: `4`},
+		{name: "scale at the bounds", question: scale, raw: `5.0`, want: `5`},
+		{name: "scale above the default range", question: scale, raw: `6`, wantErr: true},
+		{name: "scale not whole", question: scale, raw: `2.5`, wantErr: true},
+		{name: "custom scale", question: customScale, raw: `0`, want: `0`},
+		{name: "custom scale too high", question: customScale, raw: `11`, wantErr: true},
+		{name: "free text trimmed", question: freeText, raw: `"  lovely chat "`, want: `"lovely chat"`},
+		{name: "free text blank", question: freeText, raw: `"   "`, empty: true},
+		{name: "free text at the limit", question: freeText, raw: `"` + strings.Repeat("a", maxFreeTextAnswer) + `"`, want: `"` + strings.Repeat("a", maxFreeTextAnswer) + `"`},
+		{name: "free text too long", question: freeText, raw: `"` + strings.Repeat("a", maxFreeTextAnswer+1) + `"`, wantErr: true},
+		{name: "unknown answer type", question: models.VelvetHourQuestion{AnswerType: "ranking"}, raw: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, empty, err := validateAnswer(tt.question, json.RawMessage(tt.raw))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("validateAnswer() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAnswer() error = %v", err)
+			}
+			if empty != tt.empty {
+				t.Errorf("empty = %v, want %v", empty, tt.empty)
+			}
+			if !tt.empty && string(got) != tt.want {
+				t.Errorf("validateAnswer() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameAnswerFormat(t *testing.T) {
+	one, five, ten := 1, 5, 10
+	choice := models.VelvetHourQuestion{AnswerType: answerTypeSingleChoice, Options: []string{"Yes", "No"}}
+	scale := models.VelvetHourQuestion{AnswerType: answerTypeScale, ScaleMin: &one, ScaleMax: &five}
+
+	tests := []struct {
+		name     string
+		question models.VelvetHourQuestion
+		req      models.CreateVelvetHourQuestionRequest
+		want     bool
+	}{
+		{
+			name:     "only the wording changes",
+			question: choice,
+			req:      models.CreateVelvetHourQuestionRequest{QuestionText: "Again?", AnswerType: answerTypeSingleChoice, Options: []string{"Yes", "No"}},
+			want:     true,
+		},
+		{
+			name:     "answer type changes",
+			question: choice,
+			req:      models.CreateVelvetHourQuestionRequest{AnswerType: answerTypeMultiChoice, Options: []string{"Yes", "No"}},
+		},
+		{
+			name:     "option renamed",
+			question: choice,
+			req:      models.CreateVelvetHourQuestionRequest{AnswerType: answerTypeSingleChoice, Options: []string{"Yes", "Nope"}},
+		},
+		{
+			name:     "option added",
+			question: choice,
+			req:      models.CreateVelvetHourQuestionRequest{AnswerType: answerTypeSingleChoice, Options: []string{"Yes", "No", "Maybe"}},
+		},
+		{
+			name:     "same scale",
+			question: scale,
+			req:      models.CreateVelvetHourQuestionRequest{AnswerType: answerTypeScale, ScaleMin: &one, ScaleMax: &five},
+			want:     true,
+		},
+		{
+			name:     "scale widened",
+			question: scale,
+			req:      models.CreateVelvetHourQuestionRequest{AnswerType: answerTypeScale, ScaleMin: &one, ScaleMax: &ten},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameAnswerFormat(tt.question, tt.req); got != tt.want {
+				t.Errorf("sameAnswerFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
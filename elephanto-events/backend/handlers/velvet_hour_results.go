@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// scanResultFeedback turns the nullable columns of one direction of feedback
+// into feedback, or nil when none was left
+func scanResultFeedback(wantToConnect sql.NullBool, reason sql.NullString, answers []byte, submittedAt sql.NullTime) *models.VelvetHourResultFeedback {
+	if !wantToConnect.Valid {
+		return nil
+	}
+	feedback := &models.VelvetHourResultFeedback{
+		WantToConnect: wantToConnect.Bool,
+		Reason:        reason.String,
+		SubmittedAt:   submittedAt.Time,
+	}
+	if len(answers) > 0 {
+		feedback.Answers = json.RawMessage(answers)
+	}
+	return feedback
+}
+
+// GetSessionResults exports every pairing of a session for post-event
+// follow-up: round, match number and color, when each member confirmed, the
+// feedback each gave the other, and whether the interest was mutual. Pass
+// ?sessionId= for an earlier session; ?format=csv downloads a spreadsheet.
+// Rows are streamed as they are read.
+func (h *VelvetHourHandler) GetSessionResults(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := h.timelineSession(r, eventID)
+	if err == errSessionNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to export results", http.StatusInternalServerError)
+		return
+	}
+
+	// Each pair of members once, with what each said about the other
+	rows, err := h.db.Query(`
+		SELECT m.id, m.round_number, m.match_number, m.match_color, m.status, t.name, m.compatibility_score,
+			   a.user_id, ua.name, ua.email, a.confirmed_at,
+			   b.user_id, ub.name, ub.email, b.confirmed_at,
+			   fa.want_to_connect, fa.feedback_reason, fa.submitted_at,
+			   (SELECT json_object_agg(q.question_text, fq.answer)
+				FROM velvet_hour_feedback_answers fq
+				JOIN velvet_hour_questions q ON fq.question_id = q.id
+				WHERE fq.feedback_id = fa.id),
+			   fb.want_to_connect, fb.feedback_reason, fb.submitted_at,
+			   (SELECT json_object_agg(q.question_text, fq.answer)
+				FROM velvet_hour_feedback_answers fq
+				JOIN velvet_hour_questions q ON fq.question_id = q.id
+				WHERE fq.feedback_id = fb.id),
+			   EXISTS(SELECT 1 FROM velvet_hour_connections c
+					  WHERE c.match_id = m.id
+						AND c.user1_id = LEAST(a.user_id, b.user_id)
+						AND c.user2_id = GREATEST(a.user_id, b.user_id))
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_match_members a ON a.match_id = m.id
+		JOIN velvet_hour_match_members b ON b.match_id = m.id AND b.position > a.position
+		JOIN users ua ON a.user_id = ua.id
+		JOIN users ub ON b.user_id = ub.id
+		LEFT JOIN velvet_hour_tables t ON m.table_id = t.id
+		LEFT JOIN velvet_hour_feedback fa ON fa.match_id = m.id AND fa.from_user_id = a.user_id AND fa.to_user_id = b.user_id
+		LEFT JOIN velvet_hour_feedback fb ON fb.match_id = m.id AND fb.from_user_id = b.user_id AND fb.to_user_id = a.user_id
+		WHERE m.session_id = $1
+		ORDER BY m.round_number, m.match_number, a.position, b.position
+	`, sessionID)
+	if err != nil {
+		log.Printf("Failed to query session results: %v", err)
+		http.Error(w, "Failed to export results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	next := func() (*models.VelvetHourResultPair, error) {
+		pair := models.VelvetHourResultPair{SessionID: sessionID}
+		var tableName sql.NullString
+		var score sql.NullFloat64
+		var aWant, bWant sql.NullBool
+		var aReason, bReason sql.NullString
+		var aAnswers, bAnswers []byte
+		var aSubmitted, bSubmitted sql.NullTime
+		err := rows.Scan(
+			&pair.MatchID, &pair.RoundNumber, &pair.MatchNumber, &pair.MatchColor, &pair.MatchStatus, &tableName, &score,
+			&pair.User1.UserID, &pair.User1.Name, &pair.User1.Email, &pair.User1.ConfirmedAt,
+			&pair.User2.UserID, &pair.User2.Name, &pair.User2.Email, &pair.User2.ConfirmedAt,
+			&aWant, &aReason, &aSubmitted, &aAnswers,
+			&bWant, &bReason, &bSubmitted, &bAnswers,
+			&pair.Mutual,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if tableName.Valid {
+			pair.TableName = &tableName.String
+		}
+		if score.Valid {
+			pair.CompatibilityScore = &score.Float64
+		}
+		pair.User1Feedback = scanResultFeedback(aWant, aReason, aAnswers, aSubmitted)
+		pair.User2Feedback = scanResultFeedback(bWant, bReason, bAnswers, bSubmitted)
+		return &pair, nil
+	}
+
+	if format != "csv" {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		fmt.Fprint(w, "[")
+		for first := true; rows.Next(); first = false {
+			pair, err := next()
+			if err != nil {
+				log.Printf("Failed to scan session result: %v", err)
+				break
+			}
+			if !first {
+				fmt.Fprint(w, ",")
+			}
+			if err := encoder.Encode(pair); err != nil {
+				log.Printf("Failed to write session result: %v", err)
+				return
+			}
+		}
+		fmt.Fprint(w, "]\n")
+		if err := rows.Err(); err != nil {
+			log.Printf("Failed to read session results: %v", err)
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("velvet_hour_results_%s.csv", sessionID)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Session ID", "Round", "Match ID", "Match Number", "Match Color", "Match Status", "Table", "Predicted Score",
+		"User 1 ID", "User 1 Name", "User 1 Email", "User 1 Confirmed At",
+		"User 2 ID", "User 2 Name", "User 2 Email", "User 2 Confirmed At",
+		"User 1 Wants To Connect", "User 1 Reason", "User 1 Answers", "User 1 Feedback At",
+		"User 2 Wants To Connect", "User 2 Reason", "User 2 Answers", "User 2 Feedback At",
+		"Mutual",
+	}
+	if err := writer.Write(headers); err != nil {
+		log.Printf("Failed to write CSV headers: %v", err)
+		return
+	}
+
+	for rows.Next() {
+		pair, err := next()
+		if err != nil {
+			log.Printf("Failed to scan session result: %v", err)
+			return
+		}
+
+		var tableName, score string
+		if pair.TableName != nil {
+			tableName = *pair.TableName
+		}
+		if pair.CompatibilityScore != nil {
+			score = strconv.FormatFloat(*pair.CompatibilityScore, 'f', 2, 64)
+		}
+		record := []string{
+			pair.SessionID.String(),
+			strconv.Itoa(pair.RoundNumber),
+			pair.MatchID.String(),
+			strconv.Itoa(pair.MatchNumber),
+			pair.MatchColor,
+			pair.MatchStatus,
+			tableName,
+			score,
+		}
+		record = append(record, resultMemberRecord(pair.User1)...)
+		record = append(record, resultMemberRecord(pair.User2)...)
+		record = append(record, resultFeedbackRecord(pair.User1Feedback)...)
+		record = append(record, resultFeedbackRecord(pair.User2Feedback)...)
+		record = append(record, strconv.FormatBool(pair.Mutual))
+
+		if err := writer.Write(record); err != nil {
+			log.Printf("Failed to write CSV row: %v", err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to read session results: %v", err)
+	}
+}
+
+// resultMemberRecord lays out one side of a pairing for CSV
+func resultMemberRecord(member models.VelvetHourResultMember) []string {
+	var name, confirmedAt string
+	if member.Name != nil {
+		name = *member.Name
+	}
+	if member.ConfirmedAt != nil {
+		confirmedAt = member.ConfirmedAt.Format(time.RFC3339)
+	}
+	return []string{member.UserID.String(), name, member.Email, confirmedAt}
+}
+
+// resultFeedbackRecord lays out one direction of feedback for CSV, blank when
+// none was left
+func resultFeedbackRecord(feedback *models.VelvetHourResultFeedback) []string {
+	if feedback == nil {
+		return []string{"", "", "", ""}
+	}
+	return []string{
+		strconv.FormatBool(feedback.WantToConnect),
+		feedback.Reason,
+		string(feedback.Answers),
+		feedback.SubmittedAt.Format(time.RFC3339),
+	}
+}
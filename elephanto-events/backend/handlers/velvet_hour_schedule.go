@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// scheduledPair is one planned pairing in a round. User2 is uuid.Nil when
+// User1 sits the round out (a bye).
+type scheduledPair struct {
+	User1 uuid.UUID
+	User2 uuid.UUID
+}
+
+// buildRoundRobinSchedule plans every round up front with the circle method
+// (a 1-factorization of the complete graph). With n participants it yields
+// n-1 rounds (n when n is odd) in which nobody meets the same person twice and,
+// for odd n, everybody gets at most one bye. Rounds beyond that repeat the cycle.
+// Seats are shuffled with rng.
+func buildRoundRobinSchedule(rng *rand.Rand, participants []uuid.UUID, rounds int) [][]scheduledPair {
+	if len(participants) < 2 || rounds <= 0 {
+		return nil
+	}
+
+	players := make([]uuid.UUID, len(participants))
+	copy(players, participants)
+	rng.Shuffle(len(players), func(i, j int) {
+		players[i], players[j] = players[j], players[i]
+	})
+
+	// An odd headcount gets a phantom player; whoever meets it has a bye
+	if len(players)%2 == 1 {
+		players = append(players, uuid.Nil)
+	}
+
+	n := len(players)
+	schedule := make([][]scheduledPair, 0, rounds)
+	for r := 0; r < rounds; r++ {
+		// Keep the first player fixed and rotate everyone else one seat per round
+		shift := r % (n - 1)
+		circle := make([]uuid.UUID, n)
+		circle[0] = players[0]
+		for i := 1; i < n; i++ {
+			circle[i] = players[1+(i-1+shift)%(n-1)]
+		}
+
+		round := make([]scheduledPair, 0, n/2)
+		for i := 0; i < n/2; i++ {
+			user1, user2 := circle[i], circle[n-1-i]
+			if user1 == uuid.Nil {
+				user1, user2 = user2, user1
+			}
+			round = append(round, scheduledPair{User1: user1, User2: user2})
+		}
+		schedule = append(schedule, round)
+	}
+
+	return schedule
+}
+
+// scheduleAttempts is how many seatings planSchedule tries when looking for
+// one that keeps forbidden pairs apart
+const scheduleAttempts = 20
+
+// planSchedule builds a round-robin schedule, trying several seatings and
+// keeping the one that puts the fewest forbidden pairs together. The circle
+// method can't always avoid them all; planRound pairs anyone left over.
+func planSchedule(rng *rand.Rand, participants []uuid.UUID, rounds int, forbidden map[string]bool) [][]scheduledPair {
+	var best [][]scheduledPair
+	bestConflicts := -1
+	for attempt := 0; attempt < scheduleAttempts; attempt++ {
+		schedule := buildRoundRobinSchedule(rng, participants, rounds)
+		conflicts := 0
+		for _, round := range schedule {
+			for _, pair := range round {
+				if pair.User2 != uuid.Nil && forbidden[pairKey(pair.User1, pair.User2)] {
+					conflicts++
+				}
+			}
+		}
+		if bestConflicts < 0 || conflicts < bestConflicts {
+			best, bestConflicts = schedule, conflicts
+		}
+		if conflicts == 0 {
+			break
+		}
+	}
+	return best
+}
+
+// loadPlannedRound returns the planned pairs for a round of the session's schedule
+func loadPlannedRound(q queryer, sessionID uuid.UUID, roundNumber int) ([]scheduledPair, error) {
+	rows, err := q.Query(`
+		SELECT user1_id, user2_id FROM velvet_hour_schedule
+		WHERE session_id = $1 AND round_number = $2
+	`, sessionID, roundNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load planned round: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []scheduledPair
+	for rows.Next() {
+		var pair scheduledPair
+		var user2 uuid.NullUUID
+		if err := rows.Scan(&pair.User1, &user2); err != nil {
+			return nil, fmt.Errorf("failed to scan planned pair: %w", err)
+		}
+		if user2.Valid {
+			pair.User2 = user2.UUID
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, rows.Err()
+}
+
+// ensureSchedule plans all of the session's rounds for the given participants
+// in the caller's transaction, keeping forbidden pairs apart where it can,
+// unless they have been planned already. StartSession plans for the attendees
+// present and every join before the first round plans again for the people
+// who actually joined; a session without a plan gets one when its first round
+// is previewed or started.
+func ensureSchedule(tx *sql.Tx, sessionID uuid.UUID, participants []uuid.UUID, forbidden map[string]bool) error {
+	var totalRounds int
+	err := tx.QueryRow(`
+		SELECT e.the_hour_total_rounds
+		FROM events e
+		JOIN velvet_hour_sessions s ON e.id = s.event_id
+		WHERE s.id = $1
+	`, sessionID).Scan(&totalRounds)
+	if err != nil {
+		return fmt.Errorf("failed to get total rounds: %w", err)
+	}
+
+	// A preview and a round start can both get here first. A preview doesn't
+	// lock the session row, so they take turns on an advisory lock instead and
+	// only the first stores a schedule.
+	_, err = tx.Exec(`
+		SELECT pg_advisory_xact_lock(hashtext('velvet_hour_schedule:' || $1::text))
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to lock schedule: %w", err)
+	}
+	var planned bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM velvet_hour_schedule WHERE session_id = $1)
+	`, sessionID).Scan(&planned)
+	if err != nil {
+		return fmt.Errorf("failed to check schedule: %w", err)
+	}
+	if planned {
+		return nil
+	}
+
+	schedule := planSchedule(rand.New(rand.NewSource(time.Now().UnixNano())), participants, totalRounds, forbidden)
+	for i, round := range schedule {
+		for _, pair := range round {
+			user2 := uuid.NullUUID{UUID: pair.User2, Valid: pair.User2 != uuid.Nil}
+			_, err = tx.Exec(`
+				INSERT INTO velvet_hour_schedule (session_id, round_number, user1_id, user2_id)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (session_id, round_number, user1_id) DO NOTHING
+			`, sessionID, i+1, pair.User1, user2)
+			if err != nil {
+				return fmt.Errorf("failed to store schedule: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// replanSchedule plans the session's rounds again for the participants who
+// have joined so far, as long as the first round hasn't started
+func (h *VelvetHourHandler) replanSchedule(sessionID uuid.UUID) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The session lock keeps this from racing the first round's start
+	session, err := lockSession(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.CurrentRound > 0 {
+		return nil
+	}
+
+	participants, err := h.getActiveParticipants(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get participants: %w", err)
+	}
+	forbidden, err := h.forbiddenPairs(session.EventID, participants)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM velvet_hour_schedule WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to clear schedule: %w", err)
+	}
+	if err := ensureSchedule(tx, sessionID, participants, forbidden); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// presentAttendees narrows the users connected to an event down to those who
+// are attending and onboarded and not kept out by the event's no-show limit,
+// the people a new session is planned for
+func presentAttendees(tx *sql.Tx, eventID uuid.UUID, present []uuid.UUID, noShowLimit *int) ([]uuid.UUID, error) {
+	ids := make([]string, len(present))
+	for i, userID := range present {
+		ids[i] = userID.String()
+	}
+
+	rows, err := tx.Query(`
+		SELECT ea.user_id
+		FROM event_attendance ea
+		JOIN users u ON ea.user_id = u.id
+		WHERE ea.event_id = $1 AND ea.attending = true AND u.isonboarded = true
+		  AND ea.user_id = ANY($2::uuid[])
+		  AND ($3::int IS NULL OR (SELECT COUNT(*) FROM velvet_hour_no_shows ns WHERE ns.user_id = ea.user_id) < $3)
+		ORDER BY ea.user_id
+	`, eventID, pq.Array(ids), noShowLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get present attendees: %w", err)
+	}
+	defer rows.Close()
+
+	var attendees []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, userID)
+	}
+	return attendees, rows.Err()
+}
+
+// getSchedule returns the full planned schedule of a session for the admin view
+func (h *VelvetHourHandler) getSchedule(sessionID uuid.UUID) ([]models.VelvetHourScheduleRound, error) {
+	rows, err := h.db.Query(`
+		SELECT vs.round_number, vs.user1_id, u1.name, vs.user2_id, u2.name
+		FROM velvet_hour_schedule vs
+		JOIN users u1 ON vs.user1_id = u1.id
+		LEFT JOIN users u2 ON vs.user2_id = u2.id
+		WHERE vs.session_id = $1
+		ORDER BY vs.round_number, u1.name
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedule := []models.VelvetHourScheduleRound{}
+	for rows.Next() {
+		var roundNumber int
+		var user1ID uuid.UUID
+		var user1Name string
+		var user2ID uuid.NullUUID
+		var user2Name sql.NullString
+		if err := rows.Scan(&roundNumber, &user1ID, &user1Name, &user2ID, &user2Name); err != nil {
+			return nil, err
+		}
+
+		if len(schedule) == 0 || schedule[len(schedule)-1].RoundNumber != roundNumber {
+			schedule = append(schedule, models.VelvetHourScheduleRound{
+				RoundNumber: roundNumber,
+				Pairs:       []models.VelvetHourSchedulePair{},
+				Byes:        []models.VelvetHourScheduleBye{},
+			})
+		}
+		round := &schedule[len(schedule)-1]
+
+		if !user2ID.Valid {
+			round.Byes = append(round.Byes, models.VelvetHourScheduleBye{
+				UserID:   user1ID,
+				UserName: user1Name,
+			})
+			continue
+		}
+		round.Pairs = append(round.Pairs, models.VelvetHourSchedulePair{
+			User1ID:   user1ID,
+			User1Name: user1Name,
+			User2ID:   user2ID.UUID,
+			User2Name: user2Name.String,
+		})
+	}
+
+	return schedule, rows.Err()
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testUsers returns n distinct user IDs
+func testUsers(n int) []uuid.UUID {
+	users := make([]uuid.UUID, n)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+	return users
+}
+
+func TestBuildRoundRobinScheduleNeverRepeats(t *testing.T) {
+	for n := 2; n <= 15; n++ {
+		// A full cycle: n-1 rounds for an even headcount, n for an odd one
+		rounds := n - 1
+		if n%2 == 1 {
+			rounds = n
+		}
+		users := testUsers(n)
+		schedule := buildRoundRobinSchedule(rand.New(rand.NewSource(int64(n))), users, rounds)
+		if len(schedule) != rounds {
+			t.Fatalf("n=%d: got %d rounds, want %d", n, len(schedule), rounds)
+		}
+
+		met := make(map[string]bool)
+		byes := make(map[uuid.UUID]int)
+		for r, round := range schedule {
+			seen := make(map[uuid.UUID]bool)
+			for _, pair := range round {
+				if pair.User1 == uuid.Nil {
+					t.Fatalf("n=%d round %d: a bye was planned without anyone sitting out", n, r+1)
+				}
+				for _, userID := range []uuid.UUID{pair.User1, pair.User2} {
+					if userID == uuid.Nil {
+						continue
+					}
+					if seen[userID] {
+						t.Fatalf("n=%d round %d: %s planned twice", n, r+1, userID)
+					}
+					seen[userID] = true
+				}
+
+				if pair.User2 == uuid.Nil {
+					byes[pair.User1]++
+					continue
+				}
+				if met[pairKey(pair.User1, pair.User2)] {
+					t.Fatalf("n=%d round %d: %s and %s meet again", n, r+1, pair.User1, pair.User2)
+				}
+				addPair(met, pair.User1, pair.User2)
+			}
+			if len(seen) != n {
+				t.Fatalf("n=%d round %d: %d of %d participants planned", n, r+1, len(seen), n)
+			}
+		}
+
+		// Over a full cycle everybody meets everybody, and with an odd
+		// headcount everybody sits out exactly once
+		if want := n * (n - 1); len(met) != want {
+			t.Errorf("n=%d: %d pairs met, want %d", n, len(met)/2, want/2)
+		}
+		for _, userID := range users {
+			want := 0
+			if n%2 == 1 {
+				want = 1
+			}
+			if byes[userID] != want {
+				t.Errorf("n=%d: %s has %d byes, want %d", n, userID, byes[userID], want)
+			}
+		}
+	}
+}
+
+func TestBuildRoundRobinScheduleSameSeed(t *testing.T) {
+	users := testUsers(9)
+	first := buildRoundRobinSchedule(rand.New(rand.NewSource(7)), users, 5)
+	second := buildRoundRobinSchedule(rand.New(rand.NewSource(7)), users, 5)
+	for r := range first {
+		for i := range first[r] {
+			if first[r][i] != second[r][i] {
+				t.Fatalf("round %d differs between runs with the same seed", r+1)
+			}
+		}
+	}
+}
+
+func TestBuildRoundRobinScheduleTooSmall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if schedule := buildRoundRobinSchedule(rng, testUsers(1), 3); schedule != nil {
+		t.Errorf("one participant: got %d rounds, want none", len(schedule))
+	}
+	if schedule := buildRoundRobinSchedule(rng, testUsers(4), 0); schedule != nil {
+		t.Errorf("no rounds: got %d rounds, want none", len(schedule))
+	}
+}
+
+func TestPlanScheduleAvoidsForbiddenPairs(t *testing.T) {
+	users := testUsers(8)
+	forbidden := make(map[string]bool)
+	addPair(forbidden, users[0], users[1])
+	addPair(forbidden, users[2], users[3])
+
+	schedule := planSchedule(rand.New(rand.NewSource(3)), users, 2, forbidden)
+	if len(schedule) != 2 {
+		t.Fatalf("got %d rounds, want 2", len(schedule))
+	}
+	for r, round := range schedule {
+		for _, pair := range round {
+			if forbidden[pairKey(pair.User1, pair.User2)] {
+				t.Errorf("round %d pairs %s and %s despite a hard constraint", r+1, pair.User1, pair.User2)
+			}
+		}
+	}
+}
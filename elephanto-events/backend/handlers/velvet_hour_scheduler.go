@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VelvetHourScheduler moves active Velvet Hour sessions through their timeline
+// (in_round -> break -> next round -> completed) when round_ends_at passes,
+// closes matches nobody confirmed within the event's confirmation window, and
+// emails participants their digest once a session has completed.
+// It keeps no state of its own: every tick is driven by velvet_hour_sessions,
+// so a restarted process resumes exactly where the previous one stopped.
+type VelvetHourScheduler struct {
+	handler  *VelvetHourHandler
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// dueSession is an active session whose current round or break has run out
+type dueSession struct {
+	ID           uuid.UUID
+	EventID      uuid.UUID
+	Status       string
+	CurrentRound int
+	TotalRounds  int
+}
+
+// NewVelvetHourScheduler creates a scheduler that checks for due sessions every interval
+func NewVelvetHourScheduler(handler *VelvetHourHandler, interval time.Duration) *VelvetHourScheduler {
+	return &VelvetHourScheduler{
+		handler:  handler,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Run starts the scheduler loop and blocks until Stop is called
+func (s *VelvetHourScheduler) Run() {
+	s.recover()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.handler.expireUnconfirmedMatches()
+			s.advanceDueSessions()
+			s.handler.sendDueDigests()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop ends the scheduler loop
+func (s *VelvetHourScheduler) Stop() {
+	close(s.stop)
+}
+
+// recover logs the sessions the scheduler is taking over after a restart and
+// immediately catches up any round or break that expired while we were down
+func (s *VelvetHourScheduler) recover() {
+	var count int
+	err := s.handler.db.QueryRow(`
+		SELECT COUNT(*) FROM velvet_hour_sessions
+		WHERE is_active = true AND auto_advance = true AND status IN ('in_round', 'break')
+	`).Scan(&count)
+	if err != nil {
+		log.Printf("VelvetHour scheduler: failed to load active sessions: %v", err)
+		return
+	}
+
+	log.Printf("VelvetHour scheduler: resuming %d active session(s)", count)
+	s.handler.expireUnconfirmedMatches()
+	s.advanceDueSessions()
+}
+
+// advanceDueSessions performs the next transition for every session whose timer has expired
+func (s *VelvetHourScheduler) advanceDueSessions() {
+	sessions, err := s.findDueSessions()
+	if err != nil {
+		log.Printf("VelvetHour scheduler: failed to find due sessions: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		switch session.Status {
+		case "in_round":
+			log.Printf("VelvetHour scheduler: closing round %d for session %s", session.CurrentRound, session.ID)
+			err = s.handler.closeRound(session.EventID, session.ID, session.TotalRounds, true)
+		case "break":
+			log.Printf("VelvetHour scheduler: starting round %d for session %s", session.CurrentRound+1, session.ID)
+			_, _, err = s.handler.startRound(session.EventID, session.ID, nil, nil, true)
+		}
+
+		// Paused, extended or switched to manual since it was found
+		if errors.Is(err, errSessionNotDue) {
+			log.Printf("VelvetHour scheduler: session %s is no longer due, leaving it", session.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("VelvetHour scheduler: failed to advance session %s: %v", session.ID, err)
+		}
+	}
+}
+
+// findDueSessions returns auto-advancing sessions whose round or break has ended.
+// Paused sessions are never due. The read is unlocked, so closeRound and
+// startRound check again once they hold the session.
+func (s *VelvetHourScheduler) findDueSessions() ([]dueSession, error) {
+	rows, err := s.handler.db.Query(`
+		SELECT s.id, s.event_id, s.status, s.current_round, e.the_hour_total_rounds
+		FROM velvet_hour_sessions s
+		JOIN events e ON s.event_id = e.id
+		WHERE s.is_active = true AND s.auto_advance = true
+		  AND s.status IN ('in_round', 'break') AND s.paused_at IS NULL
+		  AND s.round_ends_at IS NOT NULL AND s.round_ends_at <= $1
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []dueSession
+	for rows.Next() {
+		var session dueSession
+		if err := rows.Scan(&session.ID, &session.EventID, &session.Status, &session.CurrentRound, &session.TotalRounds); err != nil {
+			log.Printf("VelvetHour scheduler: failed to scan session: %v", err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
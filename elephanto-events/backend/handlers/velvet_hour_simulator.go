@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"fmt"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// Matchers the offline simulator can compare
+const (
+	// SimulateGreedy pairs each round with the greedy matcher alone
+	SimulateGreedy = "greedy"
+	// SimulateRoundRobin follows a circle-method schedule planned up front, as live sessions do
+	SimulateRoundRobin = "round-robin"
+	// SimulateWeighted pairs each round for the highest total compatibility
+	SimulateWeighted = "weighted"
+)
+
+// SimulationMatchers lists every matcher the simulator knows, in report order
+var SimulationMatchers = []string{SimulateGreedy, SimulateRoundRobin, SimulateWeighted}
+
+// Survey answers synthetic participants are drawn from
+var (
+	simulatedGenders         = []string{"Male", "Female"}
+	simulatedTorontoMeanings = []string{"new_beginning", "temporary_stop", "place_to_visit", "land_of_opportunity", "home"}
+	simulatedPersonalities   = []string{"Ambitious", "Adventurous", "Balanced", "Intentional", "Social"}
+	simulatedConnectionTypes = []string{"Dating", "Friendship", "Professional"}
+	simulatedCocktails       = []string{"beer", "wine", "cocktail", "non-alcoholic"}
+)
+
+// Age range of synthetic participants
+const (
+	simulatedMinAge = 21
+	simulatedMaxAge = 45
+)
+
+// SimulationOptions describes an offline run of the Velvet Hour matchers
+type SimulationOptions struct {
+	Participants int
+	Rounds       int
+	Seed         int64
+	OddPolicy    string   // rotating_bye or trio
+	Matchers     []string // defaults to every matcher
+}
+
+// SimulationResult sums up how one matcher did over every simulated round
+type SimulationResult struct {
+	Matcher          string
+	Meetings         int     // pairs of people who shared a match, counted once per round
+	DistinctPairs    int     // pairs of people who met at least once
+	PossiblePairs    int     // pairs that could meet at all
+	Coverage         float64 // DistinctPairs / PossiblePairs
+	Byes             int     // rounds sat out, over everyone
+	MaxByes          int     // most rounds any one person sat out
+	RepeatPairs      int     // meetings between people who had already met
+	AvgCompatibility float64 // mean compatibility over all meetings
+}
+
+// simulationRun holds one matcher's state while its rounds are played out
+type simulationRun struct {
+	participants []uuid.UUID
+	profiles     map[uuid.UUID]matchProfile
+	config       models.VelvetHourMatchingConfig
+	forbidden    map[string]bool
+	oddPolicy    string
+	rng          *rand.Rand
+
+	met       map[string]bool
+	byeCounts map[uuid.UUID]int
+	result    SimulationResult
+	scoreSum  float64
+}
+
+// SimulateVelvetHour plays out rounds of Velvet Hour for synthetic participants
+// with random survey answers, once per matcher, and reports how each did. It
+// needs no database or network. The same seed gives the same results, and
+// every matcher starts from it.
+func SimulateVelvetHour(opts SimulationOptions) ([]SimulationResult, error) {
+	if opts.Participants < 2 {
+		return nil, fmt.Errorf("at least 2 participants are needed")
+	}
+	if opts.Rounds < 1 {
+		return nil, fmt.Errorf("at least 1 round is needed")
+	}
+	if opts.OddPolicy == "" {
+		opts.OddPolicy = oddPolicyRotatingBye
+	}
+	if !validOddPolicies[opts.OddPolicy] {
+		return nil, fmt.Errorf("unknown odd policy: %s", opts.OddPolicy)
+	}
+	matchers := opts.Matchers
+	if len(matchers) == 0 {
+		matchers = SimulationMatchers
+	}
+	for _, matcher := range matchers {
+		if matcher != SimulateGreedy && matcher != SimulateRoundRobin && matcher != SimulateWeighted {
+			return nil, fmt.Errorf("unknown matcher: %s", matcher)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	participants, profiles, err := syntheticParticipants(rng, opts.Participants)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultMatchingConfig(uuid.Nil)
+	forbidden := make(map[string]bool)
+	for i := 0; i < len(participants); i++ {
+		for j := i + 1; j < len(participants); j++ {
+			if !pairAllowed(profiles[participants[i]], profiles[participants[j]], config) {
+				addPair(forbidden, participants[i], participants[j])
+			}
+		}
+	}
+
+	results := make([]SimulationResult, 0, len(matchers))
+	for _, matcher := range matchers {
+		run := &simulationRun{
+			participants: participants,
+			profiles:     profiles,
+			config:       config,
+			forbidden:    forbidden,
+			oddPolicy:    opts.OddPolicy,
+			rng:          rand.New(rand.NewSource(opts.Seed)),
+			met:          make(map[string]bool),
+			byeCounts:    make(map[uuid.UUID]int),
+		}
+		run.result.Matcher = matcher
+
+		var schedule [][]scheduledPair
+		if matcher == SimulateRoundRobin {
+			schedule = buildRoundRobinSchedule(run.rng, participants, opts.Rounds)
+		}
+
+		for round := 0; round < opts.Rounds; round++ {
+			var matches []models.ManualMatch
+			var byes []uuid.UUID
+			switch matcher {
+			case SimulateRoundRobin:
+				matches, byes = run.planScheduledRound(schedule[round])
+			default:
+				matches, byes = run.planRound(matcher)
+			}
+			run.record(matches, byes)
+		}
+
+		results = append(results, run.summary())
+	}
+
+	return results, nil
+}
+
+// syntheticParticipants makes n participants with random survey answers
+func syntheticParticipants(rng *rand.Rand, n int) ([]uuid.UUID, map[uuid.UUID]matchProfile, error) {
+	pick := func(values []string) string {
+		return values[rng.Intn(len(values))]
+	}
+
+	participants := make([]uuid.UUID, 0, n)
+	profiles := make(map[uuid.UUID]matchProfile, n)
+	for i := 0; i < n; i++ {
+		userID, err := uuid.NewRandomFromReader(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		participants = append(participants, userID)
+		profiles[userID] = matchProfile{
+			Age:                simulatedMinAge + rng.Intn(simulatedMaxAge-simulatedMinAge+1),
+			Gender:             pick(simulatedGenders),
+			TorontoMeaning:     pick(simulatedTorontoMeanings),
+			Personality:        pick(simulatedPersonalities),
+			ConnectionType:     pick(simulatedConnectionTypes),
+			CocktailPreference: pick(simulatedCocktails),
+		}
+	}
+	return participants, profiles, nil
+}
+
+// excluded returns the pairs a round must avoid: hard constraints plus
+// everyone who has already met
+func (s *simulationRun) excluded() map[string]bool {
+	excluded := make(map[string]bool, len(s.forbidden)+len(s.met))
+	for key := range s.forbidden {
+		excluded[key] = true
+	}
+	for key := range s.met {
+		excluded[key] = true
+	}
+	return excluded
+}
+
+// takeBye sits out whoever has had the fewest byes when the policy rotates
+// byes and the headcount is odd
+func (s *simulationRun) takeBye(unplaced []uuid.UUID) ([]uuid.UUID, []uuid.UUID) {
+	if s.oddPolicy != oddPolicyRotatingBye || len(unplaced)%2 == 0 {
+		return unplaced, nil
+	}
+	bye := pickByeUser(unplaced, s.byeCounts)
+	return removeUser(unplaced, bye), []uuid.UUID{bye}
+}
+
+// planRound pairs a round with the greedy or weighted matcher, the same way a
+// live round handles byes and an odd headcount
+func (s *simulationRun) planRound(matcher string) ([]models.ManualMatch, []uuid.UUID) {
+	excluded := s.excluded()
+	unplaced, byes := s.takeBye(s.participants)
+
+	var pairs [][2]uuid.UUID
+	if matcher == SimulateWeighted {
+		pairs = weightedPairings(unplaced, s.profiles, s.config, excluded)
+	} else {
+		pairs, _ = (&VelvetHourHandler{}).findUniquePairings(s.rng, unplaced, excluded)
+	}
+
+	matches, extraByes := resolveOddHeadcount(pairs, unpaired(unplaced, pairs), s.oddPolicy, excluded, s.forbidden)
+	return matches, append(byes, extraByes...)
+}
+
+// planScheduledRound follows planRound in velvet_hour.go: planned pairs first,
+// then a rotating bye, then the greedy matcher for anyone left over
+func (s *simulationRun) planScheduledRound(planned []scheduledPair) ([]models.ManualMatch, []uuid.UUID) {
+	excluded := s.excluded()
+
+	used := make(map[uuid.UUID]bool)
+	var pairs [][2]uuid.UUID
+	for _, pair := range planned {
+		if pair.User2 == uuid.Nil || excluded[pairKey(pair.User1, pair.User2)] {
+			continue
+		}
+		pairs = append(pairs, [2]uuid.UUID{pair.User1, pair.User2})
+		used[pair.User1] = true
+		used[pair.User2] = true
+	}
+
+	var unplaced []uuid.UUID
+	for _, userID := range s.participants {
+		if !used[userID] {
+			unplaced = append(unplaced, userID)
+		}
+	}
+	unplaced, byes := s.takeBye(unplaced)
+
+	fallback, _ := (&VelvetHourHandler{}).findUniquePairings(s.rng, unplaced, excluded)
+	pairs = append(pairs, fallback...)
+
+	matches, extraByes := resolveOddHeadcount(pairs, unpaired(unplaced, fallback), s.oddPolicy, excluded, s.forbidden)
+	return matches, append(byes, extraByes...)
+}
+
+// unpaired returns the users none of the pairs include
+func unpaired(users []uuid.UUID, pairs [][2]uuid.UUID) []uuid.UUID {
+	paired := make(map[uuid.UUID]bool, 2*len(pairs))
+	for _, pair := range pairs {
+		paired[pair[0]] = true
+		paired[pair[1]] = true
+	}
+	var left []uuid.UUID
+	for _, userID := range users {
+		if !paired[userID] {
+			left = append(left, userID)
+		}
+	}
+	return left
+}
+
+// record counts a round's meetings and byes
+func (s *simulationRun) record(matches []models.ManualMatch, byes []uuid.UUID) {
+	for _, match := range matches {
+		members := match.Members()
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				a, b := members[i], members[j]
+				if s.met[pairKey(a, b)] {
+					s.result.RepeatPairs++
+				}
+				addPair(s.met, a, b)
+				s.result.Meetings++
+				s.scoreSum += compatibilityScore(s.profiles[a], s.profiles[b], s.config)
+			}
+		}
+	}
+
+	for _, userID := range byes {
+		s.byeCounts[userID]++
+		s.result.Byes++
+		if s.byeCounts[userID] > s.result.MaxByes {
+			s.result.MaxByes = s.byeCounts[userID]
+		}
+	}
+}
+
+// summary fills in the totals derived from the recorded rounds
+func (s *simulationRun) summary() SimulationResult {
+	result := s.result
+	n := len(s.participants)
+	result.DistinctPairs = len(s.met) / 2
+	result.PossiblePairs = n * (n - 1) / 2
+	if result.PossiblePairs > 0 {
+		result.Coverage = float64(result.DistinctPairs) / float64(result.PossiblePairs)
+	}
+	if result.Meetings > 0 {
+		result.AvgCompatibility = s.scoreSum / float64(result.Meetings)
+	}
+	return result
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Velvet Hour session statuses
+const (
+	sessionStatusWaiting   = "waiting"
+	sessionStatusInRound   = "in_round"
+	sessionStatusBreak     = "break"
+	sessionStatusCompleted = "completed"
+)
+
+// sessionTransitions lists the statuses a session may move to from each status.
+// A completed session can still be closed by an admin, which only deactivates it.
+var sessionTransitions = map[string][]string{
+	sessionStatusWaiting:   {sessionStatusInRound, sessionStatusCompleted},
+	sessionStatusInRound:   {sessionStatusBreak, sessionStatusCompleted},
+	sessionStatusBreak:     {sessionStatusInRound, sessionStatusCompleted},
+	sessionStatusCompleted: {},
+}
+
+// errNoActiveSession is returned when an event has no active session to act on
+var errNoActiveSession = errors.New("no active session")
+
+// errSessionAlreadyActive is returned when an event already has an active session
+var errSessionAlreadyActive = errors.New("session already active")
+
+// errSessionPaused is returned when a paused session's clock is changed in a
+// way that needs it running
+var errSessionPaused = errors.New("Session is paused")
+
+// errSessionNotPaused is returned when resuming a session that isn't paused
+var errSessionNotPaused = errors.New("Session is not paused")
+
+// errNoSessionTimer is returned when there is no round or break clock to control
+var errNoSessionTimer = errors.New("No round or break is running")
+
+// errSessionNotDue is returned when the scheduler finds a session it picked up
+// was paused, extended or taken off auto-advance before it got the lock
+var errSessionNotDue = errors.New("session is no longer due")
+
+// errDigestsNotReady is returned when sending the digests of a session that
+// hasn't completed
+var errDigestsNotReady = errors.New("Digests can only be sent once the session has completed")
+
+// sessionTransitionError reports a transition the state machine doesn't allow
+type sessionTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *sessionTransitionError) Error() string {
+	return fmt.Sprintf("Cannot move session from %s to %s", e.From, e.To)
+}
+
+// canTransition reports whether a session may move from one status to another
+func canTransition(from, to string) bool {
+	for _, allowed := range sessionTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionState is a session whose row is locked until the transaction it was
+// loaded in ends. All changes to velvet_hour_sessions go through it, so
+// two requests can never act on the same session at the same time.
+type sessionState struct {
+	tx              *sql.Tx
+	ID              uuid.UUID
+	EventID         uuid.UUID
+	Status          string
+	CurrentRound    int
+	RoundEndsAt     *time.Time
+	PausedAt        *time.Time
+	PausedRemaining *int
+	AutoAdvance     bool
+}
+
+// lockSession loads and locks an active session by ID.
+// FOR NO KEY UPDATE still lets other connections insert rows that reference
+// the session (the planned schedule) while the lock is held.
+func lockSession(tx *sql.Tx, sessionID uuid.UUID) (*sessionState, error) {
+	return scanSessionState(tx, tx.QueryRow(`
+		SELECT id, event_id, status, current_round, round_ends_at, paused_at, paused_remaining_seconds,
+			   COALESCE(auto_advance, false)
+		FROM velvet_hour_sessions
+		WHERE id = $1 AND is_active = true
+		FOR NO KEY UPDATE
+	`, sessionID))
+}
+
+// lockActiveSession loads and locks the active session of an event
+func lockActiveSession(tx *sql.Tx, eventID uuid.UUID) (*sessionState, error) {
+	return scanSessionState(tx, tx.QueryRow(`
+		SELECT id, event_id, status, current_round, round_ends_at, paused_at, paused_remaining_seconds,
+			   COALESCE(auto_advance, false)
+		FROM velvet_hour_sessions
+		WHERE event_id = $1 AND is_active = true
+		FOR NO KEY UPDATE
+	`, eventID))
+}
+
+// lockAnySession loads and locks a session by ID whether or not it is still
+// active, for work that carries on after it ends
+func lockAnySession(tx *sql.Tx, sessionID uuid.UUID) (*sessionState, error) {
+	return scanSessionState(tx, tx.QueryRow(`
+		SELECT id, event_id, status, current_round, round_ends_at, paused_at, paused_remaining_seconds,
+			   COALESCE(auto_advance, false)
+		FROM velvet_hour_sessions
+		WHERE id = $1
+		FOR NO KEY UPDATE
+	`, sessionID))
+}
+
+func scanSessionState(tx *sql.Tx, row *sql.Row) (*sessionState, error) {
+	state := &sessionState{tx: tx}
+	err := row.Scan(
+		&state.ID, &state.EventID, &state.Status, &state.CurrentRound,
+		&state.RoundEndsAt, &state.PausedAt, &state.PausedRemaining,
+		&state.AutoAdvance,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errNoActiveSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock session: %w", err)
+	}
+	return state, nil
+}
+
+// createSession starts a new waiting session for an event, inheriting the
+// event's auto-advance default. A unique index allows one active session per event.
+func createSession(tx *sql.Tx, eventID uuid.UUID) (uuid.UUID, error) {
+	var sessionID uuid.UUID
+	var autoAdvance bool
+	err := tx.QueryRow(`
+		INSERT INTO velvet_hour_sessions (event_id, status, auto_advance)
+		SELECT id, $2, COALESCE(the_hour_auto_advance, false) FROM events WHERE id = $1
+		RETURNING id, auto_advance
+	`, eventID, sessionStatusWaiting).Scan(&sessionID, &autoAdvance)
+	if err != nil && strings.Contains(err.Error(), "idx_velvet_hour_sessions_one_active") {
+		return uuid.Nil, errSessionAlreadyActive
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = recordSessionEvent(tx, sessionID, sessionEventCreated, 0, nil, nil, sessionCreatedPayload{
+		EventID:     eventID,
+		Status:      sessionStatusWaiting,
+		AutoAdvance: autoAdvance,
+	})
+	return sessionID, err
+}
+
+// deleteSession removes a session row; its dependants must already be gone
+func deleteSession(tx *sql.Tx, sessionID uuid.UUID) error {
+	_, err := tx.Exec(`DELETE FROM velvet_hour_sessions WHERE id = $1`, sessionID)
+	return err
+}
+
+// require checks that the session may move to the given status
+func (s *sessionState) require(to string) error {
+	if !canTransition(s.Status, to) {
+		return &sessionTransitionError{From: s.Status, To: to}
+	}
+	return nil
+}
+
+// apply moves the session to a new status, setting the given columns as well.
+// Moving on always releases a pause.
+func (s *sessionState) apply(to, assignments string, args ...interface{}) error {
+	if err := s.require(to); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE velvet_hour_sessions
+		SET status = $1, %s, paused_at = NULL, paused_remaining_seconds = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3
+	`, assignments)
+	result, err := s.tx.Exec(query, append([]interface{}{to, s.ID, s.Status}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to move session to %s: %w", to, err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return &sessionTransitionError{From: s.Status, To: to}
+	}
+
+	log.Printf("VelvetHour: session %s moved from %s to %s", s.ID, s.Status, to)
+	s.Status = to
+	s.PausedAt, s.PausedRemaining = nil, nil
+	return nil
+}
+
+// beginRound moves the session into its next round, timed to end at endsAt
+func (s *sessionState) beginRound(endsAt time.Time) error {
+	from, nextRound := s.Status, s.CurrentRound+1
+	err := s.apply(sessionStatusInRound,
+		"current_round = $4, round_started_at = CURRENT_TIMESTAMP, round_ends_at = $5",
+		nextRound, endsAt)
+	if err != nil {
+		return err
+	}
+	s.CurrentRound, s.RoundEndsAt = nextRound, &endsAt
+	return s.recordStatus(from, true)
+}
+
+// startBreak ends the current round and starts the break before the next one
+func (s *sessionState) startBreak(endsAt time.Time) error {
+	from := s.Status
+	if err := s.apply(sessionStatusBreak, "round_ends_at = $4", endsAt); err != nil {
+		return err
+	}
+	s.RoundEndsAt = &endsAt
+	return s.recordStatus(from, true)
+}
+
+// complete marks the session finished after its final round. It stays active
+// so participants can still leave feedback until an admin ends it.
+func (s *sessionState) complete() error {
+	from := s.Status
+	if err := s.apply(sessionStatusCompleted, "ended_at = CURRENT_TIMESTAMP"); err != nil {
+		return err
+	}
+	return s.recordStatus(from, true)
+}
+
+// end completes the session, if it isn't already, and deactivates it
+func (s *sessionState) end() error {
+	if s.Status != sessionStatusCompleted {
+		if err := s.require(sessionStatusCompleted); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET is_active = false, status = $2, ended_at = COALESCE(ended_at, CURRENT_TIMESTAMP),
+			paused_at = NULL, paused_remaining_seconds = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, s.ID, sessionStatusCompleted)
+	if err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+
+	log.Printf("VelvetHour: session %s ended from %s", s.ID, s.Status)
+	from := s.Status
+	s.Status = sessionStatusCompleted
+	s.PausedAt, s.PausedRemaining = nil, nil
+	return s.recordStatus(from, false)
+}
+
+// requireDue checks, under the lock, that the scheduler may still move the
+// session on from the given status: it auto-advances, isn't paused, and its
+// round or break has run out
+func (s *sessionState) requireDue(from string, now time.Time) error {
+	if s.Status != from || !s.AutoAdvance || s.PausedAt != nil {
+		return errSessionNotDue
+	}
+	if s.RoundEndsAt == nil || s.RoundEndsAt.After(now) {
+		return errSessionNotDue
+	}
+	return nil
+}
+
+// requireTimer checks that a round or break clock is running or paused
+func (s *sessionState) requireTimer() error {
+	if s.Status != sessionStatusInRound && s.Status != sessionStatusBreak {
+		return errNoSessionTimer
+	}
+	if s.RoundEndsAt == nil && s.PausedAt == nil {
+		return errNoSessionTimer
+	}
+	return nil
+}
+
+// timeLeft is how many seconds remain on the round or break clock
+func (s *sessionState) timeLeft(now time.Time) int {
+	if s.PausedAt != nil && s.PausedRemaining != nil {
+		return *s.PausedRemaining
+	}
+	if s.RoundEndsAt == nil {
+		return 0
+	}
+	remaining := int(s.RoundEndsAt.Sub(now).Seconds())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// pause freezes the round or break clock with its remaining time
+func (s *sessionState) pause(now time.Time) error {
+	if err := s.requireTimer(); err != nil {
+		return err
+	}
+	if s.PausedAt != nil {
+		return errSessionPaused
+	}
+
+	remaining := s.timeLeft(now)
+	_, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET paused_at = $1, paused_remaining_seconds = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, now, remaining, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to pause session: %w", err)
+	}
+
+	s.PausedAt, s.PausedRemaining = &now, &remaining
+	return s.recordClock()
+}
+
+// resume restarts a paused clock from where it stopped. The time spent paused
+// is added to the confirmation window of the round's open matches.
+func (s *sessionState) resume(now time.Time) error {
+	if err := s.requireTimer(); err != nil {
+		return err
+	}
+	if s.PausedAt == nil {
+		return errSessionNotPaused
+	}
+
+	if s.Status == sessionStatusInRound {
+		_, err := s.tx.Exec(`
+			UPDATE velvet_hour_matches
+			SET confirmation_paused_seconds = confirmation_paused_seconds +
+				GREATEST(0, EXTRACT(EPOCH FROM ($3::timestamp - GREATEST($2::timestamp, created_at))))::int
+			WHERE session_id = $1 AND round_number = $4 AND status = 'active'
+		`, s.ID, *s.PausedAt, now, s.CurrentRound)
+		if err != nil {
+			return fmt.Errorf("failed to extend confirmation windows: %w", err)
+		}
+	}
+
+	endsAt := now.Add(time.Duration(s.timeLeft(now)) * time.Second)
+	_, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET round_ends_at = $1, paused_at = NULL, paused_remaining_seconds = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, endsAt, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to resume session: %w", err)
+	}
+
+	s.RoundEndsAt, s.PausedAt, s.PausedRemaining = &endsAt, nil, nil
+	return s.recordClock()
+}
+
+// adjustTimer adds time to, or with a negative delta takes time from, the
+// round or break clock. A paused clock stays paused with the new remaining time;
+// a running clock can be shortened to end immediately but not into the past.
+func (s *sessionState) adjustTimer(delta time.Duration, now time.Time) error {
+	if err := s.requireTimer(); err != nil {
+		return err
+	}
+
+	if s.PausedAt != nil {
+		remaining := s.timeLeft(now) + int(delta.Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		_, err := s.tx.Exec(`
+			UPDATE velvet_hour_sessions
+			SET paused_remaining_seconds = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+		`, remaining, s.ID)
+		if err != nil {
+			return fmt.Errorf("failed to adjust timer: %w", err)
+		}
+		s.PausedRemaining = &remaining
+		return s.recordClock()
+	}
+
+	endsAt := s.RoundEndsAt.Add(delta)
+	if endsAt.Before(now) {
+		endsAt = now
+	}
+	_, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET round_ends_at = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, endsAt, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to adjust timer: %w", err)
+	}
+	s.RoundEndsAt = &endsAt
+	return s.recordClock()
+}
+
+// setAutoAdvance turns the round scheduler on or off for the session
+func (s *sessionState) setAutoAdvance(enabled bool) error {
+	_, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET auto_advance = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, enabled, s.ID)
+	if err != nil {
+		return err
+	}
+	return s.record(sessionEventAutoAdvance, autoAdvancePayload{Enabled: enabled})
+}
+
+// markDigestsSent records that a completed session's digests are going out,
+// reporting whether this call was the first to
+func (s *sessionState) markDigestsSent() (bool, error) {
+	if s.Status != sessionStatusCompleted {
+		return false, errDigestsNotReady
+	}
+	result, err := s.tx.Exec(`
+		UPDATE velvet_hour_sessions
+		SET digest_sent_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND digest_sent_at IS NULL
+	`, s.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark digests sent: %w", err)
+	}
+	marked, _ := result.RowsAffected()
+	return marked > 0, nil
+}
+
+// writeSessionError responds to a failed session change: 409 for an illegal
+// transition, a duplicate session or a clock in the wrong state, 400 when there
+// is no session, 500 otherwise
+func writeSessionError(w http.ResponseWriter, err error, message string) {
+	var transitionErr *sessionTransitionError
+	var matchErr *manualMatchError
+	switch {
+	case errors.As(err, &transitionErr):
+		http.Error(w, transitionErr.Error(), http.StatusConflict)
+	case errors.As(err, &matchErr):
+		http.Error(w, matchErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, errSessionAlreadyActive):
+		http.Error(w, "Session already active", http.StatusConflict)
+	case errors.Is(err, errSessionPaused), errors.Is(err, errSessionNotPaused), errors.Is(err, errNoSessionTimer),
+		errors.Is(err, errDigestsNotReady):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errProposalStale):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errProposalNotFound), errors.Is(err, errMatchNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errNoRoundInProgress), errors.Is(err, errParticipantUnavailable), errors.Is(err, errPairExcluded),
+		errors.Is(err, errPairNotAllowed), errors.Is(err, errPairRepeat):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errUserNotInMatch), errors.Is(err, errTableUnavailable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errNoActiveSession):
+		http.Error(w, "No active session", http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
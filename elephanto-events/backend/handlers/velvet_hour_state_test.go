@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{sessionStatusWaiting, sessionStatusInRound, true},
+		{sessionStatusWaiting, sessionStatusBreak, false},
+		{sessionStatusWaiting, sessionStatusCompleted, true},
+		{sessionStatusInRound, sessionStatusBreak, true},
+		{sessionStatusInRound, sessionStatusInRound, false},
+		{sessionStatusInRound, sessionStatusWaiting, false},
+		{sessionStatusInRound, sessionStatusCompleted, true},
+		{sessionStatusBreak, sessionStatusInRound, true},
+		{sessionStatusBreak, sessionStatusBreak, false},
+		{sessionStatusBreak, sessionStatusCompleted, true},
+		{sessionStatusCompleted, sessionStatusInRound, false},
+		{sessionStatusCompleted, sessionStatusWaiting, false},
+		{"unknown", sessionStatusInRound, false},
+	}
+
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSessionStateRequireDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Second), now.Add(time.Minute)
+
+	tests := []struct {
+		name  string
+		state sessionState
+		from  string
+		due   bool
+	}{
+		{
+			name:  "round ran out",
+			state: sessionState{Status: sessionStatusInRound, AutoAdvance: true, RoundEndsAt: &past},
+			from:  sessionStatusInRound,
+			due:   true,
+		},
+		{
+			name:  "round ends right now",
+			state: sessionState{Status: sessionStatusBreak, AutoAdvance: true, RoundEndsAt: &now},
+			from:  sessionStatusBreak,
+			due:   true,
+		},
+		{
+			name:  "round was extended",
+			state: sessionState{Status: sessionStatusInRound, AutoAdvance: true, RoundEndsAt: &future},
+			from:  sessionStatusInRound,
+		},
+		{
+			name:  "moved on already",
+			state: sessionState{Status: sessionStatusBreak, AutoAdvance: true, RoundEndsAt: &past},
+			from:  sessionStatusInRound,
+		},
+		{
+			name:  "auto-advance turned off",
+			state: sessionState{Status: sessionStatusInRound, RoundEndsAt: &past},
+			from:  sessionStatusInRound,
+		},
+		{
+			name:  "paused",
+			state: sessionState{Status: sessionStatusInRound, AutoAdvance: true, RoundEndsAt: &past, PausedAt: &past},
+			from:  sessionStatusInRound,
+		},
+		{
+			name:  "no clock",
+			state: sessionState{Status: sessionStatusInRound, AutoAdvance: true},
+			from:  sessionStatusInRound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.state.requireDue(tt.from, now)
+			if tt.due && err != nil {
+				t.Errorf("requireDue() = %v, want nil", err)
+			}
+			if !tt.due && !errors.Is(err, errSessionNotDue) {
+				t.Errorf("requireDue() = %v, want %v", err, errSessionNotDue)
+			}
+		})
+	}
+}
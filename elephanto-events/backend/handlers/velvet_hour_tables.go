@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// errTableUnavailable is returned when a hand-made match names a table that
+// isn't part of the event's layout, is already taken or is too small
+var errTableUnavailable = errors.New("A match was given a table that is not free or too small")
+
+// Table capacities an event may set
+const (
+	minTableCapacity = 2
+	maxTableCapacity = 20
+)
+
+// seatingHistory is where each participant has sat in a session so far
+type seatingHistory struct {
+	visits map[uuid.UUID]map[string]int
+	last   map[uuid.UUID]string
+}
+
+// tablePlace is the part of the room a table counts as for rotation: its zone,
+// or the table itself when it has none
+func tablePlace(table models.VelvetHourTable) string {
+	if table.Zone != "" {
+		return table.Zone
+	}
+	return table.Name
+}
+
+// matchTable describes a table to the members sent to it
+func matchTable(table models.VelvetHourTable) *models.VelvetHourMatchTable {
+	return &models.VelvetHourMatchTable{
+		ID:         table.ID,
+		Name:       table.Name,
+		Zone:       table.Zone,
+		Accessible: table.Accessible,
+	}
+}
+
+// tableIDOf returns the ID of a match's table, or nil when it has none
+func tableIDOf(table *models.VelvetHourMatchTable) *uuid.UUID {
+	if table == nil {
+		return nil
+	}
+	return &table.ID
+}
+
+// loadMatchTable returns the table a match was sent to, or nil when it has none
+func loadMatchTable(q queryer, tableID *uuid.UUID) (*models.VelvetHourMatchTable, error) {
+	if tableID == nil {
+		return nil, nil
+	}
+	var table models.VelvetHourMatchTable
+	err := q.QueryRow(`
+		SELECT id, name, zone, accessible FROM velvet_hour_tables WHERE id = $1
+	`, *tableID).Scan(&table.ID, &table.Name, &table.Zone, &table.Accessible)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table: %w", err)
+	}
+	return &table, nil
+}
+
+// loadSessionTables returns the layout of the session's event in display order
+func loadSessionTables(q queryer, sessionID uuid.UUID) ([]models.VelvetHourTable, error) {
+	rows, err := q.Query(`
+		SELECT t.id, t.event_id, t.name, t.zone, t.capacity, t.accessible, t.sort_order, t.created_at, t.updated_at
+		FROM velvet_hour_tables t
+		JOIN velvet_hour_sessions s ON s.event_id = t.event_id
+		WHERE s.id = $1
+		ORDER BY t.sort_order, t.name
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []models.VelvetHourTable
+	for rows.Next() {
+		var table models.VelvetHourTable
+		err := rows.Scan(&table.ID, &table.EventID, &table.Name, &table.Zone, &table.Capacity,
+			&table.Accessible, &table.SortOrder, &table.CreatedAt, &table.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// loadAccessibilityNeeds returns the session's participants who need an
+// accessible table
+func loadAccessibilityNeeds(q queryer, sessionID uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := q.Query(`
+		SELECT user_id FROM velvet_hour_participants
+		WHERE session_id = $1 AND needs_accessible_seating = true
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seating needs: %w", err)
+	}
+	defer rows.Close()
+
+	needs := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		needs[userID] = true
+	}
+	return needs, rows.Err()
+}
+
+// loadSeatingHistory counts the places each participant sat in during the
+// session's earlier rounds and remembers the latest one
+func loadSeatingHistory(q queryer, sessionID uuid.UUID, roundNumber int) (*seatingHistory, error) {
+	rows, err := q.Query(`
+		SELECT mm.user_id, COALESCE(NULLIF(t.zone, ''), t.name)
+		FROM velvet_hour_matches m
+		JOIN velvet_hour_match_members mm ON mm.match_id = m.id
+		JOIN velvet_hour_tables t ON t.id = m.table_id
+		WHERE m.session_id = $1 AND m.round_number < $2
+		ORDER BY m.round_number, m.created_at
+	`, sessionID, roundNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seating history: %w", err)
+	}
+	defer rows.Close()
+
+	history := &seatingHistory{
+		visits: make(map[uuid.UUID]map[string]int),
+		last:   make(map[uuid.UUID]string),
+	}
+	for rows.Next() {
+		var userID uuid.UUID
+		var place string
+		if err := rows.Scan(&userID, &place); err != nil {
+			return nil, err
+		}
+		if history.visits[userID] == nil {
+			history.visits[userID] = make(map[string]int)
+		}
+		history.visits[userID][place]++
+		history.last[userID] = place
+	}
+	return history, rows.Err()
+}
+
+// needsAccessibleTable reports whether anyone in the match needs an accessible table
+func needsAccessibleTable(members []uuid.UUID, needs map[uuid.UUID]bool) bool {
+	for _, member := range members {
+		if needs[member] {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseTable picks the free table that fits the members and moves them
+// around the room the most: places they sat in last round cost the most,
+// places they've been before cost less. Accessible tables are kept for those
+// who need them where possible, and among equals the snuggest fit wins.
+// It returns -1 when no free table will do.
+func chooseTable(tables []models.VelvetHourTable, used map[uuid.UUID]bool, members []uuid.UUID, accessible bool, history *seatingHistory) int {
+	best, bestCost := -1, 0
+	for i, table := range tables {
+		if used[table.ID] || table.Capacity < len(members) || (accessible && !table.Accessible) {
+			continue
+		}
+
+		place := tablePlace(table)
+		cost := 0
+		for _, member := range members {
+			cost += history.visits[member][place]
+			if history.last[member] == place {
+				cost += 2
+			}
+		}
+		if table.Accessible && !accessible {
+			cost++
+		}
+
+		if best < 0 || cost < bestCost || (cost == bestCost && table.Capacity < tables[best].Capacity) {
+			best, bestCost = i, cost
+		}
+	}
+	return best
+}
+
+// assignTables sends each match of a new round to a table of the event's
+// layout. Tables an admin picked by hand are kept; matches with someone who
+// needs an accessible table are seated first, then bigger groups before
+// smaller ones. Events without a layout are left as they are. Matches that
+// can't be seated are reported as warnings and keep only their number and color.
+func assignTables(tx *sql.Tx, sessionID uuid.UUID, roundNumber int, matches []models.ManualMatch) ([]models.ManualMatch, []string, error) {
+	tables, err := loadSessionTables(tx, sessionID)
+	if err != nil || len(tables) == 0 {
+		return matches, nil, err
+	}
+	needs, err := loadAccessibilityNeeds(tx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	history, err := loadSeatingHistory(tx, sessionID, roundNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.VelvetHourTable)
+	for _, table := range tables {
+		byID[table.ID] = table
+	}
+
+	seated := make([]models.ManualMatch, len(matches))
+	copy(seated, matches)
+	used := make(map[uuid.UUID]bool)
+	var unseated []int
+	for i, match := range seated {
+		if match.TableID == nil {
+			unseated = append(unseated, i)
+			continue
+		}
+		table, ok := byID[*match.TableID]
+		if !ok || used[table.ID] || table.Capacity < len(match.Members()) {
+			return nil, nil, errTableUnavailable
+		}
+		used[table.ID] = true
+	}
+
+	sort.SliceStable(unseated, func(a, b int) bool {
+		membersA, membersB := seated[unseated[a]].Members(), seated[unseated[b]].Members()
+		needsA, needsB := needsAccessibleTable(membersA, needs), needsAccessibleTable(membersB, needs)
+		if needsA != needsB {
+			return needsA
+		}
+		return len(membersA) > len(membersB)
+	})
+
+	var warnings []string
+	for _, i := range unseated {
+		members := seated[i].Members()
+		accessible := needsAccessibleTable(members, needs)
+		best := chooseTable(tables, used, members, accessible, history)
+		if best < 0 {
+			if accessible {
+				warnings = append(warnings, fmt.Sprintf("Match %d needs an accessible table but none is free", seated[i].MatchNumber))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("Match %d has no free table for %d people", seated[i].MatchNumber, len(members)))
+			}
+			continue
+		}
+		used[tables[best].ID] = true
+		tableID := tables[best].ID
+		seated[i].TableID = &tableID
+	}
+
+	return seated, warnings, nil
+}
+
+// pickFreeTable finds a table nobody in the current round is using for a
+// match made mid-round. It returns nil when the event has no layout or every
+// suitable table is taken.
+func pickFreeTable(tx *sql.Tx, sessionID uuid.UUID, roundNumber int, members []uuid.UUID) (*models.VelvetHourMatchTable, error) {
+	tables, err := loadSessionTables(tx, sessionID)
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+	needs, err := loadAccessibilityNeeds(tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := loadSeatingHistory(tx, sessionID, roundNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT table_id FROM velvet_hour_matches
+		WHERE session_id = $1 AND round_number = $2 AND status = 'active' AND table_id IS NOT NULL
+	`, sessionID, roundNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables in use: %w", err)
+	}
+	defer rows.Close()
+	used := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var tableID uuid.UUID
+		if err := rows.Scan(&tableID); err != nil {
+			return nil, err
+		}
+		used[tableID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	best := chooseTable(tables, used, members, needsAccessibleTable(members, needs), history)
+	if best < 0 {
+		return nil, nil
+	}
+	return matchTable(tables[best]), nil
+}
+
+// validateTableRequest checks a venue table before it is stored
+func validateTableRequest(req *models.VelvetHourTableRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Zone = strings.TrimSpace(req.Zone)
+	if req.Name == "" {
+		return "Table name is required"
+	}
+	if len(req.Name) > 100 || len(req.Zone) > 100 {
+		return "Table name and zone must be at most 100 characters"
+	}
+	if req.Capacity < minTableCapacity || req.Capacity > maxTableCapacity {
+		return fmt.Sprintf("Table capacity must be between %d and %d", minTableCapacity, maxTableCapacity)
+	}
+	return ""
+}
+
+// GetTables lists the venue layout for an event
+func (h *VelvetHourHandler) GetTables(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, event_id, name, zone, capacity, accessible, sort_order, created_at, updated_at
+		FROM velvet_hour_tables
+		WHERE event_id = $1
+		ORDER BY sort_order, name
+	`, eventID)
+	if err != nil {
+		log.Printf("Failed to get tables: %v", err)
+		http.Error(w, "Failed to get tables", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tables := []models.VelvetHourTable{}
+	for rows.Next() {
+		var table models.VelvetHourTable
+		err := rows.Scan(&table.ID, &table.EventID, &table.Name, &table.Zone, &table.Capacity,
+			&table.Accessible, &table.SortOrder, &table.CreatedAt, &table.UpdatedAt)
+		if err != nil {
+			log.Printf("Failed to scan table: %v", err)
+			continue
+		}
+		tables = append(tables, table)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tables)
+}
+
+// CreateTable adds a table or spot to an event's venue layout
+func (h *VelvetHourHandler) CreateTable(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.VelvetHourTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if message := validateTableRequest(&req); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	table := models.VelvetHourTable{
+		EventID:    eventID,
+		Name:       req.Name,
+		Zone:       req.Zone,
+		Capacity:   req.Capacity,
+		Accessible: req.Accessible,
+		SortOrder:  req.SortOrder,
+	}
+	err = h.db.QueryRow(`
+		INSERT INTO velvet_hour_tables (event_id, name, zone, capacity, accessible, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, eventID, req.Name, req.Zone, req.Capacity, req.Accessible, req.SortOrder).Scan(&table.ID, &table.CreatedAt, &table.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "velvet_hour_tables_event_id_name_key") {
+			http.Error(w, "A table with that name already exists", http.StatusConflict)
+			return
+		}
+		if strings.Contains(err.Error(), "foreign key") {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to create table: %v", err)
+		http.Error(w, "Failed to create table", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(table)
+}
+
+// UpdateTable changes a venue table. Matches already sent to it keep it.
+func (h *VelvetHourHandler) UpdateTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	tableID, err := uuid.Parse(vars["tableId"])
+	if err != nil {
+		http.Error(w, "Invalid table ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.VelvetHourTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if message := validateTableRequest(&req); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	var table models.VelvetHourTable
+	err = h.db.QueryRow(`
+		UPDATE velvet_hour_tables
+		SET name = $3, zone = $4, capacity = $5, accessible = $6, sort_order = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND event_id = $2
+		RETURNING id, event_id, name, zone, capacity, accessible, sort_order, created_at, updated_at
+	`, tableID, eventID, req.Name, req.Zone, req.Capacity, req.Accessible, req.SortOrder).Scan(
+		&table.ID, &table.EventID, &table.Name, &table.Zone, &table.Capacity,
+		&table.Accessible, &table.SortOrder, &table.CreatedAt, &table.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "velvet_hour_tables_event_id_name_key") {
+			http.Error(w, "A table with that name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to update table: %v", err)
+		http.Error(w, "Failed to update table", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(table)
+}
+
+// DeleteTable removes a venue table. Matches sent to it lose their table but
+// keep their number and color.
+func (h *VelvetHourHandler) DeleteTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	tableID, err := uuid.Parse(vars["tableId"])
+	if err != nil {
+		http.Error(w, "Invalid table ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec(`
+		DELETE FROM velvet_hour_tables WHERE id = $1 AND event_id = $2
+	`, tableID, eventID)
+	if err != nil {
+		log.Printf("Failed to delete table: %v", err)
+		http.Error(w, "Failed to delete table", http.StatusInternalServerError)
+		return
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		http.Error(w, "Table not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Table deleted"})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChooseTable(t *testing.T) {
+	tables := []models.VelvetHourTable{
+		{ID: uuid.New(), Name: "Table 1", Zone: "Bar", Capacity: 4},
+		{ID: uuid.New(), Name: "Table 2", Capacity: 2},
+		{ID: uuid.New(), Name: "Table 3", Zone: "Patio", Capacity: 6, Accessible: true},
+	}
+	users := testUsers(7)
+	pair := users[:2]
+
+	emptyHistory := &seatingHistory{visits: map[uuid.UUID]map[string]int{}, last: map[uuid.UUID]string{}}
+	visitedTable2 := &seatingHistory{
+		visits: map[uuid.UUID]map[string]int{users[0]: {"Table 2": 1}},
+		last:   map[uuid.UUID]string{},
+	}
+	// Sitting somewhere last round counts for more than two earlier visits
+	satAtTable2 := &seatingHistory{
+		visits: map[uuid.UUID]map[string]int{users[0]: {"Bar": 2, "Table 2": 1}},
+		last:   map[uuid.UUID]string{users[0]: "Table 2"},
+	}
+
+	tests := []struct {
+		name       string
+		used       map[uuid.UUID]bool
+		members    []uuid.UUID
+		accessible bool
+		history    *seatingHistory
+		want       int
+	}{
+		{name: "snuggest fit", members: pair, history: emptyHistory, want: 1},
+		{name: "skips used tables", used: map[uuid.UUID]bool{tables[1].ID: true}, members: pair, history: emptyHistory, want: 0},
+		{name: "accessible needed", members: pair, accessible: true, history: emptyHistory, want: 2},
+		{name: "accessible when nothing else is free", used: map[uuid.UUID]bool{tables[0].ID: true, tables[1].ID: true}, members: pair, history: emptyHistory, want: 2},
+		{name: "big group", members: users[:5], history: emptyHistory, want: 2},
+		{name: "nothing fits", members: users, history: emptyHistory, want: -1},
+		{name: "avoids places visited before", members: pair, history: visitedTable2, want: 0},
+		{name: "moves on from last round", members: pair, history: satAtTable2, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseTable(tables, tt.used, tt.members, tt.accessible, tt.history); got != tt.want {
+				t.Errorf("chooseTable() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"database/sql"
+	"elephanto-events/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Kinds of entry in a session's event log
+const (
+	sessionEventCreated       = "session_created"
+	sessionEventStatusChanged = "status_changed"
+	sessionEventClockChanged  = "clock_changed"
+	sessionEventAutoAdvance   = "auto_advance_changed"
+	sessionEventJoined        = "participant_joined"
+	sessionEventPresence      = "presence_changed"
+	sessionEventBye           = "bye_recorded"
+	sessionEventWaiting       = "left_waiting"
+	sessionEventMatchCreated  = "match_created"
+	sessionEventMatchClosed   = "match_closed"
+	sessionEventConfirmed     = "match_confirmed"
+	sessionEventFeedback      = "feedback_submitted"
+)
+
+// errSessionNotFound is returned when a timeline is asked for a session the
+// event doesn't have
+var errSessionNotFound = errors.New("Session not found")
+
+// Payloads stored with log entries. Replay reads them back, so fields are
+// only ever added.
+type sessionCreatedPayload struct {
+	EventID     uuid.UUID `json:"eventId"`
+	Status      string    `json:"status"`
+	AutoAdvance bool      `json:"autoAdvance"`
+}
+
+type sessionStatusPayload struct {
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	RoundEndsAt *time.Time `json:"roundEndsAt"`
+	IsActive    bool       `json:"isActive"`
+}
+
+type sessionClockPayload struct {
+	RoundEndsAt            *time.Time `json:"roundEndsAt"`
+	PausedAt               *time.Time `json:"pausedAt"`
+	PausedRemainingSeconds *int       `json:"pausedRemainingSeconds"`
+}
+
+type autoAdvancePayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+type participantJoinedPayload struct {
+	NeedsAccessibleSeating bool `json:"needsAccessibleSeating"`
+}
+
+type presencePayload struct {
+	Present bool `json:"present"`
+}
+
+type matchCreatedPayload struct {
+	Members     []uuid.UUID `json:"members"`
+	MatchNumber int         `json:"matchNumber"`
+	MatchColor  string      `json:"matchColor"`
+	TableID     *uuid.UUID  `json:"tableId,omitempty"`
+}
+
+type matchClosedPayload struct {
+	Status string `json:"status"`
+}
+
+type feedbackPayload struct {
+	ToUserID        uuid.UUID `json:"toUserId"`
+	WantToConnect   bool      `json:"wantToConnect"`
+	NeverMatchAgain bool      `json:"neverMatchAgain"`
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordSessionEvent appends an entry to a session's event log
+func recordSessionEvent(e execer, sessionID uuid.UUID, eventType string, roundNumber int, userID, matchID *uuid.UUID, payload interface{}) error {
+	if payload == nil {
+		payload = struct{}{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode session event: %w", err)
+	}
+
+	_, err = e.Exec(`
+		INSERT INTO velvet_hour_session_events (session_id, event_type, round_number, user_id, match_id, payload)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb)
+	`, sessionID, eventType, roundNumber, userID, matchID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to record session event: %w", err)
+	}
+	return nil
+}
+
+// recordMatchEvent appends an entry about a match to its session's event log
+func recordMatchEvent(e execer, matchID uuid.UUID, eventType string, userID *uuid.UUID, payload interface{}) error {
+	if payload == nil {
+		payload = struct{}{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode session event: %w", err)
+	}
+
+	_, err = e.Exec(`
+		INSERT INTO velvet_hour_session_events (session_id, event_type, round_number, user_id, match_id, payload)
+		SELECT session_id, $2, round_number, $3, id, $4::jsonb
+		FROM velvet_hour_matches WHERE id = $1
+	`, matchID, eventType, userID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to record session event: %w", err)
+	}
+	return nil
+}
+
+// record appends an entry to the locked session's event log
+func (s *sessionState) record(eventType string, payload interface{}) error {
+	return recordSessionEvent(s.tx, s.ID, eventType, s.CurrentRound, nil, nil, payload)
+}
+
+// recordStatus logs a move from one status to the session's current one
+func (s *sessionState) recordStatus(from string, active bool) error {
+	return s.record(sessionEventStatusChanged, sessionStatusPayload{
+		From:        from,
+		To:          s.Status,
+		RoundEndsAt: s.RoundEndsAt,
+		IsActive:    active,
+	})
+}
+
+// recordClock logs the round or break clock after it was paused, resumed or changed
+func (s *sessionState) recordClock() error {
+	return s.record(sessionEventClockChanged, sessionClockPayload{
+		RoundEndsAt:            s.RoundEndsAt,
+		PausedAt:               s.PausedAt,
+		PausedRemainingSeconds: s.PausedRemaining,
+	})
+}
+
+// recordPresence logs a participant connecting to or leaving the event room
+// while the event has an active session they joined
+func (h *VelvetHourHandler) recordPresence(eventID, userID uuid.UUID, present bool) {
+	var sessionID uuid.UUID
+	var currentRound int
+	err := h.db.QueryRow(`
+		SELECT s.id, s.current_round FROM velvet_hour_sessions s
+		JOIN velvet_hour_participants p ON p.session_id = s.id AND p.user_id = $2
+		WHERE s.event_id = $1 AND s.is_active = true
+	`, eventID, userID).Scan(&sessionID, &currentRound)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err == nil {
+		err = recordSessionEvent(h.db, sessionID, sessionEventPresence, currentRound, &userID, nil, presencePayload{Present: present})
+	}
+	if err != nil {
+		log.Printf("Failed to record presence change: %v", err)
+	}
+}
+
+// timelineSession picks the session a timeline request is about: the one named
+// in ?sessionId=, or else the event's active or most recent session
+func (h *VelvetHourHandler) timelineSession(r *http.Request, eventID uuid.UUID) (uuid.UUID, error) {
+	var sessionID uuid.UUID
+	var err error
+	if raw := r.URL.Query().Get("sessionId"); raw != "" {
+		requested, parseErr := uuid.Parse(raw)
+		if parseErr != nil {
+			return uuid.Nil, errSessionNotFound
+		}
+		err = h.db.QueryRow(`
+			SELECT id FROM velvet_hour_sessions WHERE id = $1 AND event_id = $2
+		`, requested, eventID).Scan(&sessionID)
+	} else {
+		err = h.db.QueryRow(`
+			SELECT id FROM velvet_hour_sessions
+			WHERE event_id = $1
+			ORDER BY is_active DESC, created_at DESC
+			LIMIT 1
+		`, eventID).Scan(&sessionID)
+	}
+	if err == sql.ErrNoRows {
+		return uuid.Nil, errSessionNotFound
+	}
+	return sessionID, err
+}
+
+// loadSessionEvents returns a session's log in order, up to and including
+// the given time when one is given
+func (h *VelvetHourHandler) loadSessionEvents(sessionID uuid.UUID, until *time.Time) ([]models.VelvetHourSessionEvent, error) {
+	rows, err := h.db.Query(`
+		SELECT e.id, e.session_id, e.event_type, e.round_number, e.user_id,
+			   COALESCE(NULLIF(u.name, ''), u.email), e.match_id, e.payload, e.created_at
+		FROM velvet_hour_session_events e
+		LEFT JOIN users u ON e.user_id = u.id
+		WHERE e.session_id = $1 AND ($2::timestamptz IS NULL OR e.created_at <= $2)
+		ORDER BY e.id
+	`, sessionID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.VelvetHourSessionEvent{}
+	for rows.Next() {
+		var event models.VelvetHourSessionEvent
+		var payload []byte
+		err := rows.Scan(&event.ID, &event.SessionID, &event.EventType, &event.RoundNumber, &event.UserID,
+			&event.UserName, &event.MatchID, &payload, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetSessionTimeline returns the ordered event log of a session as JSON, or
+// as CSV with ?format=csv
+func (h *VelvetHourHandler) GetSessionTimeline(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := h.timelineSession(r, eventID)
+	if err == errSessionNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to get timeline", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := h.loadSessionEvents(sessionID, nil)
+	if err != nil {
+		log.Printf("Failed to get timeline: %v", err)
+		http.Error(w, "Failed to get timeline", http.StatusInternalServerError)
+		return
+	}
+
+	if format != "csv" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+		return
+	}
+
+	filename := fmt.Sprintf("velvet_hour_timeline_%s.csv", sessionID)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	headers := []string{"ID", "Time", "Event", "Round", "User ID", "User Name", "Match ID", "Details"}
+	if err := writer.Write(headers); err != nil {
+		log.Printf("Failed to write CSV headers: %v", err)
+		return
+	}
+	for _, event := range events {
+		var userID, userName, matchID string
+		if event.UserID != nil {
+			userID = event.UserID.String()
+		}
+		if event.UserName != nil {
+			userName = *event.UserName
+		}
+		if event.MatchID != nil {
+			matchID = event.MatchID.String()
+		}
+		record := []string{
+			strconv.FormatInt(event.ID, 10),
+			event.CreatedAt.UTC().Format(time.RFC3339Nano),
+			event.EventType,
+			strconv.Itoa(event.RoundNumber),
+			userID,
+			userName,
+			matchID,
+			string(event.Payload),
+		}
+		if err := writer.Write(record); err != nil {
+			log.Printf("Failed to write CSV row: %v", err)
+			return
+		}
+	}
+}
+
+// sessionReplay is a session's state rebuilt from its event log
+type sessionReplay struct {
+	session      *models.VelvetHourSession
+	participants []*models.VelvetHourParticipant
+	matches      []*models.VelvetHourMatch
+}
+
+// replaySessionEvents rebuilds the session, its participants and its matches
+// from log entries in order. The session is nil when the log doesn't reach
+// its creation.
+func replaySessionEvents(events []models.VelvetHourSessionEvent) (*sessionReplay, error) {
+	replay := &sessionReplay{}
+	participants := make(map[uuid.UUID]*models.VelvetHourParticipant)
+	matches := make(map[uuid.UUID]*models.VelvetHourMatch)
+
+	for _, event := range events {
+		at := event.CreatedAt
+		if replay.session == nil && event.EventType != sessionEventCreated {
+			continue
+		}
+
+		switch event.EventType {
+		case sessionEventCreated:
+			var payload sessionCreatedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			replay.session = &models.VelvetHourSession{
+				ID:          event.SessionID,
+				EventID:     payload.EventID,
+				StartedAt:   at,
+				IsActive:    true,
+				Status:      payload.Status,
+				AutoAdvance: payload.AutoAdvance,
+				CreatedAt:   at,
+			}
+
+		case sessionEventStatusChanged:
+			var payload sessionStatusPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			session := replay.session
+			session.Status = payload.To
+			session.CurrentRound = event.RoundNumber
+			session.RoundEndsAt = payload.RoundEndsAt
+			session.PausedAt, session.PausedRemainingSeconds = nil, nil
+			session.IsActive = payload.IsActive
+			if payload.To == sessionStatusInRound && payload.From != sessionStatusInRound {
+				session.RoundStartedAt = &at
+			}
+			if payload.To == sessionStatusCompleted && session.EndedAt == nil {
+				session.EndedAt = &at
+			}
+
+		case sessionEventClockChanged:
+			var payload sessionClockPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			replay.session.RoundEndsAt = payload.RoundEndsAt
+			replay.session.PausedAt = payload.PausedAt
+			replay.session.PausedRemainingSeconds = payload.PausedRemainingSeconds
+
+		case sessionEventAutoAdvance:
+			var payload autoAdvancePayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			replay.session.AutoAdvance = payload.Enabled
+
+		case sessionEventJoined:
+			var payload participantJoinedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			if event.UserID == nil {
+				continue
+			}
+			participant, ok := participants[*event.UserID]
+			if !ok {
+				participant = &models.VelvetHourParticipant{
+					SessionID: event.SessionID,
+					UserID:    *event.UserID,
+					JoinedAt:  at,
+					CreatedAt: at,
+				}
+				participants[*event.UserID] = participant
+				replay.participants = append(replay.participants, participant)
+			}
+			participant.Status = "waiting"
+			participant.NeedsAccessibleSeating = payload.NeedsAccessibleSeating
+			participant.UpdatedAt = at
+
+		case sessionEventMatchCreated:
+			var payload matchCreatedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			if event.MatchID == nil || len(payload.Members) < 2 {
+				continue
+			}
+			manual := models.NewManualMatch(payload.Members)
+			match := &models.VelvetHourMatch{
+				ID:          *event.MatchID,
+				SessionID:   event.SessionID,
+				RoundNumber: event.RoundNumber,
+				User1ID:     manual.User1ID,
+				User2ID:     manual.User2ID,
+				User3ID:     manual.User3ID,
+				MatchNumber: payload.MatchNumber,
+				MatchColor:  payload.MatchColor,
+				TableID:     payload.TableID,
+				Status:      "active",
+				CreatedAt:   at,
+				UpdatedAt:   at,
+			}
+			for i, member := range payload.Members {
+				match.Group = append(match.Group, models.VelvetHourMatchMember{UserID: member, Position: i + 1})
+			}
+			matches[match.ID] = match
+			replay.matches = append(replay.matches, match)
+
+		case sessionEventMatchClosed:
+			var payload matchClosedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("invalid %s entry %d: %w", event.EventType, event.ID, err)
+			}
+			if event.MatchID == nil || matches[*event.MatchID] == nil {
+				continue
+			}
+			matches[*event.MatchID].Status = payload.Status
+			matches[*event.MatchID].UpdatedAt = at
+
+		case sessionEventConfirmed, sessionEventFeedback:
+			if event.MatchID == nil || event.UserID == nil || matches[*event.MatchID] == nil {
+				continue
+			}
+			match := matches[*event.MatchID]
+			for i := range match.Group {
+				member := &match.Group[i]
+				if member.UserID != *event.UserID {
+					continue
+				}
+				if event.EventType == sessionEventConfirmed {
+					member.Confirmed = true
+					member.ConfirmedAt = &at
+					match.ConfirmedUser1 = match.ConfirmedUser1 || member.Position == 1
+					match.ConfirmedUser2 = match.ConfirmedUser2 || member.Position == 2
+					match.ConfirmedUser3 = match.ConfirmedUser3 || member.Position == 3
+					match.ConfirmedAt, match.StartedAt = &at, &at
+				} else {
+					member.FeedbackSubmitted = true
+					match.User1FeedbackSubmitted = match.User1FeedbackSubmitted || member.Position == 1
+					match.User2FeedbackSubmitted = match.User2FeedbackSubmitted || member.Position == 2
+					match.User3FeedbackSubmitted = match.User3FeedbackSubmitted || member.Position == 3
+				}
+			}
+			match.UpdatedAt = at
+		}
+
+		replay.session.UpdatedAt = at
+	}
+
+	return replay, nil
+}
+
+// ReplaySession rebuilds the admin status of a session as it was at ?at=
+// (RFC 3339) from the session's event log. The event's current config and the
+// session's planned schedule are included as they are now.
+func (h *VelvetHourHandler) ReplaySession(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(mux.Vars(r)["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	at, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("at"))
+	if err != nil {
+		http.Error(w, "at must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	sessionID, err := h.timelineSession(r, eventID)
+	if err == errSessionNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get session: %v", err)
+		http.Error(w, "Failed to replay session", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := h.loadSessionEvents(sessionID, &at)
+	var replay *sessionReplay
+	if err == nil {
+		replay, err = replaySessionEvents(events)
+	}
+	if err != nil {
+		log.Printf("Failed to replay session %s: %v", sessionID, err)
+		http.Error(w, "Failed to replay session", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.AdminVelvetHourStatusResponse{
+		Session:        replay.session,
+		Participants:   []models.VelvetHourParticipant{},
+		CurrentMatches: []models.VelvetHourMatch{},
+		Config:         h.loadEventConfig(eventID),
+		Schedule:       []models.VelvetHourScheduleRound{},
+	}
+	if replay.session == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if err := h.fillReplayNames(replay); err != nil {
+		log.Printf("Failed to get replay names: %v", err)
+	}
+	for _, participant := range replay.participants {
+		response.Participants = append(response.Participants, *participant)
+	}
+	for _, match := range replay.matches {
+		if match.RoundNumber != replay.session.CurrentRound {
+			continue
+		}
+		match.Table, err = loadMatchTable(h.db, match.TableID)
+		if err != nil {
+			log.Printf("Failed to get match table: %v", err)
+		}
+		response.CurrentMatches = append(response.CurrentMatches, *match)
+	}
+	sort.SliceStable(response.CurrentMatches, func(i, j int) bool {
+		abandonedI := response.CurrentMatches[i].Status == "abandoned"
+		abandonedJ := response.CurrentMatches[j].Status == "abandoned"
+		if abandonedI != abandonedJ {
+			return !abandonedI
+		}
+		return response.CurrentMatches[i].MatchNumber < response.CurrentMatches[j].MatchNumber
+	})
+
+	response.CompletedRounds = replay.session.CurrentRound
+	response.CanStartRound = replay.session.Status == sessionStatusWaiting && len(response.Participants) >= 2
+	if schedule, err := h.getSchedule(sessionID); err != nil {
+		log.Printf("Failed to get schedule: %v", err)
+	} else {
+		response.Schedule = schedule
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// fillReplayNames adds current names and emails to replayed participants and
+// match members
+func (h *VelvetHourHandler) fillReplayNames(replay *sessionReplay) error {
+	seen := make(map[uuid.UUID]bool)
+	var ids []string
+	addUser := func(userID uuid.UUID) {
+		if !seen[userID] {
+			seen[userID] = true
+			ids = append(ids, userID.String())
+		}
+	}
+	for _, participant := range replay.participants {
+		addUser(participant.UserID)
+	}
+	for _, match := range replay.matches {
+		for _, member := range match.Members() {
+			addUser(member)
+		}
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, COALESCE(name, ''), email FROM users WHERE id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[uuid.UUID]string)
+	emails := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var userID uuid.UUID
+		var name, email string
+		if err := rows.Scan(&userID, &name, &email); err != nil {
+			return err
+		}
+		names[userID], emails[userID] = name, email
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, participant := range replay.participants {
+		participant.UserName = names[participant.UserID]
+		participant.UserEmail = emails[participant.UserID]
+	}
+	for _, match := range replay.matches {
+		match.User1Name = names[match.User1ID]
+		match.User2Name = names[match.User2ID]
+		if match.User3ID != nil {
+			name := names[*match.User3ID]
+			match.User3Name = &name
+		}
+		for i := range match.Group {
+			name := names[match.Group[i].UserID]
+			match.Group[i].Name = &name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionLog builds event log entries one after another, a second apart
+type sessionLog struct {
+	t         *testing.T
+	sessionID uuid.UUID
+	start     time.Time
+	events    []models.VelvetHourSessionEvent
+}
+
+func (l *sessionLog) add(eventType string, round int, userID, matchID *uuid.UUID, payload interface{}) {
+	l.t.Helper()
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	l.events = append(l.events, models.VelvetHourSessionEvent{
+		ID:          int64(len(l.events) + 1),
+		SessionID:   l.sessionID,
+		EventType:   eventType,
+		RoundNumber: round,
+		UserID:      userID,
+		MatchID:     matchID,
+		Payload:     raw,
+		CreatedAt:   l.start.Add(time.Duration(len(l.events)) * time.Second),
+	})
+}
+
+func TestReplaySessionEvents(t *testing.T) {
+	users := testUsers(3)
+	alice, bob, carol := users[0], users[1], users[2]
+	eventID, matchID := uuid.New(), uuid.New()
+	roundEnds := time.Date(2026, 1, 1, 20, 10, 0, 0, time.UTC)
+
+	log := &sessionLog{t: t, sessionID: uuid.New(), start: time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)}
+	log.add(sessionEventJoined, 0, &alice, nil, participantJoinedPayload{}) // before the session existed
+	log.add(sessionEventCreated, 0, nil, nil, sessionCreatedPayload{EventID: eventID, Status: sessionStatusWaiting})
+	log.add(sessionEventJoined, 0, &alice, nil, participantJoinedPayload{})
+	log.add(sessionEventJoined, 0, &bob, nil, participantJoinedPayload{NeedsAccessibleSeating: true})
+	log.add(sessionEventJoined, 0, &carol, nil, participantJoinedPayload{})
+	log.add(sessionEventAutoAdvance, 0, nil, nil, autoAdvancePayload{Enabled: true})
+	log.add(sessionEventMatchCreated, 1, nil, &matchID, matchCreatedPayload{Members: []uuid.UUID{alice, bob}, MatchNumber: 1, MatchColor: "red"})
+	log.add(sessionEventBye, 1, &carol, nil, struct{}{})
+	log.add(sessionEventStatusChanged, 1, nil, nil, sessionStatusPayload{From: sessionStatusWaiting, To: sessionStatusInRound, RoundEndsAt: &roundEnds, IsActive: true})
+	log.add(sessionEventConfirmed, 1, &bob, &matchID, struct{}{})
+	log.add(sessionEventClockChanged, 1, nil, nil, sessionClockPayload{RoundEndsAt: &roundEnds, PausedAt: &roundEnds})
+	log.add(sessionEventFeedback, 1, &alice, &matchID, feedbackPayload{ToUserID: bob, WantToConnect: true})
+
+	t.Run("in round", func(t *testing.T) {
+		replay, err := replaySessionEvents(log.events)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session := replay.session
+		if session == nil {
+			t.Fatal("session not rebuilt")
+		}
+		if session.EventID != eventID || session.Status != sessionStatusInRound || session.CurrentRound != 1 {
+			t.Errorf("session = %s round %d of event %s", session.Status, session.CurrentRound, session.EventID)
+		}
+		if !session.AutoAdvance || session.PausedAt == nil || session.RoundStartedAt == nil {
+			t.Errorf("session clock not replayed: autoAdvance %v, paused %v, started %v", session.AutoAdvance, session.PausedAt, session.RoundStartedAt)
+		}
+
+		if len(replay.participants) != 3 {
+			t.Fatalf("got %d participants, want 3", len(replay.participants))
+		}
+		if replay.participants[0].UserID != alice || !replay.participants[1].NeedsAccessibleSeating {
+			t.Errorf("participants not replayed in join order")
+		}
+
+		if len(replay.matches) != 1 {
+			t.Fatalf("got %d matches, want 1", len(replay.matches))
+		}
+		match := replay.matches[0]
+		if match.User1ID != alice || match.User2ID != bob || match.Status != "active" {
+			t.Errorf("match = %s and %s, %s", match.User1ID, match.User2ID, match.Status)
+		}
+		if match.ConfirmedUser1 || !match.ConfirmedUser2 || !match.Group[1].Confirmed {
+			t.Errorf("confirmation not replayed onto bob")
+		}
+		if !match.User1FeedbackSubmitted || match.User2FeedbackSubmitted {
+			t.Errorf("feedback not replayed onto alice")
+		}
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		events := append([]models.VelvetHourSessionEvent(nil), log.events...)
+		done := &sessionLog{t: t, sessionID: log.sessionID, start: log.start.Add(time.Hour), events: events}
+		done.add(sessionEventMatchClosed, 1, nil, &matchID, matchClosedPayload{Status: "abandoned"})
+		done.add(sessionEventStatusChanged, 1, nil, nil, sessionStatusPayload{From: sessionStatusInRound, To: sessionStatusCompleted})
+
+		replay, err := replaySessionEvents(done.events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replay.session.Status != sessionStatusCompleted || replay.session.IsActive || replay.session.EndedAt == nil {
+			t.Errorf("session not completed: %s, active %v", replay.session.Status, replay.session.IsActive)
+		}
+		if replay.session.PausedAt != nil {
+			t.Errorf("moving on did not release the pause")
+		}
+		if replay.matches[0].Status != "abandoned" {
+			t.Errorf("match status = %s, want abandoned", replay.matches[0].Status)
+		}
+	})
+
+	t.Run("no creation", func(t *testing.T) {
+		replay, err := replaySessionEvents(log.events[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replay.session != nil || len(replay.participants) != 0 {
+			t.Errorf("entries before the session was created were replayed")
+		}
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		broken := append([]models.VelvetHourSessionEvent(nil), log.events[:2]...)
+		broken = append(broken, models.VelvetHourSessionEvent{ID: 99, EventType: sessionEventStatusChanged, Payload: json.RawMessage(`{"to": 5}`)})
+		if _, err := replaySessionEvents(broken); err == nil {
+			t.Errorf("want an error for an unreadable entry")
+		}
+	})
+}
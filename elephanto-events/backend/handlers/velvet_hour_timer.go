@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"elephanto-events/models"
+	"elephanto-events/services"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxTimerAdjustment caps how far one request may move the round or break clock
+const maxTimerAdjustment = time.Hour
+
+// PauseSession freezes the clock of the current round or break, e.g. while the
+// host makes an announcement
+func (h *VelvetHourHandler) PauseSession(w http.ResponseWriter, r *http.Request) {
+	h.changeTimer(w, r, "Failed to pause session", func(session *sessionState, now time.Time) error {
+		return session.pause(now)
+	})
+}
+
+// ResumeSession restarts a paused clock with the time it had left
+func (h *VelvetHourHandler) ResumeSession(w http.ResponseWriter, r *http.Request) {
+	h.changeTimer(w, r, "Failed to resume session", func(session *sessionState, now time.Time) error {
+		return session.resume(now)
+	})
+}
+
+// AdjustTimer extends the current round or break, or shortens it when the
+// number of seconds is negative
+func (h *VelvetHourHandler) AdjustTimer(w http.ResponseWriter, r *http.Request) {
+	var req models.AdjustVelvetHourTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	delta := time.Duration(req.Seconds) * time.Second
+	if delta == 0 || delta > maxTimerAdjustment || delta < -maxTimerAdjustment {
+		http.Error(w, "Seconds must be non-zero and at most one hour either way", http.StatusBadRequest)
+		return
+	}
+
+	h.changeTimer(w, r, "Failed to adjust timer", func(session *sessionState, now time.Time) error {
+		return session.adjustTimer(delta, now)
+	})
+}
+
+// changeTimer applies a clock change to the event's active session and tells
+// every client to resync its timer
+func (h *VelvetHourHandler) changeTimer(w http.ResponseWriter, r *http.Request, message string, change func(*sessionState, time.Time) error) {
+	vars := mux.Vars(r)
+	eventID, err := uuid.Parse(vars["eventId"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to start transaction: %v", err)
+		http.Error(w, message, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	session, err := lockActiveSession(tx, eventID)
+	if err == nil {
+		err = change(session, now)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		writeSessionError(w, err, message)
+		return
+	}
+
+	timer := timerPayload(session, now)
+	log.Printf("VelvetHour: timer for session %s changed, paused=%v timeLeft=%d", session.ID, timer["paused"], timer["timeLeft"])
+	if h.hub != nil {
+		h.hub.BroadcastToEvent(eventID, services.MessageTypeVelvetHourTimerUpdated, timer)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timer)
+}
+
+// timerPayload describes a session's clock for clients to resync from
+func timerPayload(session *sessionState, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sessionId":    session.ID,
+		"status":       session.Status,
+		"currentRound": session.CurrentRound,
+		"paused":       session.PausedAt != nil,
+		"timeLeft":     session.timeLeft(now),
+		"roundEndsAt":  session.RoundEndsAt,
+	}
+}
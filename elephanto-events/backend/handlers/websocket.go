@@ -6,6 +6,7 @@ import (
 	"elephanto-events/utils"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -80,11 +81,21 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 
 	isAdmin := user.Role == "admin"
+
+	// Reconnecting clients pass the last sequence number they saw to be sent what they missed
+	var lastSeq int64
+	if raw := r.URL.Query().Get("lastSeq"); raw != "" {
+		lastSeq, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || lastSeq < 0 {
+			http.Error(w, "Invalid lastSeq", http.StatusBadRequest)
+			return
+		}
+	}
 	
-	log.Printf("WebSocket connection request: EventID=%s, UserID=%s, IsAdmin=%v", eventID, user.ID, isAdmin)
+	log.Printf("WebSocket connection request: EventID=%s, UserID=%s, IsAdmin=%v, LastSeq=%d", eventID, user.ID, isAdmin, lastSeq)
 	
 	// Upgrade connection and handle WebSocket
-	h.hub.HandleWebSocket(w, r, eventID, user.ID, isAdmin)
+	h.hub.HandleWebSocket(w, r, eventID, user.ID, isAdmin, lastSeq)
 }
 
 // GetHub returns the WebSocket hub instance
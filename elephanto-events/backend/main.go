@@ -1,15 +1,20 @@
 package main
 
 import (
+	"database/sql"
 	"elephanto-events/config"
 	"elephanto-events/db"
 	"elephanto-events/handlers"
 	"elephanto-events/middleware"
 	"elephanto-events/services"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -24,6 +29,9 @@ func main() {
 		case "serve":
 			serve()
 			return
+		case "simulate-velvet-hour":
+			runVelvetHourSimulation()
+			return
 		default:
 			log.Fatalf("Unknown command: %s", os.Args[1])
 		}
@@ -70,13 +78,18 @@ func main() {
 	velvetHourHandler := handlers.NewVelvetHourHandler(database.DB)
 	
 	// Initialize WebSocket hub and handler
-	wsHub := services.NewWebSocketHub()
+	wsHub := newWebSocketHub(cfg, database.DB)
 	go wsHub.Run() // Start the WebSocket hub in a goroutine
 	wsHandler := handlers.NewWebSocketHandler(wsHub, cfg.JWTSecret, tokenHandler)
 	
 	// Pass WebSocket hub to handlers that need to broadcast messages
 	velvetHourHandler.SetWebSocketHub(wsHub)
 	eventHandler.SetWebSocketHub(wsHub)
+	velvetHourHandler.SetEmailService(emailService)
+
+	// Advance Velvet Hour rounds and breaks when their timers run out
+	velvetHourScheduler := handlers.NewVelvetHourScheduler(velvetHourHandler, 2*time.Second)
+	go velvetHourScheduler.Run()
 
 	r := mux.NewRouter()
 
@@ -120,6 +133,10 @@ func main() {
 	protected.HandleFunc("/velvet-hour/join", velvetHourHandler.JoinSession).Methods("POST")
 	protected.HandleFunc("/velvet-hour/confirm-match", velvetHourHandler.ConfirmMatch).Methods("POST")
 	protected.HandleFunc("/velvet-hour/feedback", velvetHourHandler.SubmitFeedback).Methods("POST")
+	protected.HandleFunc("/velvet-hour/connections", velvetHourHandler.GetConnections).Methods("GET")
+	protected.HandleFunc("/velvet-hour/blocks", velvetHourHandler.GetBlocks).Methods("GET")
+	protected.HandleFunc("/velvet-hour/blocks", velvetHourHandler.BlockUser).Methods("POST")
+	protected.HandleFunc("/velvet-hour/blocks/{userId}", velvetHourHandler.UnblockUser).Methods("DELETE")
 	
 	// WebSocket endpoint for real-time updates (handles auth internally)
 	api.HandleFunc("/ws/{eventId}", wsHandler.HandleWebSocket).Methods("GET")
@@ -170,9 +187,37 @@ func main() {
 	admin.HandleFunc("/events/{eventId}/velvet-hour/attendance", velvetHourHandler.GetAttendanceStats).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/start", velvetHourHandler.StartSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/start-round", velvetHourHandler.StartRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/preview-round", velvetHourHandler.PreviewRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/swap", velvetHourHandler.SwapMatchPartners).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/pair", velvetHourHandler.PairParticipants).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/{matchId}/split", velvetHourHandler.SplitMatch).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/close-round", velvetHourHandler.CloseRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/auto-advance", velvetHourHandler.SetAutoAdvance).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/pause", velvetHourHandler.PauseSession).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/resume", velvetHourHandler.ResumeSession).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/extend", velvetHourHandler.AdjustTimer).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/end", velvetHourHandler.EndSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/config", velvetHourHandler.UpdateEventConfig).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matching-config", velvetHourHandler.GetMatchingConfig).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matching-config", velvetHourHandler.UpdateMatchingConfig).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions", velvetHourHandler.GetExclusions).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions", velvetHourHandler.CreateExclusion).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions/conflicts", velvetHourHandler.GetExclusionConflicts).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions/{exclusionId}", velvetHourHandler.DeleteExclusion).Methods("DELETE")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables", velvetHourHandler.GetTables).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables", velvetHourHandler.CreateTable).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables/{tableId}", velvetHourHandler.UpdateTable).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables/{tableId}", velvetHourHandler.DeleteTable).Methods("DELETE")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/timeline", velvetHourHandler.GetSessionTimeline).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/replay", velvetHourHandler.ReplaySession).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/send-digests", velvetHourHandler.SendDigests).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/results", velvetHourHandler.GetSessionResults).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/analytics", velvetHourHandler.GetAnalytics).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions", velvetHourHandler.GetQuestions).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions", velvetHourHandler.CreateQuestion).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions/{questionId}", velvetHourHandler.UpdateQuestion).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions/{questionId}", velvetHourHandler.DeleteQuestion).Methods("DELETE")
+	admin.HandleFunc("/velvet-hour/reliability", velvetHourHandler.GetReliability).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/reset", velvetHourHandler.ResetSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/attending-users", velvetHourHandler.GetAttendingUsers).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/present-users", velvetHourHandler.GetPresentUsers).Methods("GET")
@@ -206,6 +251,26 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+cfg.Port, r))
 }
 
+// newWebSocketHub creates the WebSocket hub with the backend WS_HUB_BACKEND
+// names: memory for a single process, or postgres to share rooms between
+// replicas
+func newWebSocketHub(cfg *config.Config, conn *sql.DB) *services.Hub {
+	switch cfg.WebSocketHubBackend {
+	case "memory":
+		return services.NewWebSocketHub()
+	case "postgres":
+		backend, err := services.NewPostgresHubBackend(conn, cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Failed to start WebSocket hub backend: %v", err)
+		}
+		log.Printf("WebSocket hub sharing rooms through Postgres as instance %s", backend.InstanceID())
+		return services.NewWebSocketHubWithBackend(backend)
+	default:
+		log.Fatalf("Unknown WS_HUB_BACKEND %q, expected memory or postgres", cfg.WebSocketHubBackend)
+		return nil
+	}
+}
+
 func runMigrations() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -241,6 +306,46 @@ func runMigrations() {
 	}
 }
 
+// runVelvetHourSimulation compares the Velvet Hour matchers on synthetic
+// participants, without a database, so settings can be chosen before an event
+func runVelvetHourSimulation() {
+	flags := flag.NewFlagSet("simulate-velvet-hour", flag.ExitOnError)
+	participants := flags.Int("participants", 20, "number of synthetic participants")
+	rounds := flags.Int("rounds", 5, "number of rounds to play")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for participants and schedules")
+	oddPolicy := flags.String("odd-policy", "rotating_bye", "odd-headcount policy: rotating_bye or trio")
+	matchers := flags.String("matchers", strings.Join(handlers.SimulationMatchers, ","), "comma-separated matchers to compare")
+	flags.Parse(os.Args[2:])
+
+	var selected []string
+	for _, matcher := range strings.Split(*matchers, ",") {
+		if matcher = strings.TrimSpace(matcher); matcher != "" {
+			selected = append(selected, matcher)
+		}
+	}
+
+	results, err := handlers.SimulateVelvetHour(handlers.SimulationOptions{
+		Participants: *participants,
+		Rounds:       *rounds,
+		Seed:         *seed,
+		OddPolicy:    *oddPolicy,
+		Matchers:     selected,
+	})
+	if err != nil {
+		log.Fatalf("Simulation failed: %v", err)
+	}
+
+	fmt.Printf("Velvet Hour simulation: %d participants, %d rounds, odd policy %s, seed %d\n\n", *participants, *rounds, *oddPolicy, *seed)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MATCHER\tMEETINGS\tDISTINCT PAIRS\tCOVERAGE\tBYES\tMAX BYES\tREPEATS\tAVG COMPATIBILITY")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d/%d\t%.1f%%\t%d\t%d\t%d\t%.3f\n",
+			result.Matcher, result.Meetings, result.DistinctPairs, result.PossiblePairs, result.Coverage*100,
+			result.Byes, result.MaxByes, result.RepeatPairs, result.AvgCompatibility)
+	}
+	w.Flush()
+}
+
 func serve() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -283,13 +388,18 @@ func serve() {
 	velvetHourHandler := handlers.NewVelvetHourHandler(database.DB)
 	
 	// Initialize WebSocket hub and handler
-	wsHub := services.NewWebSocketHub()
+	wsHub := newWebSocketHub(cfg, database.DB)
 	go wsHub.Run() // Start the WebSocket hub in a goroutine
 	wsHandler := handlers.NewWebSocketHandler(wsHub, cfg.JWTSecret, tokenHandler)
 	
 	// Pass WebSocket hub to handlers that need to broadcast messages
 	velvetHourHandler.SetWebSocketHub(wsHub)
 	eventHandler.SetWebSocketHub(wsHub)
+	velvetHourHandler.SetEmailService(emailService)
+
+	// Advance Velvet Hour rounds and breaks when their timers run out
+	velvetHourScheduler := handlers.NewVelvetHourScheduler(velvetHourHandler, 2*time.Second)
+	go velvetHourScheduler.Run()
 
 	r := mux.NewRouter()
 
@@ -333,6 +443,10 @@ func serve() {
 	protected.HandleFunc("/velvet-hour/join", velvetHourHandler.JoinSession).Methods("POST")
 	protected.HandleFunc("/velvet-hour/confirm-match", velvetHourHandler.ConfirmMatch).Methods("POST")
 	protected.HandleFunc("/velvet-hour/feedback", velvetHourHandler.SubmitFeedback).Methods("POST")
+	protected.HandleFunc("/velvet-hour/connections", velvetHourHandler.GetConnections).Methods("GET")
+	protected.HandleFunc("/velvet-hour/blocks", velvetHourHandler.GetBlocks).Methods("GET")
+	protected.HandleFunc("/velvet-hour/blocks", velvetHourHandler.BlockUser).Methods("POST")
+	protected.HandleFunc("/velvet-hour/blocks/{userId}", velvetHourHandler.UnblockUser).Methods("DELETE")
 	
 	// WebSocket endpoint for real-time updates (handles auth internally)
 	api.HandleFunc("/ws/{eventId}", wsHandler.HandleWebSocket).Methods("GET")
@@ -383,9 +497,37 @@ func serve() {
 	admin.HandleFunc("/events/{eventId}/velvet-hour/attendance", velvetHourHandler.GetAttendanceStats).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/start", velvetHourHandler.StartSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/start-round", velvetHourHandler.StartRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/preview-round", velvetHourHandler.PreviewRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/swap", velvetHourHandler.SwapMatchPartners).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/pair", velvetHourHandler.PairParticipants).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matches/{matchId}/split", velvetHourHandler.SplitMatch).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/close-round", velvetHourHandler.CloseRound).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/auto-advance", velvetHourHandler.SetAutoAdvance).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/pause", velvetHourHandler.PauseSession).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/resume", velvetHourHandler.ResumeSession).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/extend", velvetHourHandler.AdjustTimer).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/end", velvetHourHandler.EndSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/config", velvetHourHandler.UpdateEventConfig).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matching-config", velvetHourHandler.GetMatchingConfig).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/matching-config", velvetHourHandler.UpdateMatchingConfig).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions", velvetHourHandler.GetExclusions).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions", velvetHourHandler.CreateExclusion).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions/conflicts", velvetHourHandler.GetExclusionConflicts).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/exclusions/{exclusionId}", velvetHourHandler.DeleteExclusion).Methods("DELETE")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables", velvetHourHandler.GetTables).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables", velvetHourHandler.CreateTable).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables/{tableId}", velvetHourHandler.UpdateTable).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/tables/{tableId}", velvetHourHandler.DeleteTable).Methods("DELETE")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/timeline", velvetHourHandler.GetSessionTimeline).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/replay", velvetHourHandler.ReplaySession).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/send-digests", velvetHourHandler.SendDigests).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/results", velvetHourHandler.GetSessionResults).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/analytics", velvetHourHandler.GetAnalytics).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions", velvetHourHandler.GetQuestions).Methods("GET")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions", velvetHourHandler.CreateQuestion).Methods("POST")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions/{questionId}", velvetHourHandler.UpdateQuestion).Methods("PUT")
+	admin.HandleFunc("/events/{eventId}/velvet-hour/questions/{questionId}", velvetHourHandler.DeleteQuestion).Methods("DELETE")
+	admin.HandleFunc("/velvet-hour/reliability", velvetHourHandler.GetReliability).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/reset", velvetHourHandler.ResetSession).Methods("POST")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/attending-users", velvetHourHandler.GetAttendingUsers).Methods("GET")
 	admin.HandleFunc("/events/{eventId}/velvet-hour/present-users", velvetHourHandler.GetPresentUsers).Methods("GET")
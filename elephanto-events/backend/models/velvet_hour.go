@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,17 +9,20 @@ import (
 
 // VelvetHourSession represents a Velvet Hour session
 type VelvetHourSession struct {
-	ID             uuid.UUID  `json:"id" db:"id"`
-	EventID        uuid.UUID  `json:"eventId" db:"event_id"`
-	StartedAt      time.Time  `json:"startedAt" db:"started_at"`
-	EndedAt        *time.Time `json:"endedAt" db:"ended_at"`
-	IsActive       bool       `json:"isActive" db:"is_active"`
-	CurrentRound   int        `json:"currentRound" db:"current_round"`
-	RoundStartedAt *time.Time `json:"roundStartedAt" db:"round_started_at"`
-	RoundEndsAt    *time.Time `json:"roundEndsAt" db:"round_ends_at"`
-	Status         string     `json:"status" db:"status"` // waiting, in_round, break, completed
-	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	ID                     uuid.UUID  `json:"id" db:"id"`
+	EventID                uuid.UUID  `json:"eventId" db:"event_id"`
+	StartedAt              time.Time  `json:"startedAt" db:"started_at"`
+	EndedAt                *time.Time `json:"endedAt" db:"ended_at"`
+	IsActive               bool       `json:"isActive" db:"is_active"`
+	CurrentRound           int        `json:"currentRound" db:"current_round"`
+	RoundStartedAt         *time.Time `json:"roundStartedAt" db:"round_started_at"`
+	RoundEndsAt            *time.Time `json:"roundEndsAt" db:"round_ends_at"`
+	Status                 string     `json:"status" db:"status"` // waiting, in_round, break, completed
+	AutoAdvance            bool       `json:"autoAdvance" db:"auto_advance"`
+	PausedAt               *time.Time `json:"pausedAt,omitempty" db:"paused_at"`
+	PausedRemainingSeconds *int       `json:"pausedRemainingSeconds,omitempty" db:"paused_remaining_seconds"`
+	CreatedAt              time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt              time.Time  `json:"updatedAt" db:"updated_at"`
 }
 
 // VelvetHourParticipant represents a user participating in Velvet Hour
@@ -28,6 +32,7 @@ type VelvetHourParticipant struct {
 	UserID    uuid.UUID `json:"userId" db:"user_id"`
 	JoinedAt  time.Time `json:"joinedAt" db:"joined_at"`
 	Status    string    `json:"status" db:"status"` // waiting, matched, in_round, completed
+	NeedsAccessibleSeating bool `json:"needsAccessibleSeating" db:"needs_accessible_seating"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 	
@@ -36,29 +41,88 @@ type VelvetHourParticipant struct {
 	UserEmail string `json:"userEmail" db:"user_email"`
 }
 
-// VelvetHourMatch represents a match between two users
+// VelvetHourMatch represents a match between two users, three when the
+// event's odd-headcount policy folds the extra person into a trio, or a pod of
+// up to six. User1ID..User3ID are the first three members; Group lists all of
+// them when loaded.
 type VelvetHourMatch struct {
 	ID            uuid.UUID  `json:"id" db:"id"`
 	SessionID     uuid.UUID  `json:"sessionId" db:"session_id"`
 	RoundNumber   int        `json:"roundNumber" db:"round_number"`
 	User1ID       uuid.UUID  `json:"user1Id" db:"user1_id"`
 	User2ID       uuid.UUID  `json:"user2Id" db:"user2_id"`
+	User3ID       *uuid.UUID `json:"user3Id,omitempty" db:"user3_id"`
 	MatchNumber   int        `json:"matchNumber" db:"match_number"`
 	MatchColor    string     `json:"matchColor" db:"match_color"`
+	TableID       *uuid.UUID `json:"tableId,omitempty" db:"table_id"`
 	StartedAt     *time.Time `json:"startedAt" db:"started_at"`
 	ConfirmedUser1 bool      `json:"confirmedUser1" db:"confirmed_user1"`
 	ConfirmedUser2 bool      `json:"confirmedUser2" db:"confirmed_user2"`
+	ConfirmedUser3 bool      `json:"confirmedUser3" db:"confirmed_user3"`
 	ConfirmedAt   *time.Time `json:"confirmedAt" db:"confirmed_at"`
+	Status        string     `json:"status" db:"status"` // active, abandoned, no_show
 	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
 	
 	// Joined user information
 	User1Name string `json:"user1Name" db:"user1_name"`
-	User2Name string `json:"user2Name" db:"user2_name"`
+	User2Name string  `json:"user2Name" db:"user2_name"`
+	User3Name *string `json:"user3Name,omitempty" db:"user3_name"`
 	
 	// Feedback status (not stored in DB, populated by queries)
 	User1FeedbackSubmitted bool `json:"user1FeedbackSubmitted"`
 	User2FeedbackSubmitted bool `json:"user2FeedbackSubmitted"`
+	User3FeedbackSubmitted bool `json:"user3FeedbackSubmitted"`
+
+	// Every member with their own confirmation (populated by queries)
+	Group []VelvetHourMatchMember `json:"members,omitempty"`
+
+	// Where the match meets, when the event has a venue layout (populated by queries)
+	Table *VelvetHourMatchTable `json:"table,omitempty"`
+}
+
+// VelvetHourMatchTable tells a match's members where to go
+type VelvetHourMatchTable struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Zone       string    `json:"zone"`
+	Accessible bool      `json:"accessible"`
+}
+
+// VelvetHourMatchMember is one person in a match
+type VelvetHourMatchMember struct {
+	UserID            uuid.UUID  `json:"userId"`
+	Name              *string    `json:"name"`
+	Position          int        `json:"position"`
+	Confirmed         bool       `json:"confirmed"`
+	ConfirmedAt       *time.Time `json:"confirmedAt,omitempty"`
+	FeedbackSubmitted bool       `json:"feedbackSubmitted"`
+}
+
+// Members returns the IDs of everyone in the match
+func (m *VelvetHourMatch) Members() []uuid.UUID {
+	if len(m.Group) > 0 {
+		members := make([]uuid.UUID, len(m.Group))
+		for i, member := range m.Group {
+			members[i] = member.UserID
+		}
+		return members
+	}
+	members := []uuid.UUID{m.User1ID, m.User2ID}
+	if m.User3ID != nil {
+		members = append(members, *m.User3ID)
+	}
+	return members
+}
+
+// HasMember reports whether the user is part of the match
+func (m *VelvetHourMatch) HasMember(userID uuid.UUID) bool {
+	for _, member := range m.Members() {
+		if member == userID {
+			return true
+		}
+	}
+	return false
 }
 
 // VelvetHourFeedback represents feedback from one user about another
@@ -77,14 +141,26 @@ type VelvetHourFeedback struct {
 type VelvetHourQuestion struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	EventID      uuid.UUID `json:"eventId" db:"event_id"`
-	QuestionType string    `json:"questionType" db:"question_type"`
+	QuestionType string    `json:"questionType" db:"question_type"` // connect, feedback
 	QuestionText string    `json:"questionText" db:"question_text"`
-	Options      string    `json:"options" db:"options"` // JSON string for options
+	AnswerType   string    `json:"answerType" db:"answer_type"` // single_choice, multi_choice, scale, free_text
+	Options      []string  `json:"options" db:"options"`        // choices for single_choice and multi_choice
+	ScaleMin     *int      `json:"scaleMin,omitempty" db:"scale_min"`
+	ScaleMax     *int      `json:"scaleMax,omitempty" db:"scale_max"`
+	IsRequired   bool      `json:"isRequired" db:"is_required"`
 	DisplayOrder int       `json:"displayOrder" db:"display_order"`
 	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// VelvetHourAnswer is one participant's answer to a feedback question. Value
+// holds a string (single_choice, free_text), a list of strings (multi_choice)
+// or a number (scale).
+type VelvetHourAnswer struct {
+	QuestionID uuid.UUID       `json:"questionId"`
+	Value      json.RawMessage `json:"value"`
+}
+
 // Request/Response models for Velvet Hour
 
 type CreateVelvetHourSessionRequest struct {
@@ -98,7 +174,8 @@ type VelvetHourSessionResponse struct {
 }
 
 type JoinVelvetHourRequest struct {
-	// No additional fields needed - user comes from auth context
+	// The user comes from auth context; the body is optional
+	NeedsAccessibleSeating bool `json:"needsAccessibleSeating"`
 }
 
 type VelvetHourStatusResponse struct {
@@ -107,7 +184,10 @@ type VelvetHourStatusResponse struct {
 	Participant  *VelvetHourParticipant `json:"participant,omitempty"`
 	CurrentMatch *VelvetHourMatch       `json:"currentMatch,omitempty"`
 	TimeLeft     *int                   `json:"timeLeft,omitempty"` // seconds remaining
+	HasBye       bool                   `json:"hasBye,omitempty"`   // sitting out the current round
+	Paused       bool                   `json:"paused,omitempty"`   // the round or break clock is on hold
 	Config       *VelvetHourConfig      `json:"config,omitempty"`
+	Questions    []VelvetHourQuestion   `json:"questions,omitempty"` // asked with each piece of feedback
 }
 
 type ConfirmMatchRequest struct {
@@ -115,9 +195,14 @@ type ConfirmMatchRequest struct {
 }
 
 type SubmitFeedbackRequest struct {
-	MatchID        uuid.UUID `json:"matchId" validate:"required"`
+	MatchID        uuid.UUID  `json:"matchId" validate:"required"`
+	ToUserID       *uuid.UUID `json:"toUserId,omitempty"` // required for three-person matches
 	WantToConnect  bool      `json:"wantToConnect"`
-	FeedbackReason string    `json:"feedbackReason" validate:"required"`
+	FeedbackReason string    `json:"feedbackReason"` // used when the event has no questions configured
+	Answers        []VelvetHourAnswer `json:"answers,omitempty"`
+	ShareInstagram bool      `json:"shareInstagram"` // revealed only if the interest is mutual
+	ShareEmail     bool      `json:"shareEmail"`
+	NeverMatchAgain bool     `json:"neverMatchAgain"` // block the recipient from future matches
 }
 
 type AdminVelvetHourStatusResponse struct {
@@ -127,29 +212,402 @@ type AdminVelvetHourStatusResponse struct {
 	CompletedRounds int                     `json:"completedRounds"`
 	CanStartRound   bool                    `json:"canStartRound"`
 	Config          VelvetHourConfig        `json:"config"`
+	Schedule        []VelvetHourScheduleRound `json:"schedule"`
+}
+
+// VelvetHourScheduleRound is one round of a session's precomputed round-robin schedule
+type VelvetHourScheduleRound struct {
+	RoundNumber int                      `json:"roundNumber"`
+	Pairs       []VelvetHourSchedulePair `json:"pairs"`
+	Byes        []VelvetHourScheduleBye  `json:"byes"`
+}
+
+type VelvetHourSchedulePair struct {
+	User1ID   uuid.UUID `json:"user1Id"`
+	User1Name string    `json:"user1Name"`
+	User2ID   uuid.UUID `json:"user2Id"`
+	User2Name string    `json:"user2Name"`
+}
+
+// VelvetHourScheduleBye marks a participant who sits out a planned round
+type VelvetHourScheduleBye struct {
+	UserID   uuid.UUID `json:"userId"`
+	UserName string    `json:"userName"`
 }
 
 type VelvetHourConfig struct {
 	RoundDuration   int `json:"roundDuration"`
 	BreakDuration   int `json:"breakDuration"`
 	TotalRounds     int `json:"totalRounds"`
-	MinParticipants int `json:"minParticipants"`
+	MinParticipants int    `json:"minParticipants"`
+	AutoAdvance     bool   `json:"autoAdvance"`
+	OddPolicy       string `json:"oddPolicy"` // rotating_bye, trio
+	ConfirmationWindow int  `json:"confirmationWindow"` // seconds to confirm a match, 0 for no limit
+	NoShowLimit     *int   `json:"noShowLimit"`        // no-shows after which a user may not join, nil for no limit
+	CrossEventMemory   bool `json:"crossEventMemory"`   // avoid pairs who met at a recent event
+	MemoryLookbackDays int  `json:"memoryLookbackDays"` // how far back cross-event memory looks
+	GroupMode          string `json:"groupMode"`        // pairs, pods
+	PodSize            int    `json:"podSize"`          // target pod size, 3 to 6
 }
 
 type StartRoundRequest struct {
-	Matches []ManualMatch `json:"matches,omitempty"` // Optional manual matches
+	Matches    []ManualMatch `json:"matches,omitempty"`    // Optional manual matches
+	ProposalID *uuid.UUID    `json:"proposalId,omitempty"` // Optional previewed proposal to start as-is
+}
+
+// SwapMatchPartnersRequest moves user A from match A into match B and user B
+// the other way
+type SwapMatchPartnersRequest struct {
+	MatchAID uuid.UUID `json:"matchAId"`
+	UserAID  uuid.UUID `json:"userAId"`
+	MatchBID uuid.UUID `json:"matchBId"`
+	UserBID  uuid.UUID `json:"userBId"`
+}
+
+// PairParticipantsRequest pairs two participants who have no match this round
+type PairParticipantsRequest struct {
+	User1ID uuid.UUID `json:"user1Id"`
+	User2ID uuid.UUID `json:"user2Id"`
+}
+
+// PreviewRoundRequest asks for a proposal for the next round, built from the
+// given matches or, without them, from the session's plan
+type PreviewRoundRequest struct {
+	Matches []ManualMatch `json:"matches,omitempty"`
 }
 
 type ManualMatch struct {
-	User1ID     uuid.UUID `json:"user1Id"`
-	User2ID     uuid.UUID `json:"user2Id"`
-	MatchNumber int       `json:"matchNumber"`
-	MatchColor  string    `json:"matchColor"`
+	User1ID     uuid.UUID   `json:"user1Id"`
+	User2ID     uuid.UUID   `json:"user2Id"`
+	User3ID     *uuid.UUID  `json:"user3Id,omitempty"`
+	Group       []uuid.UUID `json:"members,omitempty"` // every member of a pod; User1ID..User3ID are its first three
+	MatchNumber int         `json:"matchNumber"`
+	MatchColor  string      `json:"matchColor"`
+	TableID     *uuid.UUID  `json:"tableId,omitempty"` // left empty to have a table picked
+}
+
+// NewManualMatch builds a match of two or more members
+func NewManualMatch(members []uuid.UUID) ManualMatch {
+	match := ManualMatch{User1ID: members[0], User2ID: members[1]}
+	if len(members) > 2 {
+		third := members[2]
+		match.User3ID = &third
+	}
+	if len(members) > 3 {
+		match.Group = append([]uuid.UUID(nil), members...)
+	}
+	return match
+}
+
+// Members returns the IDs of everyone in the match
+func (m ManualMatch) Members() []uuid.UUID {
+	if len(m.Group) > 0 {
+		return m.Group
+	}
+	members := []uuid.UUID{m.User1ID, m.User2ID}
+	if m.User3ID != nil {
+		members = append(members, *m.User3ID)
+	}
+	return members
 }
 
 type UpdateVelvetHourConfigRequest struct {
 	RoundDuration     *int `json:"roundDuration"`
 	BreakDuration     *int `json:"breakDuration"`
-	TotalRounds       *int `json:"totalRounds"`
+	TotalRounds       *int  `json:"totalRounds"`
+	AutoAdvance       *bool   `json:"autoAdvance"`
+	OddPolicy         *string `json:"oddPolicy"`
+	ConfirmationWindow *int   `json:"confirmationWindow"`
+	NoShowLimit       *int    `json:"noShowLimit"` // 0 removes the limit
+	CrossEventMemory  *bool   `json:"crossEventMemory"`
+	MemoryLookbackDays *int   `json:"memoryLookbackDays"`
+	GroupMode         *string `json:"groupMode"`
+	PodSize           *int    `json:"podSize"`
 	// MinParticipants is auto-calculated based on TotalRounds
+}
+
+type SetAutoAdvanceRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// AdjustVelvetHourTimerRequest moves the end of the current round or break;
+// negative seconds shorten it
+type AdjustVelvetHourTimerRequest struct {
+	Seconds int `json:"seconds"`
+}
+
+// VelvetHourMatchingWeights sets how much each survey field contributes to a pairing's score
+type VelvetHourMatchingWeights struct {
+	ConnectionType float64 `json:"connectionType"`
+	Personality    float64 `json:"personality"`
+	TorontoMeaning float64 `json:"torontoMeaning"`
+	Age            float64 `json:"age"`
+	Gender         float64 `json:"gender"`
+	Cocktail       float64 `json:"cocktail"`
+}
+
+// VelvetHourMatchingConfig holds an event's matching weights and hard constraints
+type VelvetHourMatchingConfig struct {
+	EventID                   uuid.UUID                 `json:"eventId"`
+	Weights                   VelvetHourMatchingWeights `json:"weights"`
+	MaxAgeGap                 *int                      `json:"maxAgeGap"` // nil means no limit
+	RequireSameConnectionType bool                      `json:"requireSameConnectionType"`
+	DatingGenderRule          string                    `json:"datingGenderRule"` // any, different
+	DatingGenderHard          bool                      `json:"datingGenderHard"` // rule excludes pairs instead of lowering their score
+	UpdatedAt                 *time.Time                `json:"updatedAt,omitempty"`
+}
+
+type UpdateVelvetHourMatchingConfigRequest struct {
+	Weights                   *VelvetHourMatchingWeights `json:"weights"`
+	MaxAgeGap                 *int                       `json:"maxAgeGap"` // 0 removes the limit
+	RequireSameConnectionType *bool                      `json:"requireSameConnectionType"`
+	DatingGenderRule          *string                    `json:"datingGenderRule"`
+	DatingGenderHard          *bool                      `json:"datingGenderHard"`
+}
+
+// VelvetHourConnection is a mutual want-to-connect between the current user and
+// someone they met, with the contact details that person agreed to share
+type VelvetHourConnection struct {
+	ID              uuid.UUID `json:"id"`
+	EventID         uuid.UUID `json:"eventId"`
+	EventName       string    `json:"eventName"`
+	UserID          uuid.UUID `json:"userId"`
+	Name            *string   `json:"name"`
+	InstagramHandle *string   `json:"instagramHandle,omitempty"`
+	Email           *string   `json:"email,omitempty"`
+	ConnectedAt     time.Time `json:"connectedAt"`
+}
+
+// VelvetHourReliability summarises how often a user turned up for their matches across events
+type VelvetHourReliability struct {
+	UserID      uuid.UUID `json:"userId"`
+	Name        *string   `json:"name"`
+	Email       string    `json:"email"`
+	EventCount  int       `json:"eventCount"`
+	MatchCount  int       `json:"matchCount"`
+	NoShowCount int       `json:"noShowCount"`
+	NoShowRate  float64   `json:"noShowRate"`
+}
+
+type CreateVelvetHourQuestionRequest struct {
+	QuestionType string   `json:"questionType"`
+	QuestionText string   `json:"questionText"`
+	AnswerType   string   `json:"answerType"`
+	Options      []string `json:"options"`
+	ScaleMin     *int     `json:"scaleMin"`
+	ScaleMax     *int     `json:"scaleMax"`
+	IsRequired   *bool    `json:"isRequired"`
+	DisplayOrder *int     `json:"displayOrder"`
+}
+
+// VelvetHourRoundProposal is a previewed round that can be started exactly as reviewed
+type VelvetHourRoundProposal struct {
+	ID                 uuid.UUID                 `json:"id"`
+	SessionID          uuid.UUID                 `json:"sessionId"`
+	RoundNumber        int                       `json:"roundNumber"`
+	Matches            []VelvetHourProposedMatch `json:"matches"`
+	Unmatched          []VelvetHourProposalUser  `json:"unmatched"`          // sitting the round out
+	CompatibilityScore float64                   `json:"compatibilityScore"` // average across matches
+	RepeatPairs        int                       `json:"repeatPairs"`
+	Warnings           []string                  `json:"warnings"`
+	CreatedAt          time.Time                 `json:"createdAt"`
+}
+
+// VelvetHourProposedMatch is one match of a proposal with what the admin should know about it
+type VelvetHourProposedMatch struct {
+	ManualMatch
+	User1Name          string   `json:"user1Name"`
+	User2Name          string   `json:"user2Name"`
+	User3Name          *string  `json:"user3Name,omitempty"`
+	MemberNames        []string `json:"memberNames,omitempty"` // every member of a pod, in order
+	CompatibilityScore float64  `json:"compatibilityScore"`
+	RepeatPair         bool     `json:"repeatPair"` // members already met earlier in the session
+	Warnings           []string `json:"warnings,omitempty"`
+}
+
+type VelvetHourProposalUser struct {
+	UserID uuid.UUID `json:"userId"`
+	Name   string    `json:"name"`
+}
+
+// VelvetHourPairExclusion keeps two users from being matched, at one event or
+// at every event
+type VelvetHourPairExclusion struct {
+	ID        uuid.UUID  `json:"id"`
+	User1ID   uuid.UUID  `json:"user1Id"`
+	User1Name string     `json:"user1Name"`
+	User2ID   uuid.UUID  `json:"user2Id"`
+	User2Name string     `json:"user2Name"`
+	EventID   *uuid.UUID `json:"eventId,omitempty"` // nil applies to every event
+	Source    string     `json:"source"`            // user, feedback, admin
+	CreatedBy *uuid.UUID `json:"createdBy,omitempty"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// CreatePairExclusionRequest is an admin keeping two users apart
+type CreatePairExclusionRequest struct {
+	User1ID   uuid.UUID `json:"user1Id"`
+	User2ID   uuid.UUID `json:"user2Id"`
+	Permanent bool      `json:"permanent"` // every event rather than just this one
+	Reason    string    `json:"reason"`
+}
+
+// VelvetHourBlock is someone the current user never wants to be matched with
+type VelvetHourBlock struct {
+	UserID    uuid.UUID `json:"userId"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BlockUserRequest struct {
+	UserID uuid.UUID `json:"userId"`
+	Reason string    `json:"reason"`
+}
+
+// VelvetHourExclusionConflict is a current match or planned pairing that puts
+// two excluded users together
+type VelvetHourExclusionConflict struct {
+	Kind        string     `json:"kind"` // match, schedule
+	RoundNumber int        `json:"roundNumber"`
+	MatchID     *uuid.UUID `json:"matchId,omitempty"`
+	MatchNumber *int       `json:"matchNumber,omitempty"`
+	User1ID     uuid.UUID  `json:"user1Id"`
+	User1Name   string     `json:"user1Name"`
+	User2ID     uuid.UUID  `json:"user2Id"`
+	User2Name   string     `json:"user2Name"`
+}
+
+// VelvetHourTable is a table or spot at the venue that a match can be sent to
+type VelvetHourTable struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	EventID    uuid.UUID `json:"eventId" db:"event_id"`
+	Name       string    `json:"name" db:"name"`
+	Zone       string    `json:"zone" db:"zone"`
+	Capacity   int       `json:"capacity" db:"capacity"`
+	Accessible bool      `json:"accessible" db:"accessible"`
+	SortOrder  int       `json:"sortOrder" db:"sort_order"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// VelvetHourTableRequest creates or updates a venue table
+type VelvetHourTableRequest struct {
+	Name       string `json:"name"`
+	Zone       string `json:"zone"`
+	Capacity   int    `json:"capacity"`
+	Accessible bool   `json:"accessible"`
+	SortOrder  int    `json:"sortOrder"`
+}
+
+// VelvetHourSessionEvent is one entry in a session's append-only event log
+type VelvetHourSessionEvent struct {
+	ID          int64           `json:"id" db:"id"`
+	SessionID   uuid.UUID       `json:"sessionId" db:"session_id"`
+	EventType   string          `json:"eventType" db:"event_type"`
+	RoundNumber int             `json:"roundNumber" db:"round_number"`
+	UserID      *uuid.UUID      `json:"userId,omitempty" db:"user_id"`
+	UserName    *string         `json:"userName,omitempty"`
+	MatchID     *uuid.UUID      `json:"matchId,omitempty" db:"match_id"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// VelvetHourOutcomeStats counts how a set of matches turned out. Rates are
+// fractions between 0 and 1. Abandoned matches, replaced when members were
+// re-paired, only count in AbandonedMatches; no-show matches count as matches
+// whose members never confirmed, and nobody is expected to give feedback on them.
+type VelvetHourOutcomeStats struct {
+	Matches            int     `json:"matches"`
+	NoShowMatches      int     `json:"noShowMatches"`
+	AbandonedMatches   int     `json:"abandonedMatches"`
+	Members            int     `json:"members"`
+	Confirmed          int     `json:"confirmed"`
+	ConfirmationRate   float64 `json:"confirmationRate"`
+	FeedbackExpected   int     `json:"feedbackExpected"` // every member about every other member
+	FeedbackSubmitted  int     `json:"feedbackSubmitted"`
+	FeedbackCompletion float64 `json:"feedbackCompletion"`
+	WantToConnect      int     `json:"wantToConnect"`
+	WantToConnectRate  float64 `json:"wantToConnectRate"` // share of submitted feedback
+	MutualMatches      int     `json:"mutualMatches"`
+}
+
+// VelvetHourRoundAnalytics is the outcome of one round
+type VelvetHourRoundAnalytics struct {
+	RoundNumber int `json:"roundNumber"`
+	VelvetHourOutcomeStats
+}
+
+// VelvetHourScoreBandAnalytics is the outcome of matches whose predicted
+// compatibility fell in a band. Matches made before scores were stored have
+// no band and are reported as unscored.
+type VelvetHourScoreBandAnalytics struct {
+	Label    string   `json:"label"`
+	MinScore *float64 `json:"minScore"`
+	MaxScore *float64 `json:"maxScore"`
+	VelvetHourOutcomeStats
+}
+
+// VelvetHourReasonCount is how often a feedback reason was given
+type VelvetHourReasonCount struct {
+	Reason string  `json:"reason"`
+	Count  int     `json:"count"`
+	Share  float64 `json:"share"`
+}
+
+// VelvetHourAnalytics sums up match outcomes for one session, or for every
+// session of an event when SessionID is empty
+type VelvetHourAnalytics struct {
+	EventID     uuid.UUID                      `json:"eventId"`
+	SessionID   *uuid.UUID                     `json:"sessionId,omitempty"`
+	Sessions    int                            `json:"sessions"`
+	Overall     VelvetHourOutcomeStats         `json:"overall"`
+	Rounds      []VelvetHourRoundAnalytics     `json:"rounds"`
+	ScoreBands  []VelvetHourScoreBandAnalytics `json:"scoreBands"`
+	Reasons     []VelvetHourReasonCount        `json:"reasons"`
+	GeneratedAt time.Time                      `json:"generatedAt"`
+}
+
+// VelvetHourResultMember is one side of an exported pairing
+type VelvetHourResultMember struct {
+	UserID      uuid.UUID  `json:"userId"`
+	Name        *string    `json:"name"`
+	Email       string     `json:"email"`
+	ConfirmedAt *time.Time `json:"confirmedAt"`
+}
+
+// VelvetHourResultFeedback is what one member of a pairing said about the other
+type VelvetHourResultFeedback struct {
+	WantToConnect bool            `json:"wantToConnect"`
+	Reason        string          `json:"reason"`
+	Answers       json.RawMessage `json:"answers,omitempty"` // question text to answer
+	SubmittedAt   time.Time       `json:"submittedAt"`
+}
+
+// VelvetHourResultPair is one pairing of a session's results export. A trio
+// or pod appears once for every pair of its members.
+type VelvetHourResultPair struct {
+	SessionID          uuid.UUID                 `json:"sessionId"`
+	RoundNumber        int                       `json:"roundNumber"`
+	MatchID            uuid.UUID                 `json:"matchId"`
+	MatchNumber        int                       `json:"matchNumber"`
+	MatchColor         string                    `json:"matchColor"`
+	MatchStatus        string                    `json:"matchStatus"`
+	TableName          *string                   `json:"tableName,omitempty"`
+	CompatibilityScore *float64                  `json:"compatibilityScore,omitempty"`
+	User1              VelvetHourResultMember    `json:"user1"`
+	User2              VelvetHourResultMember    `json:"user2"`
+	User1Feedback      *VelvetHourResultFeedback `json:"user1Feedback"` // from user 1 about user 2
+	User2Feedback      *VelvetHourResultFeedback `json:"user2Feedback"` // from user 2 about user 1
+	Mutual             bool                      `json:"mutual"`
+}
+
+// VelvetHourDigestResult counts what a run of a session's digest emails did.
+// Skipped covers participants already emailed and those who met nobody.
+type VelvetHourDigestResult struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	Sent      int       `json:"sent"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
 }
\ No newline at end of file
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/smtp"
+	"strings"
 )
 
 type EmailService struct {
@@ -45,21 +47,40 @@ func NewEmailService(service, brevoKey, smtpHost string, smtpPort int, frontendU
 	}
 }
 
+// VelvetHourDigest is the summary a participant receives after a Velvet Hour session
+type VelvetHourDigest struct {
+	Name        string
+	EventName   string
+	PeopleMet   int
+	Connections []VelvetHourDigestConnection
+}
+
+// VelvetHourDigestConnection is a mutual connection and the contact details
+// that person chose to share
+type VelvetHourDigestConnection struct {
+	Name      string
+	Email     string // empty when not shared
+	Instagram string // empty when not shared
+}
+
+// siteURL returns the first domain of the configured frontend URL
+func (e *EmailService) siteURL() string {
+	if len(e.frontendURL) > 0 && e.frontendURL[0:4] == "http" {
+		for i := 0; i < len(e.frontendURL); i++ {
+			if e.frontendURL[i] == ',' {
+				return e.frontendURL[:i]
+			}
+		}
+	}
+	return e.frontendURL
+}
+
 func (e *EmailService) SendMagicLink(email, token, origin string) error {
-	// Use the request origin if provided, otherwise fallback to configured frontend URL
-	baseURL := e.frontendURL
+	// Use the request origin if provided, otherwise fallback to the first
+	// domain from the configured frontend URL
+	baseURL := e.siteURL()
 	if origin != "" {
 		baseURL = origin
-	} else {
-		// If no origin provided, use the first domain from the configured frontend URL
-		if len(e.frontendURL) > 0 && e.frontendURL[0:4] == "http" {
-			for i := 0; i < len(e.frontendURL); i++ {
-				if e.frontendURL[i] == ',' {
-					baseURL = e.frontendURL[:i]
-					break
-				}
-			}
-		}
 	}
 	
 	magicLink := fmt.Sprintf("%s/verify?token=%s", baseURL, token)
@@ -119,6 +140,92 @@ func (e *EmailService) SendMagicLink(email, token, origin string) error {
 	}
 }
 
+// SendVelvetHourDigest emails a participant how many people they met at a
+// Velvet Hour session and their mutual connections, with a link back to the
+// dashboard
+func (e *EmailService) SendVelvetHourDigest(email string, digest VelvetHourDigest) error {
+	baseURL := e.siteURL()
+	dashboardLink := fmt.Sprintf("%s/dashboard", baseURL)
+
+	greeting := "Hi there"
+	if digest.Name != "" {
+		greeting = "Hi " + html.EscapeString(digest.Name)
+	}
+	people := "people"
+	if digest.PeopleMet == 1 {
+		people = "person"
+	}
+
+	var connections strings.Builder
+	if len(digest.Connections) == 0 {
+		connections.WriteString(`<p style="color: #666; font-size: 16px; line-height: 1.6;">No mutual connections this time, but every conversation counts. We hope to see you at the next one!</p>`)
+	} else {
+		connections.WriteString(`<h3 style="color: #333;">Your mutual connections</h3><ul style="color: #666; font-size: 16px; line-height: 1.8; padding-left: 20px;">`)
+		for _, connection := range digest.Connections {
+			connections.WriteString("<li><strong>" + html.EscapeString(connection.Name) + "</strong>")
+			var details []string
+			if connection.Email != "" {
+				details = append(details, html.EscapeString(connection.Email))
+			}
+			if connection.Instagram != "" {
+				details = append(details, "Instagram: "+html.EscapeString(connection.Instagram))
+			}
+			if len(details) > 0 {
+				connections.WriteString(" &middot; " + strings.Join(details, " &middot; "))
+			}
+			connections.WriteString("</li>")
+		}
+		connections.WriteString("</ul>")
+	}
+
+	subject := fmt.Sprintf("Your Velvet Hour at %s", digest.EventName)
+	htmlContent := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="utf-8">
+			<title>Your Velvet Hour</title>
+		</head>
+		<body style="font-family: Arial, sans-serif; background: linear-gradient(135deg, #2563eb 0%%, #7c3aed 100%%); margin: 0; padding: 20px;">
+			<div style="max-width: 600px; margin: 0 auto; background: rgba(255, 255, 255, 0.1); backdrop-filter: blur(10px); border-radius: 20px; padding: 30px; border: 1px solid rgba(255, 255, 255, 0.2);">
+				<div style="text-align: center; margin-bottom: 30px;">
+					<h1 style="color: white; font-size: 32px; margin: 0; text-shadow: 0 2px 4px rgba(0,0,0,0.3);">🐘🗼 ElephantTO Events</h1>
+					<p style="color: rgba(255,255,255,0.9); font-size: 16px; margin: 10px 0 0 0;">%s</p>
+				</div>
+				
+				<div style="background: rgba(255, 255, 255, 0.9); border-radius: 15px; padding: 25px; margin-bottom: 20px;">
+					<h2 style="color: #333; margin-top: 0;">%s, thanks for joining Velvet Hour!</h2>
+					<p style="color: #666; font-size: 16px; line-height: 1.6;">
+						You met <strong>%d</strong> %s tonight.
+					</p>
+					%s
+					<div style="text-align: center; margin: 30px 0;">
+						<a href="%s" style="background: linear-gradient(135deg, #2563eb 0%%, #7c3aed 100%%); color: white; text-decoration: none; padding: 15px 30px; border-radius: 25px; font-weight: bold; font-size: 16px; display: inline-block; box-shadow: 0 4px 15px rgba(37, 99, 235, 0.4);">
+							See your connections
+						</a>
+					</div>
+				</div>
+				
+				<div style="text-align: center; color: rgba(255, 255, 255, 0.8); font-size: 14px;">
+					<p>This email was sent to %s because you took part in Velvet Hour.</p>
+					<p style="margin-top: 20px;">ElephantTO Events - Making memories, one event at a time in Toronto 🎉</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, html.EscapeString(digest.EventName), greeting, digest.PeopleMet, people, connections.String(), dashboardLink, html.EscapeString(email))
+
+	emailService := e.determineEmailService(baseURL)
+	switch emailService {
+	case "brevo":
+		return e.sendViaBrevo(email, subject, htmlContent)
+	case "mailpit":
+		return e.sendViaSMTP(email, subject, htmlContent)
+	default:
+		return fmt.Errorf("unsupported email service: %s", emailService)
+	}
+}
+
 // determineEmailService decides which email service to use based on the request origin
 func (e *EmailService) determineEmailService(origin string) string {
 	// If email service override is not enabled, use the configured service
@@ -25,14 +25,22 @@ const (
 	MessageTypeVelvetHourSessionReset     = "VELVET_HOUR_SESSION_RESET"
 	MessageTypeAttendanceStatsUpdate      = "ATTENDANCE_STATS_UPDATE"
 	MessageTypeVelvetHourStatusUpdate     = "VELVET_HOUR_STATUS_UPDATE"
+	MessageTypeVelvetHourConnectionMade   = "VELVET_HOUR_CONNECTION_MADE"
+	MessageTypeVelvetHourMatchReassigned  = "VELVET_HOUR_MATCH_REASSIGNED"
+	MessageTypeVelvetHourNoShow           = "VELVET_HOUR_NO_SHOW"
+	MessageTypeVelvetHourTimerUpdated     = "VELVET_HOUR_TIMER_UPDATED"
+	MessageTypeResyncRequired             = "RESYNC_REQUIRED"
 	MessageTypePing                       = "PING"
 	MessageTypePong                       = "PONG"
 )
 
-// WebSocketMessage represents a message sent over WebSocket
+// WebSocketMessage represents a message sent over WebSocket.
+// Broadcasts carry a sequence number that increases with every broadcast to
+// the event; clients pass the last one they saw when reconnecting.
 type WebSocketMessage struct {
 	Type      string      `json:"type"`
 	EventID   uuid.UUID   `json:"eventId"`
+	Seq       int64       `json:"seq,omitempty"`
 	Data      interface{} `json:"data"`
 	Timestamp int64       `json:"timestamp"`
 }
@@ -46,8 +54,16 @@ type Client struct {
 	Send          chan WebSocketMessage
 	IsAdmin       bool
 	LastHeartbeat time.Time
+	LastSeq       int64 // last sequence number seen before reconnecting, 0 for a fresh connection
+
+	// The event's latest sequence number when a reconnecting client arrived
+	latestSeq int64
 }
 
+// PresenceListener is called when a user's first connection to an event opens
+// (present is true) or their last one closes (present is false)
+type PresenceListener func(eventID uuid.UUID, userID uuid.UUID, present bool)
+
 // Hub manages WebSocket connections and message broadcasting
 type Hub struct {
 	// Event-based rooms: eventID -> map[clientID]Client
@@ -66,18 +82,38 @@ type Hub struct {
 	presenceUpdateTimers map[uuid.UUID]*time.Timer
 	presenceUpdateMutex  sync.Mutex
 
+	// Callbacks for users arriving at or leaving an event
+	presenceListeners []PresenceListener
+
+	// Shares broadcasts and presence with other instances serving the same events
+	backend HubBackend
+
+	// Recent sequenced messages per event, replayed to clients that reconnect
+	replay map[uuid.UUID]*replayBuffer
+
+	// Keeps presence changes reaching the backend in order
+	presenceSyncMutex sync.Mutex
+
 	// Mutex for thread-safe operations
 	mutex sync.RWMutex
 }
 
-// NewWebSocketHub creates a new WebSocket hub
+// NewWebSocketHub creates a new WebSocket hub that serves a single process
 func NewWebSocketHub() *Hub {
+	return NewWebSocketHubWithBackend(NewMemoryHubBackend())
+}
+
+// NewWebSocketHubWithBackend creates a new WebSocket hub that shares its rooms
+// with other instances through the given backend
+func NewWebSocketHubWithBackend(backend HubBackend) *Hub {
 	return &Hub{
 		Rooms:                make(map[uuid.UUID]map[uuid.UUID]*Client),
 		Register:             make(chan *Client),
 		Unregister:           make(chan *Client),
 		Broadcast:            make(chan WebSocketMessage),
 		presenceUpdateTimers: make(map[uuid.UUID]*time.Timer),
+		backend:              backend,
+		replay:               make(map[uuid.UUID]*replayBuffer),
 	}
 }
 
@@ -86,6 +122,13 @@ func (h *Hub) Run() {
 	// Create a ticker to check for stale connections every 60 seconds (increased from 30s)
 	heartbeatTicker := time.NewTicker(60 * time.Second)
 	defer heartbeatTicker.Stop()
+
+	// Deliver what other instances publish to this instance's clients
+	go func() {
+		if err := h.backend.Start(h.deliverRemote); err != nil {
+			log.Printf("WebSocket hub backend stopped, only serving this instance: %v", err)
+		}
+	}()
 	
 	for {
 		select {
@@ -113,7 +156,16 @@ func (h *Hub) registerClient(client *Client) {
 		h.Rooms[client.EventID] = make(map[uuid.UUID]*Client)
 	}
 	
+	// Catch a reconnecting client up before anything new reaches it
+	if client.LastSeq > 0 {
+		h.replayLocked(client, client.latestSeq)
+	}
+
+	alreadyPresent := h.isUserConnectedLocked(client.EventID, client.UserID)
 	h.Rooms[client.EventID][client.ID] = client
+	if !alreadyPresent {
+		go h.presenceChanged(client.EventID, client.UserID, true)
+	}
 	
 	log.Printf("Client %s joined event room %s (Admin: %v)", client.ID, client.EventID, client.IsAdmin)
 	
@@ -139,6 +191,9 @@ func (h *Hub) unregisterClient(client *Client) {
 			}
 			
 			log.Printf("Client %s left event room %s", client.ID, client.EventID)
+			if !h.isUserConnectedLocked(client.EventID, client.UserID) {
+				go h.presenceChanged(client.EventID, client.UserID, false)
+			}
 			
 			// Broadcast presence update to admins
 			presentCount := h.getPresentUserCountLocked(client.EventID)
@@ -171,54 +226,96 @@ func (h *Hub) broadcastToRoom(message WebSocketMessage) {
 
 // BroadcastToEvent sends a message to all clients in an event room
 func (h *Hub) BroadcastToEvent(eventID uuid.UUID, messageType string, data interface{}) {
+	h.send(eventID, messageType, data, HubAudience{Scope: AudienceAll})
+}
+
+// BroadcastToAdmins sends a message only to admin clients in an event room
+func (h *Hub) BroadcastToAdmins(eventID uuid.UUID, messageType string, data interface{}) {
+	h.send(eventID, messageType, data, HubAudience{Scope: AudienceAdmins})
+}
+
+// SendToUsers sends a message only to the given users' connections in an event room
+func (h *Hub) SendToUsers(eventID uuid.UUID, userIDs []uuid.UUID, messageType string, data interface{}) {
+	h.send(eventID, messageType, data, HubAudience{Scope: AudienceUsers, Users: userIDs})
+}
+
+// BroadcastScoped delivers a message whose details are private to a few users.
+// The given users and admins receive the private payload; everyone else in the
+// room receives the redacted public payload, or nothing when it is nil.
+func (h *Hub) BroadcastScoped(eventID uuid.UUID, userIDs []uuid.UUID, messageType string, private, public interface{}) {
+	h.send(eventID, messageType, private, HubAudience{Scope: AudiencePrivate, Users: userIDs})
+	if public != nil {
+		h.send(eventID, messageType, public, HubAudience{Scope: AudiencePublic, Users: userIDs})
+	}
+}
+
+// send delivers a message to this instance's clients in the audience and
+// publishes it for the other instances to deliver to theirs
+func (h *Hub) send(eventID uuid.UUID, messageType string, data interface{}, audience HubAudience) {
 	message := WebSocketMessage{
 		Type:      messageType,
 		EventID:   eventID,
 		Data:      data,
 		Timestamp: getCurrentTimestamp(),
 	}
-	
-	select {
-	case h.Broadcast <- message:
+	seq, err := h.backend.NextSequence(eventID)
+	if err != nil {
+		// Still deliver it; clients that miss it can't have it replayed
+		log.Printf("Failed to number %s message for event %s: %v", messageType, eventID, err)
+	}
+	message.Seq = seq
+	h.deliver(message, audience)
+	h.publish(message, audience)
+}
+
+// publish hands a message to the other instances serving its event
+func (h *Hub) publish(message WebSocketMessage, audience HubAudience) {
+	err := h.backend.Publish(HubEnvelope{Kind: HubEnvelopeMessage, Message: message, Audience: audience})
+	if err != nil {
+		log.Printf("Failed to publish %s message for event %s to other instances: %v", message.Type, message.EventID, err)
+	}
+}
+
+// deliverRemote acts on an envelope published by another instance
+func (h *Hub) deliverRemote(envelope HubEnvelope) {
+	switch envelope.Kind {
+	case HubEnvelopeMessage:
+		h.deliver(envelope.Message, envelope.Audience)
+	case HubEnvelopeDisconnect:
+		h.disconnectNonAdmins(envelope.Message.EventID)
+	case HubEnvelopeResync:
+		h.resyncAll()
 	default:
-		log.Printf("Broadcast channel is full, dropping message type: %s", messageType)
+		log.Printf("Ignoring unknown hub envelope kind %q", envelope.Kind)
 	}
 }
 
-// BroadcastToAdmins sends a message only to admin clients in an event room
-func (h *Hub) BroadcastToAdmins(eventID uuid.UUID, messageType string, data interface{}) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+// deliver sends a message to the clients in its event room that the audience
+// includes, and keeps it for replay even when none are connected here.
+// Clients whose send channel is full miss the message; the stale connection
+// check removes them if they stop responding.
+func (h *Hub) deliver(message WebSocketMessage, audience HubAudience) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 
-	room, exists := h.Rooms[eventID]
+	h.bufferLocked(message, audience)
+
+	room, exists := h.Rooms[message.EventID]
 	if !exists {
-		log.Printf("DEBUG BroadcastToAdmins: No room exists for event %s", eventID)
 		return
 	}
 
-	message := WebSocketMessage{
-		Type:      messageType,
-		EventID:   eventID,
-		Data:      data,
-		Timestamp: getCurrentTimestamp(),
-	}
-
-	adminCount := 0
+	include := audience.matcher()
 	for clientID, client := range room {
-		if client.IsAdmin {
-			adminCount++
-			select {
-			case client.Send <- message:
-				log.Printf("DEBUG BroadcastToAdmins: Sent %s message to admin client %s", messageType, clientID)
-			default:
-				// Client's send channel is blocked, remove client
-				close(client.Send)
-				delete(room, clientID)
-				log.Printf("Removed blocked admin client %s from room %s", clientID, eventID)
-			}
+		if !include(client) {
+			continue
+		}
+		select {
+		case client.Send <- message:
+		default:
+			log.Printf("Dropped %s message for blocked client %s in room %s", message.Type, clientID, message.EventID)
 		}
 	}
-	log.Printf("DEBUG BroadcastToAdmins: Sent %s to %d admin clients in event %s", messageType, adminCount, eventID)
 }
 
 // GetRoomCount returns the number of clients in an event room
@@ -233,64 +330,107 @@ func (h *Hub) GetRoomCount(eventID uuid.UUID) int {
 }
 
 // GetPresentUsers returns a list of user IDs currently connected to an event
+// through any instance
 // Includes all users (both admin and non-admin) as potential participants
 func (h *Hub) GetPresentUsers(eventID uuid.UUID) []uuid.UUID {
+	userSet := h.remotePresence(eventID)
+	if userSet == nil {
+		userSet = make(map[uuid.UUID]bool)
+	}
+
 	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	for _, client := range h.Rooms[eventID] {
+		// Include all connections (both admin and non-admin can participate)
+		userSet[client.UserID] = true
+	}
+	h.mutex.RUnlock()
 
 	var presentUsers []uuid.UUID
-	if room, exists := h.Rooms[eventID]; exists {
-		userSet := make(map[uuid.UUID]bool)
-		for _, client := range room {
-			// Include all connections (both admin and non-admin can participate)
-			userSet[client.UserID] = true
-		}
-		for userID := range userSet {
-			presentUsers = append(presentUsers, userID)
-		}
+	for userID := range userSet {
+		presentUsers = append(presentUsers, userID)
 	}
 	return presentUsers
 }
 
 // GetPresentUserCount returns the number of unique users currently connected to an event
+// through any instance
 // Includes all users (both admin and non-admin) as potential participants
 func (h *Hub) GetPresentUserCount(eventID uuid.UUID) int {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	if room, exists := h.Rooms[eventID]; exists {
-		userSet := make(map[uuid.UUID]bool)
-		log.Printf("DEBUG GetPresentUserCount: Event %s has %d total connections:", eventID, len(room))
-		for clientID, client := range room {
-			log.Printf("DEBUG GetPresentUserCount: - Client %s: User %s (Admin: %v)", clientID, client.UserID, client.IsAdmin)
-			// Count all connections (both admin and non-admin can participate)
-			userSet[client.UserID] = true
-		}
-		count := len(userSet)
-		log.Printf("DEBUG GetPresentUserCount: Returning total user count: %d", count)
-		return count
-	}
-	log.Printf("DEBUG GetPresentUserCount: No room exists for event %s", eventID)
-	return 0
+	return len(h.GetPresentUsers(eventID))
 }
 
 // IsUserPresent checks if a specific user is currently connected to an event
+// through any instance
 // Includes both admin and non-admin users as they can both participate
 func (h *Hub) IsUserPresent(eventID uuid.UUID, userID uuid.UUID) bool {
 	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	present := h.isUserConnectedLocked(eventID, userID)
+	h.mutex.RUnlock()
 
-	if room, exists := h.Rooms[eventID]; exists {
-		for _, client := range room {
-			// Consider all connections as "present" (both admin and non-admin can participate)
-			if client.UserID == userID {
-				return true
-			}
+	return present || h.remotePresence(eventID)[userID]
+}
+
+// remotePresence returns the users connected to an event through other instances
+func (h *Hub) remotePresence(eventID uuid.UUID) map[uuid.UUID]bool {
+	present, err := h.backend.RemotePresence(eventID)
+	if err != nil {
+		log.Printf("Failed to get presence from other instances for event %s: %v", eventID, err)
+	}
+	return present
+}
+
+// OnPresenceChange registers a callback for users arriving at or leaving an event
+func (h *Hub) OnPresenceChange(listener PresenceListener) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.presenceListeners = append(h.presenceListeners, listener)
+}
+
+// isUserConnectedLocked checks whether a user still has a connection to an event (assumes mutex is already locked)
+func (h *Hub) isUserConnectedLocked(eventID uuid.UUID, userID uuid.UUID) bool {
+	for _, client := range h.Rooms[eventID] {
+		if client.UserID == userID {
+			return true
 		}
 	}
 	return false
 }
 
+// presenceChanged records a user's first connection to an event on this
+// instance opening, or their last one closing, with the backend. The presence
+// listeners only hear of it when no other instance has the user connected.
+// Call it in a goroutine of its own; it waits for the hub's lock.
+func (h *Hub) presenceChanged(eventID uuid.UUID, userID uuid.UUID, present bool) {
+	h.presenceSyncMutex.Lock()
+	defer h.presenceSyncMutex.Unlock()
+
+	h.mutex.RLock()
+	connections := 0
+	for _, client := range h.Rooms[eventID] {
+		if client.UserID == userID {
+			connections++
+		}
+	}
+	listeners := h.presenceListeners
+	h.mutex.RUnlock()
+
+	if err := h.backend.SetPresence(eventID, userID, connections); err != nil {
+		log.Printf("Failed to record presence of user %s in event %s: %v", userID, eventID, err)
+	}
+
+	// The user has come or gone again since; that change reports itself
+	if (connections > 0) != present {
+		return
+	}
+	if h.remotePresence(eventID)[userID] {
+		return
+	}
+	for _, listener := range listeners {
+		go listener(eventID, userID, present)
+	}
+}
+
 // getCurrentTimestamp returns current Unix timestamp in milliseconds
 func getCurrentTimestamp() int64 {
 	return time.Now().UnixMilli()
@@ -410,6 +550,9 @@ func (h *Hub) checkStaleConnections() {
 				if len(room) == 0 {
 					delete(h.Rooms, client.EventID)
 				}
+				if !h.isUserConnectedLocked(client.EventID, client.UserID) {
+					go h.presenceChanged(client.EventID, client.UserID, false)
+				}
 				
 				// Broadcast presence update
 				presentCount := h.getPresentUserCountLocked(client.EventID)
@@ -421,11 +564,26 @@ func (h *Hub) checkStaleConnections() {
 	if len(staleClients) > 0 {
 		log.Printf("Removed %d stale connections", len(staleClients))
 	}
+
+	h.pruneReplayLocked()
 }
 
 // ClearAllConnections forcibly disconnects non-admin users from an event room
+// on every instance and returns how many were disconnected from this one
 // Admin users stay connected to continue monitoring
 func (h *Hub) ClearAllConnections(eventID uuid.UUID) int {
+	err := h.backend.Publish(HubEnvelope{
+		Kind:    HubEnvelopeDisconnect,
+		Message: WebSocketMessage{EventID: eventID, Timestamp: getCurrentTimestamp()},
+	})
+	if err != nil {
+		log.Printf("Failed to ask other instances to clear connections for event %s: %v", eventID, err)
+	}
+	return h.disconnectNonAdmins(eventID)
+}
+
+// disconnectNonAdmins forcibly disconnects non-admin users from this instance's event room
+func (h *Hub) disconnectNonAdmins(eventID uuid.UUID) int {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	
@@ -486,18 +644,17 @@ func (h *Hub) ClearAllConnections(eventID uuid.UUID) int {
 			disconnectedCount++
 		}
 	}
-	
-	// After disconnecting non-admin users, send updated presence count to remaining admins
-	// Count remaining users (should only be admin users now)
-	presentCount := 0
-	userSet := make(map[uuid.UUID]bool)
-	for _, client := range room {
-		userSet[client.UserID] = true
+	for _, client := range nonAdminClients {
+		if !h.isUserConnectedLocked(eventID, client.UserID) {
+			go h.presenceChanged(eventID, client.UserID, false)
+		}
 	}
-	presentCount = len(userSet)
 	
-	// Send updated presence count to admin users immediately
+	// After disconnecting non-admin users, send updated presence count to remaining admins
+	// Send updated presence count to admin users immediately, counting users
+	// still connected through any instance
 	go func() {
+		presentCount := h.GetPresentUserCount(eventID)
 		h.BroadcastToAdmins(eventID, MessageTypeAttendanceStatsUpdate, map[string]interface{}{
 			"presentCount": presentCount,
 			"eventId":      eventID,
@@ -558,8 +715,19 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// HandleWebSocket handles WebSocket connection upgrades
-func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, eventID uuid.UUID, userID uuid.UUID, isAdmin bool) {
+// HandleWebSocket handles WebSocket connection upgrades. A client reconnecting
+// with the last sequence number it saw (lastSeq > 0) is sent what it missed
+// first, or RESYNC_REQUIRED when that is no longer available.
+func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, eventID uuid.UUID, userID uuid.UUID, isAdmin bool, lastSeq int64) {
+	var latestSeq int64
+	if lastSeq > 0 {
+		var err error
+		latestSeq, err = h.backend.LatestSequence(eventID)
+		if err != nil {
+			log.Printf("Failed to get latest sequence for event %s: %v", eventID, err)
+		}
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -574,6 +742,8 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, eventID uu
 		Send:          make(chan WebSocketMessage, 256),
 		IsAdmin:       isAdmin,
 		LastHeartbeat: time.Now(),
+		LastSeq:       lastSeq,
+		latestSeq:     latestSeq,
 	}
 
 	// Register client
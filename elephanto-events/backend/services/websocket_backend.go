@@ -0,0 +1,123 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of envelope passed between instances
+const (
+	// HubEnvelopeMessage carries a message for the clients in its audience
+	HubEnvelopeMessage = "message"
+	// HubEnvelopeDisconnect asks every instance to disconnect an event's non-admin clients
+	HubEnvelopeDisconnect = "disconnect"
+	// HubEnvelopeResync tells the hub envelopes may have been lost, so its
+	// replay buffers can no longer be trusted
+	HubEnvelopeResync = "resync"
+)
+
+// Audiences a message can be addressed to within an event room
+const (
+	AudienceAll     = "all"     // everyone
+	AudienceAdmins  = "admins"  // admins only
+	AudienceUsers   = "users"   // only the listed users
+	AudiencePrivate = "private" // the listed users and admins
+	AudiencePublic  = "public"  // everyone but the listed users and admins
+)
+
+// HubAudience says which clients in an event room a message is for. It travels
+// with the message so every instance can pick out its own clients.
+type HubAudience struct {
+	Scope string      `json:"scope"`
+	Users []uuid.UUID `json:"users,omitempty"`
+}
+
+// matcher returns a filter for the clients the audience includes
+func (a HubAudience) matcher() func(*Client) bool {
+	listed := make(map[uuid.UUID]bool, len(a.Users))
+	for _, userID := range a.Users {
+		listed[userID] = true
+	}
+
+	switch a.Scope {
+	case AudienceAdmins:
+		return func(client *Client) bool { return client.IsAdmin }
+	case AudienceUsers:
+		return func(client *Client) bool { return listed[client.UserID] }
+	case AudiencePrivate:
+		return func(client *Client) bool { return client.IsAdmin || listed[client.UserID] }
+	case AudiencePublic:
+		return func(client *Client) bool { return !client.IsAdmin && !listed[client.UserID] }
+	default:
+		return func(client *Client) bool { return true }
+	}
+}
+
+// HubEnvelope is what one instance hands the others through the backend
+type HubEnvelope struct {
+	Kind     string           `json:"kind"`
+	Message  WebSocketMessage `json:"message"`
+	Audience HubAudience      `json:"audience"`
+}
+
+// HubBackend connects a hub to the other instances serving the same events.
+// The hub always delivers to its own clients itself; the backend carries
+// envelopes to the other instances and tracks who is connected to them.
+type HubBackend interface {
+	// Start hands envelopes published by other instances to deliver for as
+	// long as the hub runs; the hub calls it in a goroutine of its own
+	Start(deliver func(HubEnvelope)) error
+
+	// Publish sends an envelope to every other instance
+	Publish(envelope HubEnvelope) error
+
+	// SetPresence records how many connections a user has to an event on this instance
+	SetPresence(eventID, userID uuid.UUID, connections int) error
+
+	// RemotePresence returns the users connected to an event through other instances
+	RemotePresence(eventID uuid.UUID) (map[uuid.UUID]bool, error)
+
+	// NextSequence numbers the next message broadcast to an event, in the same
+	// sequence on every instance
+	NextSequence(eventID uuid.UUID) (int64, error)
+
+	// LatestSequence returns the number of the last message broadcast to an event
+	LatestSequence(eventID uuid.UUID) (int64, error)
+}
+
+// memoryHubBackend serves a single process: there are no other instances to
+// tell, so it only numbers messages. It is the default, for development.
+// Sequence numbers start again when the process restarts.
+type memoryHubBackend struct {
+	sequences map[uuid.UUID]int64
+	mutex     sync.Mutex
+}
+
+// NewMemoryHubBackend creates a backend for a hub that runs in a single process
+func NewMemoryHubBackend() HubBackend {
+	return &memoryHubBackend{sequences: make(map[uuid.UUID]int64)}
+}
+
+func (*memoryHubBackend) Start(deliver func(HubEnvelope)) error { return nil }
+
+func (*memoryHubBackend) Publish(envelope HubEnvelope) error { return nil }
+
+func (*memoryHubBackend) SetPresence(eventID, userID uuid.UUID, connections int) error { return nil }
+
+func (*memoryHubBackend) RemotePresence(eventID uuid.UUID) (map[uuid.UUID]bool, error) {
+	return nil, nil
+}
+
+func (b *memoryHubBackend) NextSequence(eventID uuid.UUID) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sequences[eventID]++
+	return b.sequences[eventID], nil
+}
+
+func (b *memoryHubBackend) LatestSequence(eventID uuid.UUID) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.sequences[eventID], nil
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// hubNotifyChannel is the Postgres channel instances notify each other on
+	hubNotifyChannel = "websocket_hub"
+
+	// hubNotifyPayloadLimit keeps notifications under Postgres's 8000 byte
+	// payload limit; bigger envelopes are stored and only their ID is sent
+	hubNotifyPayloadLimit = 7900
+
+	// hubHeartbeatInterval is how often an instance tells the others it is alive
+	hubHeartbeatInterval = 10 * time.Second
+
+	// hubInstanceTimeout is how long an instance can miss heartbeats before the
+	// others stop counting the users connected to it
+	hubInstanceTimeout = 30 * time.Second
+
+	// hubMessageRetention is how long stored envelopes are kept for instances to read
+	hubMessageRetention = 5 * time.Minute
+)
+
+// hubNotification is the payload of a notification: the envelope itself, or
+// the ID it was stored under when too big to send
+type hubNotification struct {
+	Origin    uuid.UUID       `json:"origin"`
+	Envelope  json.RawMessage `json:"envelope,omitempty"`
+	MessageID *uuid.UUID      `json:"messageId,omitempty"`
+}
+
+// presenceKey identifies a user's connections to an event
+type presenceKey struct {
+	EventID uuid.UUID
+	UserID  uuid.UUID
+}
+
+// PostgresHubBackend lets several backend replicas share WebSocket rooms.
+// Envelopes travel between instances with LISTEN/NOTIFY, and each instance
+// records who is connected to it so presence is counted across the cluster.
+// Users connected through an instance that stops heartbeating stop counting
+// as present once it times out.
+type PostgresHubBackend struct {
+	db          *sql.DB
+	databaseURL string
+	instanceID  uuid.UUID
+	listener    *pq.Listener
+
+	// This instance's presence as last recorded, to write back if the other
+	// instances gave up on it
+	presence      map[presenceKey]int
+	presenceMutex sync.Mutex
+}
+
+// NewPostgresHubBackend registers this instance with the others sharing the
+// database. databaseURL is used to open the connection that listens for
+// notifications.
+func NewPostgresHubBackend(db *sql.DB, databaseURL string) (*PostgresHubBackend, error) {
+	b := &PostgresHubBackend{
+		db:          db,
+		databaseURL: databaseURL,
+		instanceID:  uuid.New(),
+		presence:    make(map[presenceKey]int),
+	}
+	if err := b.heartbeat(); err != nil {
+		return nil, fmt.Errorf("failed to register instance: %w", err)
+	}
+	return b, nil
+}
+
+// InstanceID returns the ID this instance is known by to the others
+func (b *PostgresHubBackend) InstanceID() uuid.UUID {
+	return b.instanceID
+}
+
+// Start listens for envelopes from other instances and keeps this instance's
+// heartbeat going
+func (b *PostgresHubBackend) Start(deliver func(HubEnvelope)) error {
+	b.listener = pq.NewListener(b.databaseURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("WebSocket hub listener: %v", err)
+		}
+	})
+	if err := b.listener.Listen(hubNotifyChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", hubNotifyChannel, err)
+	}
+
+	go b.keepAlive()
+
+	for notification := range b.listener.Notify {
+		// A nil notification means the connection was re-established;
+		// anything sent while it was down is lost
+		if notification == nil {
+			log.Printf("WebSocket hub listener reconnected, notifications may have been lost")
+			deliver(HubEnvelope{Kind: HubEnvelopeResync})
+			continue
+		}
+		envelope, ok, err := b.decode(notification.Extra)
+		if err != nil {
+			log.Printf("WebSocket hub: failed to read notification: %v", err)
+			continue
+		}
+		if ok {
+			deliver(envelope)
+		}
+	}
+	return nil
+}
+
+// decode turns a notification back into the envelope it carries. Envelopes
+// this instance published are skipped, since it delivered them itself.
+func (b *PostgresHubBackend) decode(payload string) (HubEnvelope, bool, error) {
+	var envelope HubEnvelope
+	var notification hubNotification
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		return envelope, false, err
+	}
+	if notification.Origin == b.instanceID {
+		return envelope, false, nil
+	}
+
+	raw := []byte(notification.Envelope)
+	if notification.MessageID != nil {
+		err := b.db.QueryRow(`
+			SELECT envelope FROM websocket_hub_messages WHERE id = $1
+		`, *notification.MessageID).Scan(&raw)
+		if err != nil {
+			return envelope, false, fmt.Errorf("failed to get stored envelope %s: %w", *notification.MessageID, err)
+		}
+	}
+
+	// Keep numbers as they were sent rather than rounding them through float64
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&envelope); err != nil {
+		return envelope, false, err
+	}
+	return envelope, true, nil
+}
+
+// Publish notifies every other instance of an envelope
+func (b *PostgresHubBackend) Publish(envelope HubEnvelope) error {
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(hubNotification{Origin: b.instanceID, Envelope: raw})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) >= hubNotifyPayloadLimit {
+		var messageID uuid.UUID
+		err := b.db.QueryRow(`
+			INSERT INTO websocket_hub_messages (envelope) VALUES ($1) RETURNING id
+		`, string(raw)).Scan(&messageID)
+		if err != nil {
+			return fmt.Errorf("failed to store envelope: %w", err)
+		}
+		payload, err = json.Marshal(hubNotification{Origin: b.instanceID, MessageID: &messageID})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = b.db.Exec(`SELECT pg_notify($1, $2)`, hubNotifyChannel, string(payload))
+	return err
+}
+
+// SetPresence records how many connections a user has to an event on this instance
+func (b *PostgresHubBackend) SetPresence(eventID, userID uuid.UUID, connections int) error {
+	b.presenceMutex.Lock()
+	defer b.presenceMutex.Unlock()
+
+	key := presenceKey{EventID: eventID, UserID: userID}
+	if connections == 0 {
+		delete(b.presence, key)
+		_, err := b.db.Exec(`
+			DELETE FROM websocket_hub_presence
+			WHERE instance_id = $1 AND event_id = $2 AND user_id = $3
+		`, b.instanceID, eventID, userID)
+		return err
+	}
+
+	b.presence[key] = connections
+	return b.writePresence(key, connections)
+}
+
+// writePresence stores one user's connection count for this instance
+func (b *PostgresHubBackend) writePresence(key presenceKey, connections int) error {
+	_, err := b.db.Exec(`
+		INSERT INTO websocket_hub_presence (instance_id, event_id, user_id, connections)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (instance_id, event_id, user_id)
+		DO UPDATE SET connections = EXCLUDED.connections, updated_at = CURRENT_TIMESTAMP
+	`, b.instanceID, key.EventID, key.UserID, connections)
+	return err
+}
+
+// RemotePresence returns the users connected to an event through other live instances
+func (b *PostgresHubBackend) RemotePresence(eventID uuid.UUID) (map[uuid.UUID]bool, error) {
+	rows, err := b.db.Query(`
+		SELECT DISTINCT p.user_id
+		FROM websocket_hub_presence p
+		JOIN websocket_hub_instances i ON p.instance_id = i.id
+		WHERE p.event_id = $1 AND p.instance_id <> $2
+		  AND i.heartbeat_at > CURRENT_TIMESTAMP - make_interval(secs => $3)
+	`, eventID, b.instanceID, hubInstanceTimeout.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	present := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		present[userID] = true
+	}
+	return present, rows.Err()
+}
+
+// NextSequence numbers the next message broadcast to an event
+func (b *PostgresHubBackend) NextSequence(eventID uuid.UUID) (int64, error) {
+	var seq int64
+	err := b.db.QueryRow(`
+		INSERT INTO websocket_hub_sequences (event_id, seq) VALUES ($1, 1)
+		ON CONFLICT (event_id) DO UPDATE SET seq = websocket_hub_sequences.seq + 1
+		RETURNING seq
+	`, eventID).Scan(&seq)
+	return seq, err
+}
+
+// LatestSequence returns the number of the last message broadcast to an event
+func (b *PostgresHubBackend) LatestSequence(eventID uuid.UUID) (int64, error) {
+	var seq int64
+	err := b.db.QueryRow(`
+		SELECT COALESCE((SELECT seq FROM websocket_hub_sequences WHERE event_id = $1), 0)
+	`, eventID).Scan(&seq)
+	return seq, err
+}
+
+// heartbeat tells the other instances this one is alive. If they had given up
+// on it, and removed its presence with it, the presence is written back.
+func (b *PostgresHubBackend) heartbeat() error {
+	var registered bool
+	err := b.db.QueryRow(`
+		INSERT INTO websocket_hub_instances (id) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET heartbeat_at = CURRENT_TIMESTAMP
+		RETURNING xmax = 0
+	`, b.instanceID).Scan(&registered)
+	if err != nil || !registered {
+		return err
+	}
+
+	b.presenceMutex.Lock()
+	defer b.presenceMutex.Unlock()
+	for key, connections := range b.presence {
+		if err := b.writePresence(key, connections); err != nil {
+			return fmt.Errorf("failed to restore presence: %w", err)
+		}
+	}
+	return nil
+}
+
+// keepAlive heartbeats, pings the listener connection, and clears out
+// instances that have gone away and envelopes everyone has had time to read
+func (b *PostgresHubBackend) keepAlive() {
+	ticker := time.NewTicker(hubHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := b.heartbeat(); err != nil {
+			log.Printf("WebSocket hub: heartbeat failed: %v", err)
+		}
+		if err := b.listener.Ping(); err != nil {
+			log.Printf("WebSocket hub: listener ping failed: %v", err)
+		}
+
+		_, err := b.db.Exec(`
+			DELETE FROM websocket_hub_instances
+			WHERE heartbeat_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		`, hubInstanceTimeout.Seconds())
+		if err != nil {
+			log.Printf("WebSocket hub: failed to remove stale instances: %v", err)
+		}
+		_, err = b.db.Exec(`
+			DELETE FROM websocket_hub_messages
+			WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		`, hubMessageRetention.Seconds())
+		if err != nil {
+			log.Printf("WebSocket hub: failed to remove old envelopes: %v", err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// replayBufferSize is how many recent messages per event are kept for
+	// clients that reconnect
+	replayBufferSize = 256
+
+	// replayBufferIdle is how long an event's buffer is kept without new messages
+	replayBufferIdle = 30 * time.Minute
+)
+
+// replayEntry is a sequenced message and who it was for
+type replayEntry struct {
+	Message  WebSocketMessage
+	Audience HubAudience
+}
+
+// replayBuffer holds an event's most recent sequenced messages in order.
+// Anything at or below floor may be missing from it.
+type replayBuffer struct {
+	entries   []replayEntry
+	floor     int64
+	latest    int64
+	updatedAt time.Time
+}
+
+// add files a message in sequence order, dropping the oldest once the buffer is full.
+// Messages from other instances can arrive slightly out of order.
+func (b *replayBuffer) add(entry replayEntry) {
+	seq := entry.Message.Seq
+	i := len(b.entries)
+	for i > 0 && b.entries[i-1].Message.Seq > seq {
+		i--
+	}
+	b.entries = append(b.entries, replayEntry{})
+	copy(b.entries[i+1:], b.entries[i:])
+	b.entries[i] = entry
+
+	if len(b.entries) > replayBufferSize {
+		b.floor = b.entries[0].Message.Seq
+		b.entries = b.entries[1:]
+	}
+	if seq > b.latest {
+		b.latest = seq
+	}
+	b.updatedAt = time.Now()
+}
+
+// bufferLocked keeps a sequenced message for replay (assumes mutex is already locked)
+func (h *Hub) bufferLocked(message WebSocketMessage, audience HubAudience) {
+	if message.Seq == 0 {
+		return
+	}
+	buffer, exists := h.replay[message.EventID]
+	if !exists {
+		// Messages before the first one this instance saw are unknown to it
+		buffer = &replayBuffer{floor: message.Seq - 1}
+		h.replay[message.EventID] = buffer
+	}
+	buffer.add(replayEntry{Message: message, Audience: audience})
+}
+
+// replayLocked queues what a reconnecting client missed since its last
+// sequence number, or a RESYNC_REQUIRED message when that can't be worked
+// out (assumes mutex is already locked)
+func (h *Hub) replayLocked(client *Client, latest int64) {
+	buffer := h.replay[client.EventID]
+	if buffer != nil && buffer.latest > latest {
+		latest = buffer.latest
+	}
+
+	switch {
+	case client.LastSeq == latest:
+		return
+	case client.LastSeq > latest, buffer == nil, client.LastSeq < buffer.floor:
+		// Ahead of us means the sequence was reset; behind the buffer means
+		// messages have been dropped
+		h.sendResyncLocked(client, latest)
+		return
+	}
+
+	replayed := 0
+	for _, entry := range buffer.entries {
+		if entry.Message.Seq <= client.LastSeq || !entry.Audience.matcher()(client) {
+			continue
+		}
+		select {
+		case client.Send <- entry.Message:
+			replayed++
+		default:
+			h.sendResyncLocked(client, latest)
+			return
+		}
+	}
+	log.Printf("Replayed %d messages to client %s in event %s after seq %d", replayed, client.ID, client.EventID, client.LastSeq)
+}
+
+// sendResyncLocked tells a client it missed messages that can't be replayed,
+// so it should reload its state (assumes mutex is already locked)
+func (h *Hub) sendResyncLocked(client *Client, latest int64) {
+	message := WebSocketMessage{
+		Type:    MessageTypeResyncRequired,
+		EventID: client.EventID,
+		Data: map[string]interface{}{
+			"lastSeq":   client.LastSeq,
+			"latestSeq": latest,
+		},
+		Timestamp: getCurrentTimestamp(),
+	}
+	select {
+	case client.Send <- message:
+		log.Printf("Sent RESYNC_REQUIRED to client %s in event %s (lastSeq %d, latest %d)", client.ID, client.EventID, client.LastSeq, latest)
+	default:
+		log.Printf("Failed to send RESYNC_REQUIRED to client %s", client.ID)
+	}
+}
+
+// resyncAll drops every replay buffer and tells this instance's clients to
+// reload their state, after messages from other instances may have been lost
+func (h *Hub) resyncAll() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.replay = make(map[uuid.UUID]*replayBuffer)
+	for _, room := range h.Rooms {
+		for _, client := range room {
+			// The latest sequence number isn't known here
+			h.sendResyncLocked(client, 0)
+		}
+	}
+}
+
+// pruneReplayLocked drops the buffers of events that have gone quiet (assumes mutex is already locked)
+func (h *Hub) pruneReplayLocked() {
+	for eventID, buffer := range h.replay {
+		if time.Since(buffer.updatedAt) > replayBufferIdle {
+			delete(h.replay, eventID)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func sequenced(eventID uuid.UUID, seq int64, audience HubAudience) replayEntry {
+	return replayEntry{
+		Message:  WebSocketMessage{Type: "TEST", EventID: eventID, Seq: seq},
+		Audience: audience,
+	}
+}
+
+func TestReplayBufferAdd(t *testing.T) {
+	eventID := uuid.New()
+	everyone := HubAudience{Scope: AudienceAll}
+
+	t.Run("out of order", func(t *testing.T) {
+		buffer := &replayBuffer{}
+		for _, seq := range []int64{1, 2, 5, 3, 4, 6} {
+			buffer.add(sequenced(eventID, seq, everyone))
+		}
+		for i, entry := range buffer.entries {
+			if entry.Message.Seq != int64(i+1) {
+				t.Fatalf("entry %d has seq %d, want %d", i, entry.Message.Seq, i+1)
+			}
+		}
+		if buffer.latest != 6 || buffer.floor != 0 {
+			t.Errorf("latest %d, floor %d, want 6 and 0", buffer.latest, buffer.floor)
+		}
+	})
+
+	t.Run("late arrival keeps latest", func(t *testing.T) {
+		buffer := &replayBuffer{}
+		buffer.add(sequenced(eventID, 9, everyone))
+		buffer.add(sequenced(eventID, 7, everyone))
+		if buffer.latest != 9 {
+			t.Errorf("latest %d, want 9", buffer.latest)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		buffer := &replayBuffer{}
+		total := int64(replayBufferSize + 10)
+		for seq := int64(1); seq <= total; seq++ {
+			buffer.add(sequenced(eventID, seq, everyone))
+		}
+		if len(buffer.entries) != replayBufferSize {
+			t.Fatalf("kept %d entries, want %d", len(buffer.entries), replayBufferSize)
+		}
+		if buffer.floor != 10 || buffer.entries[0].Message.Seq != 11 {
+			t.Errorf("floor %d, oldest %d, want 10 and 11", buffer.floor, buffer.entries[0].Message.Seq)
+		}
+		if buffer.latest != total {
+			t.Errorf("latest %d, want %d", buffer.latest, total)
+		}
+	})
+}
+
+func TestHubReplayLocked(t *testing.T) {
+	eventID := uuid.New()
+	user, other := uuid.New(), uuid.New()
+
+	// Seqs 5 to 8 are known, the first four were missed by this instance
+	newHub := func() *Hub {
+		hub := &Hub{replay: make(map[uuid.UUID]*replayBuffer)}
+		hub.bufferLocked(WebSocketMessage{EventID: eventID, Seq: 5}, HubAudience{Scope: AudienceAll})
+		hub.bufferLocked(WebSocketMessage{EventID: eventID, Seq: 6}, HubAudience{Scope: AudienceUsers, Users: []uuid.UUID{other}})
+		hub.bufferLocked(WebSocketMessage{EventID: eventID, Seq: 7}, HubAudience{Scope: AudienceUsers, Users: []uuid.UUID{user}})
+		hub.bufferLocked(WebSocketMessage{EventID: eventID, Seq: 8}, HubAudience{Scope: AudienceAdmins})
+		return hub
+	}
+
+	tests := []struct {
+		name     string
+		eventID  uuid.UUID
+		lastSeq  int64
+		isAdmin  bool
+		latest   int64
+		want     []int64
+		wantSync bool
+	}{
+		{name: "up to date", eventID: eventID, lastSeq: 8},
+		{name: "missed some", eventID: eventID, lastSeq: 4, want: []int64{5, 7}},
+		{name: "admin missed some", eventID: eventID, lastSeq: 5, isAdmin: true, want: []int64{7, 8}},
+		{name: "behind the buffer", eventID: eventID, lastSeq: 3, wantSync: true},
+		{name: "ahead of the buffer", eventID: eventID, lastSeq: 12, wantSync: true},
+		{name: "no buffer", eventID: uuid.New(), lastSeq: 2, latest: 4, wantSync: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newHub()
+			client := &Client{ID: uuid.New(), EventID: tt.eventID, UserID: user, IsAdmin: tt.isAdmin, LastSeq: tt.lastSeq, Send: make(chan WebSocketMessage, 8)}
+			hub.replayLocked(client, tt.latest)
+			close(client.Send)
+
+			var got []WebSocketMessage
+			for message := range client.Send {
+				got = append(got, message)
+			}
+
+			if tt.wantSync {
+				if len(got) != 1 || got[0].Type != MessageTypeResyncRequired {
+					t.Fatalf("got %v, want a single RESYNC_REQUIRED", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("replayed %d messages, want %v", len(got), tt.want)
+			}
+			for i, message := range got {
+				if message.Seq != tt.want[i] {
+					t.Errorf("message %d has seq %d, want %d", i, message.Seq, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("full send buffer", func(t *testing.T) {
+		hub := newHub()
+		client := &Client{ID: uuid.New(), EventID: eventID, UserID: user, IsAdmin: true, LastSeq: 4, Send: make(chan WebSocketMessage, 2)}
+		hub.replayLocked(client, 0)
+		if len(client.Send) != 2 {
+			t.Errorf("queued %d messages, want the send buffer filled", len(client.Send))
+		}
+	})
+}
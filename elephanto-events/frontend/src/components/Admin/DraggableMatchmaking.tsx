@@ -674,27 +674,29 @@ export const DraggableMatchmaking: React.FC<DraggableMatchmakingProps> = ({
                 {userPreferences ? (
                   <div className="space-y-3 text-sm">
                     <div>
-                      <span className="text-white/70">Primary Interest: </span>
-                      <span className="text-white">{userPreferences.primaryInterest || 'Not specified'}</span>
+                      <span className="text-white/70">Age: </span>
+                      <span className="text-white">{userPreferences.age || 'Not specified'}</span>
                     </div>
                     <div>
-                      <span className="text-white/70">Why Here: </span>
-                      <span className="text-white">{userPreferences.whyHere || 'Not specified'}</span>
+                      <span className="text-white/70">Gender: </span>
+                      <span className="text-white">{userPreferences.gender || 'Not specified'}</span>
                     </div>
                     <div>
-                      <span className="text-white/70">Professional Background: </span>
-                      <span className="text-white">{userPreferences.professionalBackground || 'Not specified'}</span>
+                      <span className="text-white/70">Looking For: </span>
+                      <span className="text-white">{userPreferences.connectionType || 'Not specified'}</span>
                     </div>
                     <div>
-                      <span className="text-white/70">Interests: </span>
-                      <span className="text-white">{userPreferences.interests?.join(', ') || 'Not specified'}</span>
+                      <span className="text-white/70">Personality: </span>
+                      <span className="text-white">{userPreferences.personality || 'Not specified'}</span>
+                    </div>
+                    <div>
+                      <span className="text-white/70">Toronto Means: </span>
+                      <span className="text-white">{userPreferences.torontoMeaning || 'Not specified'}</span>
+                    </div>
+                    <div>
+                      <span className="text-white/70">Drink: </span>
+                      <span className="text-white">{userPreferences.cocktailPreference || 'Not specified'}</span>
                     </div>
-                    {userPreferences.lookingFor && (
-                      <div>
-                        <span className="text-white/70">Looking For: </span>
-                        <span className="text-white">{userPreferences.lookingFor}</span>
-                      </div>
-                    )}
                   </div>
                 ) : (
                   <div className="text-white/60 text-center py-4">
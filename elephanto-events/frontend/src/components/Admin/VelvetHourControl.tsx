@@ -1,10 +1,10 @@
 import React, { useState, useEffect } from 'react';
-import { AdminVelvetHourControlProps, ManualMatch } from '@/types/velvet-hour';
+import { AdminVelvetHourControlProps, ManualMatch, VelvetHourTimerUpdate } from '@/types/velvet-hour';
 import { velvetHourApi } from '@/services/velvetHourApi';
 import { DraggableMatchmaking } from './DraggableMatchmaking';
 import { useWebSocket, MESSAGE_TYPES } from '@/services/websocket';
 import { useToast } from '@/components/Toast';
-import { Play, Square, Settings, Users, Clock, Target, Calendar, RotateCcw, WifiOff } from 'lucide-react';
+import { Play, Square, Settings, Users, Clock, Target, Calendar, RotateCcw, WifiOff, Pause, Plus } from 'lucide-react';
 
 export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
   eventId,
@@ -201,6 +201,11 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
       fetchAttendanceStats();
     });
 
+    const unsubscribeTimerUpdated = subscribe(MESSAGE_TYPES.VELVET_HOUR_TIMER_UPDATED, (data) => {
+      console.log('Velvet Hour timer updated:', data);
+      setTimerUpdate({ ...data, receivedAt: Date.now() });
+    });
+
     const unsubscribeFeedbackSubmitted = subscribe(MESSAGE_TYPES.VELVET_HOUR_FEEDBACK_SUBMITTED, (data) => {
       console.log('Velvet Hour feedback submitted:', data);
       // Force refresh of the admin status to show updated feedback status
@@ -235,6 +240,19 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
       fetchAttendanceStats();
     });
 
+    const unsubscribeResync = subscribe(MESSAGE_TYPES.RESYNC_REQUIRED, (data) => {
+      console.log('Missed updates while disconnected:', data);
+      const fetchAttendanceStats = async () => {
+        try {
+          const response = await velvetHourApi.getAttendanceStats(eventId);
+          setAttendanceStats(response.data);
+        } catch (error) {
+          console.error('Failed to fetch attendance stats after resync:', error);
+        }
+      };
+      fetchAttendanceStats();
+    });
+
     // Cleanup subscriptions
     return () => {
       unsubscribeAttendance();
@@ -243,9 +261,11 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
       unsubscribeRoundStarted();
       unsubscribeSessionEnded();
       unsubscribeStatusUpdate();
+      unsubscribeTimerUpdated();
       unsubscribeFeedbackSubmitted();
       unsubscribeUserJoined();
       unsubscribeUserLeft();
+      unsubscribeResync();
     };
   }, [eventId, subscribe, showPresentModal]);
 
@@ -271,8 +291,23 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
     return `${mins}:${secs.toString().padStart(2, '0')}`;
   };
 
+  // Latest timer change pushed by the server, until the next status refresh
+  const [timerUpdate, setTimerUpdate] = useState<(VelvetHourTimerUpdate & { receivedAt: number }) | null>(null);
+
+  useEffect(() => {
+    setTimerUpdate(null);
+  }, [status.session?.roundEndsAt, status.session?.pausedAt, status.session?.pausedRemainingSeconds]);
+
+  const isPaused = timerUpdate ? timerUpdate.paused : !!status.session?.pausedAt;
+
   // Calculate time remaining in seconds for precise countdown
   const calculateTimeRemaining = () => {
+    if (timerUpdate) {
+      if (timerUpdate.paused) return timerUpdate.timeLeft;
+      const elapsed = Math.floor((Date.now() - timerUpdate.receivedAt) / 1000);
+      return Math.max(0, timerUpdate.timeLeft - elapsed);
+    }
+    if (status.session?.pausedAt) return status.session.pausedRemainingSeconds || 0;
     if (!status.session?.roundEndsAt) return 0;
     const now = Date.now();
     const endTime = new Date(status.session.roundEndsAt).getTime();
@@ -291,12 +326,12 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
     // Update immediately
     updateTimer();
 
-    // Set up interval if session has an end time
-    if (status.session?.roundEndsAt) {
+    // Set up interval if session has a running clock
+    if (status.session?.roundEndsAt && !isPaused) {
       const interval = setInterval(updateTimer, 1000);
       return () => clearInterval(interval);
     }
-  }, [status.session?.roundEndsAt]);
+  }, [status.session?.roundEndsAt, status.session?.pausedAt, status.session?.pausedRemainingSeconds, timerUpdate, isPaused]);
 
   const handleStartRound = () => {
     // Button should only be enabled when all conditions are met
@@ -324,6 +359,30 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
     }
   };
 
+  const handleTogglePause = async () => {
+    try {
+      const response = isPaused
+        ? await velvetHourApi.resumeSession(eventId)
+        : await velvetHourApi.pauseSession(eventId);
+      setTimerUpdate({ ...response.data, receivedAt: Date.now() });
+      showToast(isPaused ? 'Timer resumed' : 'Timer paused', 'success');
+    } catch (error) {
+      console.error('Failed to change timer:', error);
+      showToast(isPaused ? 'Failed to resume timer' : 'Failed to pause timer', 'error');
+    }
+  };
+
+  const handleAdjustTimer = async (seconds: number) => {
+    try {
+      const response = await velvetHourApi.adjustTimer(eventId, seconds);
+      setTimerUpdate({ ...response.data, receivedAt: Date.now() });
+      showToast(`Added ${Math.round(seconds / 60)} minutes`, 'success');
+    } catch (error) {
+      console.error('Failed to adjust timer:', error);
+      showToast('Failed to adjust timer', 'error');
+    }
+  };
+
   const handleUpdateConfig = () => {
     onUpdateConfig(config);
     setShowConfig(false);
@@ -572,7 +631,7 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
             }
           </p>
           <p className="text-sm text-white/70">
-            {status.session?.status === 'in_round' ? 'Until round ends' : 'Until break ends'}
+            {isPaused ? 'Paused' : status.session?.status === 'in_round' ? 'Until round ends' : 'Until break ends'}
           </p>
         </div>
       </div>
@@ -729,6 +788,25 @@ export const VelvetHourControl: React.FC<AdminVelvetHourControlProps> = ({
               </button>
             )}
 
+            {(status.session.status === 'in_round' || status.session.status === 'break') && status.session.roundEndsAt && (
+              <>
+                <button
+                  onClick={handleTogglePause}
+                  className="flex items-center justify-center space-x-2 px-4 sm:px-6 py-2 sm:py-3 bg-yellow-600 hover:bg-yellow-500 text-white rounded-lg font-semibold text-sm sm:text-base whitespace-nowrap transition-colors duration-200"
+                >
+                  {isPaused ? <Play className="h-4 w-4" /> : <Pause className="h-4 w-4" />}
+                  <span>{isPaused ? 'Resume' : 'Pause'}</span>
+                </button>
+                <button
+                  onClick={() => handleAdjustTimer(300)}
+                  className="flex items-center justify-center space-x-2 px-4 sm:px-6 py-2 sm:py-3 bg-blue-600 hover:bg-blue-500 text-white rounded-lg font-semibold text-sm sm:text-base whitespace-nowrap transition-colors duration-200"
+                >
+                  <Plus className="h-4 w-4" />
+                  <span>5 min</span>
+                </button>
+              </>
+            )}
+
             {(status.session.status === 'break' || (status.session.status === 'waiting' && status.session.currentRound > 0)) && 
              status.session.currentRound < (status.config?.totalRounds || 4) && (
               <>
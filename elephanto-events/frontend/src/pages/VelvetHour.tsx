@@ -18,6 +18,7 @@ export const VelvetHour: React.FC = () => {
   const [gameState, setGameState] = useState<GameState>('loading');
   const [status, setStatus] = useState<VelvetHourStatusResponse | null>(null);
   const [timeLeft, setTimeLeft] = useState<number>(0);
+  const [isPaused, setIsPaused] = useState<boolean>(false);
   const [currentRound, setCurrentRound] = useState<number>(1);
   const [totalRounds, setTotalRounds] = useState<number>(4);
   const [roundDuration, setRoundDuration] = useState<number>(10);
@@ -62,6 +63,10 @@ export const VelvetHour: React.FC = () => {
   }, [gameState]);
 
   useEffect(() => {
+    if (isPaused) {
+      // The host is holding the round; wait for a timer update to resume
+      return;
+    }
     if (timeLeft > 0) {
       console.log('⏱️ VelvetHour: Timer effect - timeLeft:', timeLeft, 'gameState:', gameState);
       const timer = setTimeout(() => {
@@ -81,11 +86,12 @@ export const VelvetHour: React.FC = () => {
     } else if (timeLeft === 0) {
       console.log('⚠️ VelvetHour: Timer at 0 but gameState is:', gameState, 'hasMatch:', !!status?.currentMatch);
     }
-  }, [timeLeft, gameState, status?.currentMatch]);
+  }, [timeLeft, isPaused, gameState, status?.currentMatch]);
 
   // WebSocket event listeners for real-time updates
   useEffect(() => {
-    if (!isConnected || !eventId) return;
+    // Keep subscriptions across reconnects so messages replayed on reconnect aren't missed
+    if (!eventId) return;
 
     console.log('Setting up WebSocket listeners for Velvet Hour');
 
@@ -104,6 +110,18 @@ export const VelvetHour: React.FC = () => {
       checkStatus(); // Refresh to show round timer
     });
 
+    const unsubscribeMatchReassigned = subscribe(MESSAGE_TYPES.VELVET_HOUR_MATCH_REASSIGNED, (data) => {
+      console.log('Match reassigned:', data);
+      checkStatus(); // Refresh to show the new match number and color
+    });
+
+    const unsubscribeTimerUpdated = subscribe(MESSAGE_TYPES.VELVET_HOUR_TIMER_UPDATED, (data) => {
+      console.log('Timer updated:', data);
+      // Resync straight from the server's clock
+      setIsPaused(!!data.paused);
+      setTimeLeft(data.timeLeft ?? 0);
+    });
+
     const unsubscribeFeedbackSubmitted = subscribe(MESSAGE_TYPES.VELVET_HOUR_FEEDBACK_SUBMITTED, (data) => {
       console.log('Feedback submitted:', data);
       // Could show toast notification about partner feedback
@@ -139,18 +157,26 @@ export const VelvetHour: React.FC = () => {
       checkStatus(); // Refresh status on any general update
     });
 
+    const unsubscribeResync = subscribe(MESSAGE_TYPES.RESYNC_REQUIRED, (data) => {
+      console.log('Missed updates while disconnected:', data);
+      checkStatus(); // Reload everything we may have missed
+    });
+
     // Cleanup subscriptions
     return () => {
       unsubscribeSessionStarted();
       unsubscribeRoundStarted();
       unsubscribeMatchConfirmed();
+      unsubscribeMatchReassigned();
+      unsubscribeTimerUpdated();
       unsubscribeFeedbackSubmitted();
       unsubscribeSessionEnded();
       unsubscribeSessionReset();
       unsubscribeParticipantJoined();
       unsubscribeStatusUpdate();
+      unsubscribeResync();
     };
-  }, [isConnected, eventId, subscribe, showToast]);
+  }, [eventId, subscribe, showToast]);
 
   const checkStatus = async () => {
     // Rate limiting: prevent calls more frequent than every 2 seconds
@@ -174,6 +200,7 @@ export const VelvetHour: React.FC = () => {
         hasParticipant: !!statusData.participant
       });
       setStatus(statusData);
+      setIsPaused(!!statusData.paused);
 
       // Extract eventId from session for WebSocket connection
       if (statusData.session?.eventId && eventId !== statusData.session.eventId) {
@@ -424,7 +451,7 @@ export const VelvetHour: React.FC = () => {
               timeLeft={timeLeft}
               totalTime={totalBreakTime}
               title="BREAK TIME"
-              subtitle="NEXT ROUND STARTS"
+              subtitle={isPaused ? 'PAUSED' : 'NEXT ROUND STARTS'}
             />
 
             {/* Break info and status */}
@@ -4,7 +4,19 @@ import {
   SubmitFeedbackRequest,
   AdminVelvetHourStatusResponse,
   StartRoundRequest,
-  UpdateVelvetHourConfigRequest
+  UpdateVelvetHourConfigRequest,
+  VelvetHourTimerUpdate,
+  VelvetHourRoundProposal,
+  VelvetHourBlock,
+  VelvetHourPairExclusion,
+  VelvetHourExclusionConflict,
+  VelvetHourTable,
+  VelvetHourTableRequest,
+  VelvetHourSessionEvent,
+  VelvetHourAnalytics,
+  VelvetHourResultPair,
+  VelvetHourDigestResult,
+  ManualMatch
 } from '@/types/velvet-hour';
 
 export const velvetHourApi = {
@@ -12,14 +24,23 @@ export const velvetHourApi = {
   getStatus: () => 
     api.get<VelvetHourStatusResponse>('/velvet-hour/status'),
     
-  joinSession: () => 
-    api.post('/velvet-hour/join'),
+  joinSession: (needsAccessibleSeating = false) => 
+    api.post('/velvet-hour/join', { needsAccessibleSeating }),
     
   confirmMatch: (matchId: string) => 
     api.post('/velvet-hour/confirm-match', { matchId }),
     
   submitFeedback: (data: SubmitFeedbackRequest) => 
     api.post('/velvet-hour/feedback', data),
+    
+  getBlocks: () => 
+    api.get<VelvetHourBlock[]>('/velvet-hour/blocks'),
+    
+  blockUser: (userId: string, reason = '') => 
+    api.post('/velvet-hour/blocks', { userId, reason }),
+    
+  unblockUser: (userId: string) => 
+    api.delete(`/velvet-hour/blocks/${userId}`),
 
   // Admin endpoints
   getAdminStatus: (eventId: string) => 
@@ -40,9 +61,78 @@ export const velvetHourApi = {
   startRound: (eventId: string, data?: StartRoundRequest) => 
     api.post(`/admin/events/${eventId}/velvet-hour/start-round`, data || {}),
     
+  previewRound: (eventId: string, matches?: ManualMatch[]) => 
+    api.post<VelvetHourRoundProposal>(`/admin/events/${eventId}/velvet-hour/preview-round`, matches ? { matches } : {}),
+    
   closeRound: (eventId: string) => 
     api.post(`/admin/events/${eventId}/velvet-hour/close-round`),
     
+  pauseSession: (eventId: string) => 
+    api.post<VelvetHourTimerUpdate>(`/admin/events/${eventId}/velvet-hour/pause`),
+    
+  resumeSession: (eventId: string) => 
+    api.post<VelvetHourTimerUpdate>(`/admin/events/${eventId}/velvet-hour/resume`),
+    
+  adjustTimer: (eventId: string, seconds: number) => 
+    api.post<VelvetHourTimerUpdate>(`/admin/events/${eventId}/velvet-hour/extend`, { seconds }),
+    
+  swapMatchPartners: (eventId: string, matchAId: string, userAId: string, matchBId: string, userBId: string) => 
+    api.post(`/admin/events/${eventId}/velvet-hour/matches/swap`, { matchAId, userAId, matchBId, userBId }),
+    
+  splitMatch: (eventId: string, matchId: string) => 
+    api.post(`/admin/events/${eventId}/velvet-hour/matches/${matchId}/split`),
+    
+  pairParticipants: (eventId: string, user1Id: string, user2Id: string) => 
+    api.post(`/admin/events/${eventId}/velvet-hour/matches/pair`, { user1Id, user2Id }),
+    
+  getExclusions: (eventId: string) => 
+    api.get<VelvetHourPairExclusion[]>(`/admin/events/${eventId}/velvet-hour/exclusions`),
+    
+  createExclusion: (eventId: string, user1Id: string, user2Id: string, permanent: boolean, reason = '') => 
+    api.post<VelvetHourPairExclusion>(`/admin/events/${eventId}/velvet-hour/exclusions`, { user1Id, user2Id, permanent, reason }),
+    
+  deleteExclusion: (eventId: string, exclusionId: string) => 
+    api.delete(`/admin/events/${eventId}/velvet-hour/exclusions/${exclusionId}`),
+    
+  getExclusionConflicts: (eventId: string) => 
+    api.get<VelvetHourExclusionConflict[]>(`/admin/events/${eventId}/velvet-hour/exclusions/conflicts`),
+    
+  getTables: (eventId: string) => 
+    api.get<VelvetHourTable[]>(`/admin/events/${eventId}/velvet-hour/tables`),
+    
+  createTable: (eventId: string, table: VelvetHourTableRequest) => 
+    api.post<VelvetHourTable>(`/admin/events/${eventId}/velvet-hour/tables`, table),
+    
+  updateTable: (eventId: string, tableId: string, table: VelvetHourTableRequest) => 
+    api.put<VelvetHourTable>(`/admin/events/${eventId}/velvet-hour/tables/${tableId}`, table),
+    
+  deleteTable: (eventId: string, tableId: string) => 
+    api.delete(`/admin/events/${eventId}/velvet-hour/tables/${tableId}`),
+    
+  getTimeline: (eventId: string, sessionId?: string) => 
+    api.get<VelvetHourSessionEvent[]>(`/admin/events/${eventId}/velvet-hour/timeline`, { params: { sessionId } }),
+    
+  exportTimelineCsv: (eventId: string, sessionId?: string) => 
+    api.get<Blob>(`/admin/events/${eventId}/velvet-hour/timeline`, { params: { sessionId, format: 'csv' }, responseType: 'blob' }),
+    
+  replaySession: (eventId: string, at: string, sessionId?: string) => 
+    api.get<AdminVelvetHourStatusResponse>(`/admin/events/${eventId}/velvet-hour/replay`, { params: { at, sessionId } }),
+    
+  getAnalytics: (eventId: string, sessionId?: string, refresh = false) => 
+    api.get<VelvetHourAnalytics>(`/admin/events/${eventId}/velvet-hour/analytics`, { params: { sessionId, refresh: refresh || undefined } }),
+    
+  exportAnalyticsCsv: (eventId: string, sessionId?: string) => 
+    api.get<Blob>(`/admin/events/${eventId}/velvet-hour/analytics`, { params: { sessionId, format: 'csv' }, responseType: 'blob' }),
+    
+  getSessionResults: (eventId: string, sessionId?: string) => 
+    api.get<VelvetHourResultPair[]>(`/admin/events/${eventId}/velvet-hour/results`, { params: { sessionId } }),
+    
+  exportSessionResultsCsv: (eventId: string, sessionId?: string) => 
+    api.get<Blob>(`/admin/events/${eventId}/velvet-hour/results`, { params: { sessionId, format: 'csv' }, responseType: 'blob' }),
+    
+  sendDigests: (eventId: string, sessionId?: string) => 
+    api.post<VelvetHourDigestResult>(`/admin/events/${eventId}/velvet-hour/send-digests`, null, { params: { sessionId } }),
+    
   endSession: (eventId: string) => 
     api.post(`/admin/events/${eventId}/velvet-hour/end`),
     
@@ -14,18 +14,23 @@ export const MESSAGE_TYPES = {
   VELVET_HOUR_SESSION_RESET: 'VELVET_HOUR_SESSION_RESET',
   ATTENDANCE_STATS_UPDATE: 'ATTENDANCE_STATS_UPDATE',
   VELVET_HOUR_STATUS_UPDATE: 'VELVET_HOUR_STATUS_UPDATE',
+  VELVET_HOUR_CONNECTION_MADE: 'VELVET_HOUR_CONNECTION_MADE',
+  VELVET_HOUR_MATCH_REASSIGNED: 'VELVET_HOUR_MATCH_REASSIGNED',
+  VELVET_HOUR_TIMER_UPDATED: 'VELVET_HOUR_TIMER_UPDATED',
   VELVET_HOUR_ADMIN_DRAG_UPDATE: 'VELVET_HOUR_ADMIN_DRAG_UPDATE',
   VELVET_HOUR_ADMIN_MATCH_UPDATE: 'VELVET_HOUR_ADMIN_MATCH_UPDATE',
   VELVET_HOUR_AI_MATCHES_GENERATED: 'VELVET_HOUR_AI_MATCHES_GENERATED',
   PING: 'PING',
   PONG: 'PONG',
   ADMIN_DISCONNECT: 'ADMIN_DISCONNECT',
+  RESYNC_REQUIRED: 'RESYNC_REQUIRED',
 };
 
 // WebSocket message interface
 export interface WebSocketMessage {
   type: string;
   eventId: string;
+  seq?: number; // Set on broadcasts; increases with every broadcast to the event
   data: any;
   timestamp: number;
 }
@@ -46,6 +51,7 @@ class WebSocketService {
   private readonly HEARTBEAT_INTERVAL = 10000; // 10 seconds
   private disconnectCallback: ((message: string) => void) | null = null;
   private adminDisconnected = false; // Flag to prevent auto-reconnection after admin disconnect
+  private lastSeq = 0; // Highest sequence number seen, sent on reconnect to catch up on missed messages
 
   constructor() {
     // Check authentication status on initialization
@@ -138,6 +144,9 @@ class WebSocketService {
 
     // Reset admin disconnect flag when manually connecting (e.g., page refresh)
     this.adminDisconnected = false;
+    if (this.eventId !== eventId) {
+      this.lastSeq = 0; // Sequence numbers are per event
+    }
     this.eventId = eventId;
     this.isConnecting = true;
 
@@ -187,7 +196,11 @@ class WebSocketService {
       const wsHost = baseUrl.replace(/^https?:/, wsProtocol);
       
       // Include token as query parameter since WebSocket doesn't support custom headers
-      const wsUrl = `${wsHost}/api/ws/${this.eventId}?token=${encodeURIComponent(token)}`;
+      let wsUrl = `${wsHost}/api/ws/${this.eventId}?token=${encodeURIComponent(token)}`;
+      // When reconnecting, ask for everything broadcast since the last message we saw
+      if (this.lastSeq > 0) {
+        wsUrl += `&lastSeq=${this.lastSeq}`;
+      }
 
       try {
         this.socket = new WebSocket(wsUrl);
@@ -270,6 +283,16 @@ class WebSocketService {
   private handleMessage(message: WebSocketMessage) {
     console.log('WebSocket message received:', message);
 
+    if (message.seq && message.seq > this.lastSeq) {
+      this.lastSeq = message.seq;
+    }
+
+    // Missed messages could not be replayed; subscribers reload their state
+    if (message.type === MESSAGE_TYPES.RESYNC_REQUIRED) {
+      console.log('Resync required after reconnect:', message.data);
+      this.lastSeq = message.data?.latestSeq || 0;
+    }
+
     // Handle PONG messages
     if (message.type === MESSAGE_TYPES.PONG) {
       console.log('Received PONG response');
@@ -334,6 +357,7 @@ class WebSocketService {
     this.eventId = null;
     this.isConnecting = false;
     this.reconnectAttempts = 0;
+    this.lastSeq = 0;
     console.log('WebSocket disconnected');
   }
 
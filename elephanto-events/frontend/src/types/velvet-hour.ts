@@ -10,6 +10,8 @@ export interface VelvetHourSession {
   roundStartedAt?: string;
   roundEndsAt?: string;
   status: string; // waiting, in_round, break, completed
+  pausedAt?: string;
+  pausedRemainingSeconds?: number;
   createdAt: string;
   updatedAt: string;
 }
@@ -20,6 +22,7 @@ export interface VelvetHourParticipant {
   userId: string;
   joinedAt: string;
   status: string; // waiting, matched, in_round, completed
+  needsAccessibleSeating: boolean;
   createdAt: string;
   updatedAt: string;
   userName: string;
@@ -44,6 +47,25 @@ export interface VelvetHourMatch {
   user2Name: string;
   user1FeedbackSubmitted?: boolean;
   user2FeedbackSubmitted?: boolean;
+  members?: VelvetHourMatchMember[]; // everyone in the match, including pods
+  tableId?: string;
+  table?: VelvetHourMatchTable; // where to go, when the event has a venue layout
+}
+
+export interface VelvetHourMatchTable {
+  id: string;
+  name: string;
+  zone: string;
+  accessible: boolean;
+}
+
+export interface VelvetHourMatchMember {
+  userId: string;
+  name?: string;
+  position: number;
+  confirmed: boolean;
+  confirmedAt?: string;
+  feedbackSubmitted: boolean;
 }
 
 export interface VelvetHourFeedback {
@@ -65,13 +87,167 @@ export interface VelvetHourStatusResponse {
   participant?: VelvetHourParticipant;
   currentMatch?: VelvetHourMatch;
   timeLeft?: number; // seconds remaining
+  paused?: boolean; // the round or break clock is on hold
   config?: VelvetHourConfig;
 }
 
+export interface VelvetHourTimerUpdate {
+  sessionId: string;
+  status: string;
+  currentRound: number;
+  paused: boolean;
+  timeLeft: number;
+  roundEndsAt?: string;
+}
+
 export interface SubmitFeedbackRequest {
   matchId: string;
   wantToConnect: boolean;
   feedbackReason: string; // humor, confidence, listening, no_connection
+  neverMatchAgain?: boolean; // privately block this person from future matches
+}
+
+export interface VelvetHourBlock {
+  userId: string;
+  name: string;
+  reason: string;
+  createdAt: string;
+}
+
+export interface VelvetHourPairExclusion {
+  id: string;
+  user1Id: string;
+  user1Name: string;
+  user2Id: string;
+  user2Name: string;
+  eventId?: string; // absent for exclusions that apply to every event
+  source: 'user' | 'feedback' | 'admin';
+  createdBy?: string;
+  reason: string;
+  createdAt: string;
+}
+
+export interface VelvetHourExclusionConflict {
+  kind: 'match' | 'schedule';
+  roundNumber: number;
+  matchId?: string;
+  matchNumber?: number;
+  user1Id: string;
+  user1Name: string;
+  user2Id: string;
+  user2Name: string;
+}
+
+export interface VelvetHourTable {
+  id: string;
+  eventId: string;
+  name: string;
+  zone: string;
+  capacity: number;
+  accessible: boolean;
+  sortOrder: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface VelvetHourSessionEvent {
+  id: number;
+  sessionId: string;
+  eventType: string; // session_created, status_changed, match_created, match_confirmed, ...
+  roundNumber: number;
+  userId?: string;
+  userName?: string;
+  matchId?: string;
+  payload: Record<string, unknown>;
+  createdAt: string;
+}
+
+export interface VelvetHourOutcomeStats {
+  matches: number;
+  noShowMatches: number; // included in matches
+  abandonedMatches: number; // re-paired, not included in matches
+  members: number;
+  confirmed: number;
+  confirmationRate: number; // 0-1
+  feedbackExpected: number;
+  feedbackSubmitted: number;
+  feedbackCompletion: number; // 0-1
+  wantToConnect: number;
+  wantToConnectRate: number; // 0-1, of submitted feedback
+  mutualMatches: number;
+}
+
+export interface VelvetHourRoundAnalytics extends VelvetHourOutcomeStats {
+  roundNumber: number;
+}
+
+export interface VelvetHourScoreBandAnalytics extends VelvetHourOutcomeStats {
+  label: string; // e.g. 0.6-0.8, or unscored
+  minScore: number | null;
+  maxScore: number | null;
+}
+
+export interface VelvetHourReasonCount {
+  reason: string;
+  count: number;
+  share: number;
+}
+
+export interface VelvetHourAnalytics {
+  eventId: string;
+  sessionId?: string;
+  sessions: number;
+  overall: VelvetHourOutcomeStats;
+  rounds: VelvetHourRoundAnalytics[];
+  scoreBands: VelvetHourScoreBandAnalytics[];
+  reasons: VelvetHourReasonCount[];
+  generatedAt: string;
+}
+
+export interface VelvetHourResultMember {
+  userId: string;
+  name: string | null;
+  email: string;
+  confirmedAt: string | null;
+}
+
+export interface VelvetHourResultFeedback {
+  wantToConnect: boolean;
+  reason: string;
+  answers?: Record<string, unknown>; // question text to answer
+  submittedAt: string;
+}
+
+// One pairing of a session; trios and pods appear once per pair of members
+export interface VelvetHourResultPair {
+  sessionId: string;
+  roundNumber: number;
+  matchId: string;
+  matchNumber: number;
+  matchColor: string;
+  matchStatus: string;
+  tableName?: string;
+  compatibilityScore?: number;
+  user1: VelvetHourResultMember;
+  user2: VelvetHourResultMember;
+  user1Feedback: VelvetHourResultFeedback | null; // from user 1 about user 2
+  user2Feedback: VelvetHourResultFeedback | null;
+  mutual: boolean;
+}
+
+export interface VelvetHourDigestResult {
+  sessionId: string;
+  sent: number;
+  failed: number;
+  skipped: number; // already emailed, or met nobody
+}
+
+export interface VelvetHourTableRequest {
+  name: string;
+  zone?: string;
+  capacity: number;
+  accessible?: boolean;
+  sortOrder?: number;
 }
 
 export interface VelvetHourConfig {
@@ -79,6 +255,8 @@ export interface VelvetHourConfig {
   breakDuration: number;
   totalRounds: number;
   minParticipants: number;
+  groupMode?: 'pairs' | 'pods';
+  podSize?: number; // target pod size in pods mode (3-6)
 }
 
 export interface AdminVelvetHourStatusResponse {
@@ -92,6 +270,29 @@ export interface AdminVelvetHourStatusResponse {
 
 export interface StartRoundRequest {
   matches?: ManualMatch[];
+  proposalId?: string; // start a previewed proposal exactly as reviewed
+}
+
+export interface VelvetHourProposedMatch extends ManualMatch {
+  user1Name: string;
+  user2Name: string;
+  user3Name?: string;
+  memberNames?: string[];
+  compatibilityScore: number;
+  repeatPair: boolean;
+  warnings?: string[];
+}
+
+export interface VelvetHourRoundProposal {
+  id: string;
+  sessionId: string;
+  roundNumber: number;
+  matches: VelvetHourProposedMatch[];
+  unmatched: { userId: string; name: string }[];
+  compatibilityScore: number;
+  repeatPairs: number;
+  warnings: string[];
+  createdAt: string;
 }
 
 export interface ManualMatch {
@@ -99,12 +300,16 @@ export interface ManualMatch {
   user2Id: string;
   matchNumber: number;
   matchColor: string;
+  members?: string[]; // every member of a pod, in order
+  tableId?: string; // leave out to have a table picked
 }
 
 export interface UpdateVelvetHourConfigRequest {
   roundDuration?: number;
   breakDuration?: number;
   totalRounds?: number;
+  groupMode?: 'pairs' | 'pods';
+  podSize?: number;
   // minParticipants is auto-calculated based on totalRounds
 }
 